@@ -0,0 +1,292 @@
+// Package agent keeps files in sync with vault items, so that applications pick up rotated secrets
+// without restarting.
+//
+// An Agent watches the vault items its sinks read from, writes each sink's file atomically whenever
+// the value it holds changes, and then runs a reload command:
+//
+//	a, err := agent.New(svc, &agent.Options{
+//		Sinks:    []agent.Sink{{VaultName: "database", VaultItemName: "postgres", Path: "password", Dest: "/etc/app/db-password"}},
+//		Interval: time.Minute,
+//		Reload:   []string{"systemctl", "reload", "app"},
+//	})
+//	err = a.Run(ctx)
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/internal/fsutil"
+	"github.com/justintsteele/go-chef-vault/item"
+)
+
+const (
+	// DefaultInterval is how often vault items are polled when Options.Interval is zero.
+	DefaultInterval = 30 * time.Second
+
+	// DefaultMode is the file mode of written files when Sink.Mode is zero.
+	DefaultMode os.FileMode = 0o600
+)
+
+// ErrInvalidSink is returned when a sink spec is malformed or a sink is incomplete.
+var ErrInvalidSink = errors.New("agent: invalid sink")
+
+// Watcher watches vault items. *vault.Service satisfies it.
+type Watcher interface {
+	Watch(ctx context.Context, vaultName, vaultItem string, interval time.Duration) (<-chan vault.WatchEvent, error)
+}
+
+// Sink writes a vault item, or a value inside it, to a file.
+//
+// A string value is written as is; any other value, and the whole item less its id when Path is
+// empty, is written as indented JSON.
+type Sink struct {
+	VaultName     string
+	VaultItemName string
+
+	// Path selects a value with a JSON Pointer or dotted path, as Payload.RemovePaths does.
+	Path string
+
+	// Dest is the file written. It is replaced atomically.
+	Dest string
+
+	// Mode is the permission of the written file. Defaults to DefaultMode.
+	Mode os.FileMode
+}
+
+// Options configures an Agent.
+type Options struct {
+	// Sinks are the files kept in sync. At least one is required.
+	Sinks []Sink
+
+	// Interval is how often each vault item is polled. Defaults to DefaultInterval.
+	Interval time.Duration
+
+	// Reload is a command and its arguments, run after files were rewritten because an item changed.
+	// Empty runs nothing.
+	Reload []string
+
+	// Stdout and Stderr receive the output of the reload command. Default to discarding it.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Logger receives a record per file written, reload run and failure. Defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// Agent keeps the files of its sinks in sync with vault items. Create agents with New.
+type Agent struct {
+	watcher Watcher
+	opts    Options
+	paths   map[int]item.Path
+}
+
+// ParseSink parses a sink spec of the form FILE=VAULT/ITEM[:PATH].
+//
+//	/etc/app/db-password=database/postgres:password   one value
+//	/etc/app/web.json=apps/web                         the whole item as JSON
+func ParseSink(spec string) (Sink, error) {
+	var s Sink
+
+	dest, target, ok := strings.Cut(spec, "=")
+	if !ok || dest == "" {
+		return s, fmt.Errorf("%w: %q must be FILE=VAULT/ITEM[:PATH]", ErrInvalidSink, spec)
+	}
+	s.Dest = dest
+
+	target, s.Path, _ = strings.Cut(target, ":")
+	s.VaultName, s.VaultItemName, _ = strings.Cut(target, "/")
+	if s.VaultName == "" || s.VaultItemName == "" || strings.Contains(s.VaultItemName, "/") {
+		return s, fmt.Errorf("%w: %q must be FILE=VAULT/ITEM[:PATH]", ErrInvalidSink, spec)
+	}
+	return s, nil
+}
+
+// New returns an Agent watching vault items with w.
+func New(w Watcher, opts *Options) (*Agent, error) {
+	if opts == nil || len(opts.Sinks) == 0 {
+		return nil, fmt.Errorf("%w: no sinks configured", ErrInvalidSink)
+	}
+
+	a := &Agent{
+		watcher: w,
+		opts:    *opts,
+		paths:   make(map[int]item.Path),
+	}
+	for i, s := range a.opts.Sinks {
+		if s.VaultName == "" || s.VaultItemName == "" || s.Dest == "" {
+			return nil, fmt.Errorf("%w: sink %d needs a vault, an item and a destination", ErrInvalidSink, i)
+		}
+		if s.Path != "" {
+			p, err := item.ParsePath(s.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSink, s.Dest, err)
+			}
+			a.paths[i] = p
+		}
+	}
+
+	if a.opts.Interval == 0 {
+		a.opts.Interval = DefaultInterval
+	}
+	if a.opts.Stdout == nil {
+		a.opts.Stdout = io.Discard
+	}
+	if a.opts.Stderr == nil {
+		a.opts.Stderr = io.Discard
+	}
+	if a.opts.Logger == nil {
+		a.opts.Logger = slog.Default()
+	}
+	return a, nil
+}
+
+// Run watches the vault items until ctx is done, writing the sinks' files as the items change. It
+// returns an error only when a watch cannot be started; failures to read items, write files or
+// reload are logged and retried on the next change.
+func (a *Agent) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	merged := make(chan vault.WatchEvent)
+	var wg sync.WaitGroup
+	watching := make(map[string]bool)
+
+	var err error
+	for _, s := range a.opts.Sinks {
+		key := s.VaultName + "/" + s.VaultItemName
+		if watching[key] {
+			continue
+		}
+		watching[key] = true
+
+		var events <-chan vault.WatchEvent
+		if events, err = a.watcher.Watch(ctx, s.VaultName, s.VaultItemName, a.opts.Interval); err != nil {
+			err = fmt.Errorf("agent: watching %s: %w", key, err)
+			cancel()
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ev := range events {
+				merged <- ev
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	// events keep being drained after ctx is done, until every watch has closed its channel.
+	for ev := range merged {
+		if ctx.Err() == nil {
+			a.handle(ctx, ev)
+		}
+	}
+	return err
+}
+
+// handle writes the files of the sinks reading from the item of ev, and reloads if any changed.
+func (a *Agent) handle(ctx context.Context, ev vault.WatchEvent) {
+	log := a.opts.Logger.With("vault", ev.VaultName, "item", ev.VaultItemName)
+	if ev.Err != nil {
+		log.WarnContext(ctx, "reading vault item failed", "error", ev.Err)
+		return
+	}
+
+	changed := false
+	for i, s := range a.opts.Sinks {
+		if s.VaultName != ev.VaultName || s.VaultItemName != ev.VaultItemName {
+			continue
+		}
+
+		data, err := a.render(i, ev.Content)
+		if err != nil {
+			log.WarnContext(ctx, "rendering sink failed", "dest", s.Dest, "error", err)
+			continue
+		}
+
+		written, err := writeIfChanged(s, data)
+		if err != nil {
+			log.ErrorContext(ctx, "writing sink failed", "dest", s.Dest, "error", err)
+			continue
+		}
+		if written {
+			log.InfoContext(ctx, "sink written", "dest", s.Dest, "revision", ev.Revision)
+			changed = true
+		}
+	}
+
+	if changed && len(a.opts.Reload) > 0 {
+		cmd := exec.CommandContext(ctx, a.opts.Reload[0], a.opts.Reload[1:]...)
+		cmd.Stdout = a.opts.Stdout
+		cmd.Stderr = a.opts.Stderr
+		if err := cmd.Run(); err != nil {
+			log.ErrorContext(ctx, "reload failed", "command", a.opts.Reload[0], "error", err)
+			return
+		}
+		log.InfoContext(ctx, "reloaded", "command", a.opts.Reload[0])
+	}
+}
+
+// render returns the file contents of sink i for the item content.
+func (a *Agent) render(i int, content map[string]interface{}) ([]byte, error) {
+	var value interface{}
+	if p, ok := a.paths[i]; ok {
+		if value, ok = item.Lookup(content, p); !ok {
+			return nil, fmt.Errorf("%w: %s", vault.ErrPathNotFound, p)
+		}
+	} else {
+		whole := make(map[string]interface{}, len(content))
+		for k, v := range content {
+			if k != "id" {
+				whole[k] = v
+			}
+		}
+		value = whole
+	}
+
+	if str, ok := value.(string); ok {
+		return []byte(str), nil
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeIfChanged atomically replaces the file of s with data, unless it already holds data with the
+// right mode. It reports whether the file was written.
+func writeIfChanged(s Sink, data []byte) (bool, error) {
+	mode := s.Mode
+	if mode == 0 {
+		mode = DefaultMode
+	}
+
+	if fi, err := os.Stat(s.Dest); err == nil && fi.Mode().Perm() == mode {
+		if current, err := os.ReadFile(s.Dest); err == nil && bytes.Equal(current, data) {
+			return false, nil
+		}
+	}
+
+	if err := fsutil.WriteFile(s.Dest, data, mode, -1, -1); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWatcher serves the events sent on its channels, one channel per vault item, which the test
+// closes once the context is done.
+type fakeWatcher struct {
+	events map[string]chan vault.WatchEvent
+	err    error
+}
+
+func (f *fakeWatcher) Watch(_ context.Context, vaultName, vaultItem string, _ time.Duration) (<-chan vault.WatchEvent, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.events[vaultName+"/"+vaultItem], nil
+}
+
+func event(rev string, content map[string]interface{}) vault.WatchEvent {
+	content["id"] = "postgres"
+	return vault.WatchEvent{VaultName: "database", VaultItemName: "postgres", Revision: rev, Content: content}
+}
+
+func TestAgent_Run(t *testing.T) {
+	dir := t.TempDir()
+	reloads := filepath.Join(dir, "reloads")
+	password := filepath.Join(dir, "password")
+	whole := filepath.Join(dir, "postgres.json")
+
+	w := &fakeWatcher{events: map[string]chan vault.WatchEvent{"database/postgres": make(chan vault.WatchEvent)}}
+	var logs bytes.Buffer
+	a, err := New(w, &Options{
+		Sinks: []Sink{
+			{VaultName: "database", VaultItemName: "postgres", Path: "password", Dest: password},
+			{VaultName: "database", VaultItemName: "postgres", Dest: whole, Mode: 0o640},
+		},
+		Reload: []string{"sh", "-c", "echo reload >> " + reloads},
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	events := w.events["database/postgres"]
+	send := func(ev vault.WatchEvent) {
+		events <- ev
+		// the second of two more events is only taken once ev has been handled.
+		for i := 0; i < 2; i++ {
+			events <- vault.WatchEvent{VaultName: "database", VaultItemName: "postgres", Err: errors.New("poll failed")}
+		}
+	}
+
+	send(event("r1", map[string]interface{}{"password": "hunter2", "user": "app"}))
+
+	data, err := os.ReadFile(password)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", string(data))
+
+	data, err = os.ReadFile(whole)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"password":"hunter2","user":"app"}`, string(data))
+	fi, err := os.Stat(whole)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+
+	// a change leaving the files as they are does not reload.
+	send(event("r2", map[string]interface{}{"password": "hunter2", "user": "app"}))
+	send(event("r3", map[string]interface{}{"password": "swordfish", "user": "app"}))
+
+	data, err = os.ReadFile(password)
+	require.NoError(t, err)
+	require.Equal(t, "swordfish", string(data))
+
+	data, err = os.ReadFile(reloads)
+	require.NoError(t, err)
+	require.Equal(t, "reload\nreload\n", string(data))
+
+	cancel()
+	close(events)
+	require.NoError(t, <-done)
+	require.Contains(t, logs.String(), "poll failed")
+	require.NotContains(t, logs.String(), "swordfish")
+}
+
+func TestAgent_RunWatchError(t *testing.T) {
+	a, err := New(&fakeWatcher{err: vault.ErrInvalidInterval}, &Options{
+		Sinks:  []Sink{{VaultName: "database", VaultItemName: "postgres", Dest: "out"}},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, err)
+	require.ErrorIs(t, a.Run(context.Background()), vault.ErrInvalidInterval)
+}
+
+func TestParseSink(t *testing.T) {
+	s, err := ParseSink("/etc/app/db-password=database/postgres:conn.password")
+	require.NoError(t, err)
+	require.Equal(t, Sink{VaultName: "database", VaultItemName: "postgres", Path: "conn.password", Dest: "/etc/app/db-password"}, s)
+
+	s, err = ParseSink("web.json=apps/web")
+	require.NoError(t, err)
+	require.Equal(t, Sink{VaultName: "apps", VaultItemName: "web", Dest: "web.json"}, s)
+
+	for _, spec := range []string{"apps/web", "=apps/web", "out=apps", "out=apps/web/extra"} {
+		_, err := ParseSink(spec)
+		require.ErrorIs(t, err, ErrInvalidSink, spec)
+	}
+
+	_, err = New(&fakeWatcher{}, &Options{Sinks: []Sink{{VaultName: "apps", VaultItemName: "web", Dest: "out", Path: "a[x"}}})
+	require.ErrorIs(t, err, ErrInvalidSink)
+}
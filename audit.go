@@ -0,0 +1,177 @@
+package vault
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+)
+
+// AuditSink receives an AuditEvent for each vault change made through a Service whose AuditSink is set.
+// Implementations must be safe for concurrent use.
+type AuditSink interface {
+	Audit(event *AuditEvent) error
+}
+
+// AuditEvent describes a change made by Create, Update, Remove, Refresh, RotateKeys, Rollback, Delete or
+// DeleteItem, or an item replaced by Edit, Import or Restore. It names the content keys that changed but
+// never carries their values.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+
+	// Operation is one of the Op constants.
+	Operation string `json:"operation"`
+
+	// Actor is the client or user the Service acts as.
+	Actor string `json:"actor"`
+
+	Vault string `json:"vault"`
+	Item  string `json:"item,omitempty"`
+
+	AdminsAdded    []string `json:"admins_added,omitempty"`
+	AdminsRemoved  []string `json:"admins_removed,omitempty"`
+	ClientsAdded   []string `json:"clients_added,omitempty"`
+	ClientsRemoved []string `json:"clients_removed,omitempty"`
+
+	// SearchQueryBefore and SearchQueryAfter are nil when the item had no search query.
+	SearchQueryBefore *string `json:"search_query_before"`
+	SearchQueryAfter  *string `json:"search_query_after"`
+
+	// ModeBefore and ModeAfter are empty when the item did not exist before or after the change.
+	ModeBefore item_keys.KeysMode `json:"mode_before,omitempty"`
+	ModeAfter  item_keys.KeysMode `json:"mode_after,omitempty"`
+
+	// ChangedKeys lists the top-level content keys that were added, removed or changed. It is empty
+	// for items the Service cannot decrypt.
+	ChangedKeys []string `json:"changed_keys,omitempty"`
+
+	// Error is the error the operation failed with, empty if it succeeded. A failed operation may have
+	// made part of its change, which the rest of the event describes.
+	Error string `json:"error,omitempty"`
+}
+
+// auditState is what an AuditEvent compares before and after a change to an item.
+type auditState struct {
+	admins      []string
+	clients     []string
+	searchQuery *string
+	mode        item_keys.KeysMode
+	content     map[string]interface{}
+}
+
+// withAudit runs op, which changes vaultName/vaultItem, or the whole vault when vaultItem is empty, and
+// reports the change to the AuditSink, whether or not op succeeded. The item's state is read before and
+// after op, so it must run under the item's lock.
+func withAudit[T any](ctx context.Context, s *Service, operation, vaultName, vaultItem string, op func() (T, error)) (T, error) {
+	if s.AuditSink == nil {
+		return op()
+	}
+
+	before, err := s.loadAuditState(ctx, vaultName, vaultItem)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	res, opErr := op()
+
+	after, err := s.loadAuditState(ctx, vaultName, vaultItem)
+	if err != nil {
+		// the change may have been made; the event lacks only its outcome.
+		s.logger().Error("reading audited item failed", "vault", vaultName, "item", vaultItem, "error", err)
+		after = &auditState{}
+	}
+
+	event := s.newAuditEvent(operation, vaultName)
+	event.Item = vaultItem
+	if opErr != nil {
+		event.Error = opErr.Error()
+	}
+	event.AdminsAdded = item_keys.DiffLists(after.admins, before.admins)
+	event.AdminsRemoved = item_keys.DiffLists(before.admins, after.admins)
+	event.ClientsAdded = item_keys.DiffLists(after.clients, before.clients)
+	event.ClientsRemoved = item_keys.DiffLists(before.clients, after.clients)
+	event.SearchQueryBefore = before.searchQuery
+	event.SearchQueryAfter = after.searchQuery
+	event.ModeBefore = before.mode
+	event.ModeAfter = after.mode
+	event.ChangedKeys = changedKeys(before.content, after.content)
+	s.audit(event)
+
+	return res, opErr
+}
+
+// newAuditEvent returns an event for operation on vaultName, made now by the Service's actor.
+func (s *Service) newAuditEvent(operation, vaultName string) *AuditEvent {
+	return &AuditEvent{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Actor:     s.backend().ActorName(),
+		Vault:     vaultName,
+	}
+}
+
+// audit delivers event to the AuditSink. The change it describes has already been made, so a failed
+// delivery is logged rather than returned.
+func (s *Service) audit(event *AuditEvent) {
+	if err := s.AuditSink.Audit(event); err != nil {
+		s.logger().Error("audit delivery failed", "operation", event.Operation, "vault", event.Vault,
+			"item", event.Item, "error", err)
+	}
+}
+
+// loadAuditState reads the access rules and content of an item, or returns an empty state if it does not
+// exist or vaultItem is empty. Content is nil when the Service cannot decrypt the item.
+func (s *Service) loadAuditState(ctx context.Context, vaultName, vaultItem string) (*auditState, error) {
+	if vaultItem == "" {
+		return &auditState{}, nil
+	}
+
+	keys, err := s.loadKeysCurrentState(ctx, &Payload{VaultName: vaultName, VaultItemName: vaultItem})
+	if cheferr.IsNotFound(err) {
+		return &auditState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &auditState{
+		admins:      keys.Admins,
+		clients:     keys.Clients,
+		searchQuery: item_keys.NormalizeSearchQuery(keys.SearchQuery),
+		mode:        keys.Mode,
+	}
+	if state.mode == "" {
+		state.mode = item_keys.KeysModeDefault
+	}
+
+	content, err := s.getItem(ctx, vaultName, vaultItem, getOps{
+		deriveAESKey: item_keys.DeriveAESKey,
+		decrypt:      item.Decrypt,
+	})
+	if err == nil {
+		state.content, _ = item.DataBagItemMap(content)
+	}
+	return state, nil
+}
+
+// changedKeys returns the sorted top-level keys, other than id, whose values differ between before and after.
+func changedKeys(before, after map[string]interface{}) []string {
+	var keys []string
+	for k, v := range before {
+		if w, ok := after[k]; k != "id" && (!ok || !reflect.DeepEqual(v, w)) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range after {
+		if _, ok := before[k]; k != "id" && !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// WebhookSignatureHeader is the header carrying the HMAC-SHA256 signature of a webhook request body,
+// as "sha256=" followed by the hex digest.
+const WebhookSignatureHeader = "X-Vault-Signature"
+
+// FileAuditSink appends events to a file as JSON lines.
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ AuditSink = (*FileAuditSink)(nil)
+
+// NewFileAuditSink opens path for appending, creating it with mode 0600 if it does not exist.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: f}, nil
+}
+
+// Audit implements AuditSink. Each event is written with a single write, so concurrent writers appending
+// to the same file do not interleave lines.
+func (f *FileAuditSink) Audit(event *AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (f *FileAuditSink) Close() error {
+	return f.file.Close()
+}
+
+// WebhookAuditSink posts each event as JSON to a URL.
+type WebhookAuditSink struct {
+	// URL receives a POST per event.
+	URL string
+
+	// Secret, when set, signs each request body in the WebhookSignatureHeader header.
+	Secret []byte
+
+	// Client sends the requests. Defaults to a client with a ten second timeout.
+	Client *http.Client
+}
+
+var _ AuditSink = (*WebhookAuditSink)(nil)
+
+// defaultWebhookClient is the Client of a WebhookAuditSink without one.
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Audit implements AuditSink. Responses other than 2xx are returned as errors.
+func (w *WebhookAuditSink) Audit(event *AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.Secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(w.Secret, body))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("audit webhook %s: %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+// SignWebhook returns the WebhookSignatureHeader value for body signed with secret. Receivers compare it
+// against the header with hmac.Equal.
+func SignWebhook(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,230 @@
+package vault
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink records the events it receives.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []*AuditEvent
+}
+
+func (r *recordingSink) Audit(event *AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// last returns the last event received.
+func (r *recordingSink) last(t *testing.T) *AuditEvent {
+	t.Helper()
+	require.NotEmpty(t, r.events)
+	return r.events[len(r.events)-1]
+}
+
+func TestService_Audit(t *testing.T) {
+	svc := NewServiceWithBackend(newMemoryBackend(t))
+	sink := &recordingSink{}
+	svc.AuditSink = sink
+
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2", "user": "app"},
+		Admins:        []string{userid},
+		Clients:       []string{"node1"},
+	})
+	require.NoError(t, err)
+	event := sink.last(t)
+	require.Equal(t, OpCreate, event.Operation)
+	require.Equal(t, userid, event.Actor)
+	require.Equal(t, "vault1", event.Vault)
+	require.Equal(t, "secret1", event.Item)
+	require.Equal(t, []string{userid}, event.AdminsAdded)
+	require.Equal(t, []string{"node1"}, event.ClientsAdded)
+	require.Equal(t, item_keys.KeysMode(""), event.ModeBefore)
+	require.Equal(t, item_keys.KeysModeDefault, event.ModeAfter)
+	require.Equal(t, []string{"password", "user"}, event.ChangedKeys)
+	require.False(t, event.Time.IsZero())
+
+	query := "name:node*"
+	sparse := item_keys.KeysModeSparse
+	_, err = svc.Update(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "correct horse"},
+		SearchQuery:   &query,
+		KeysMode:      &sparse,
+	})
+	require.NoError(t, err)
+	event = sink.last(t)
+	require.Equal(t, OpUpdate, event.Operation)
+	require.Equal(t, []string{"password"}, event.ChangedKeys)
+	require.Nil(t, event.SearchQueryBefore)
+	require.Equal(t, &query, event.SearchQueryAfter)
+	require.Equal(t, item_keys.KeysModeDefault, event.ModeBefore)
+	require.Equal(t, item_keys.KeysModeSparse, event.ModeAfter)
+	require.Empty(t, event.AdminsAdded)
+	require.Empty(t, event.ClientsAdded)
+
+	_, err = svc.Remove(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Clients:       []string{"node1"},
+		RemovePaths:   []string{"user"},
+	})
+	require.NoError(t, err)
+	event = sink.last(t)
+	require.Equal(t, OpRemove, event.Operation)
+	require.Equal(t, []string{"node1"}, event.ClientsRemoved)
+	require.Equal(t, []string{"user"}, event.ChangedKeys)
+
+	_, err = svc.RotateKeys(&Payload{VaultName: "vault1", VaultItemName: "secret1"})
+	require.NoError(t, err)
+	event = sink.last(t)
+	require.Equal(t, OpRotate, event.Operation)
+	require.Empty(t, event.ChangedKeys)
+
+	_, err = svc.DeleteItem("vault1", "secret1")
+	require.NoError(t, err)
+	event = sink.last(t)
+	require.Equal(t, OpDeleteItem, event.Operation)
+	require.Equal(t, []string{userid}, event.AdminsRemoved)
+	require.Equal(t, item_keys.KeysMode(""), event.ModeAfter)
+	require.Equal(t, []string{"password"}, event.ChangedKeys)
+
+	_, err = svc.Delete("vault1")
+	require.NoError(t, err)
+	event = sink.last(t)
+	require.Equal(t, OpDelete, event.Operation)
+	require.Equal(t, "vault1", event.Vault)
+	require.Empty(t, event.Item)
+
+	require.Len(t, sink.events, 6)
+	out, err := json.Marshal(sink.events)
+	require.NoError(t, err)
+	require.NotContains(t, string(out), "hunter2")
+	require.NotContains(t, string(out), "correct horse")
+}
+
+func TestService_AuditFailures(t *testing.T) {
+	svc := NewServiceWithBackend(newMemoryBackend(t))
+	sink := &recordingSink{}
+	svc.AuditSink = sink
+
+	_, err := svc.Update(&Payload{VaultName: "vault1", VaultItemName: "missing"})
+	require.Error(t, err)
+	event := sink.last(t)
+	require.Equal(t, OpUpdate, event.Operation)
+	require.Equal(t, "missing", event.Item)
+	require.Equal(t, err.Error(), event.Error)
+	require.Empty(t, event.ChangedKeys)
+}
+
+func TestService_AuditRollbackAndReplace(t *testing.T) {
+	svc := NewServiceWithBackend(newMemoryBackend(t))
+	svc.HistoryLimit = 5
+	sink := &recordingSink{}
+	svc.AuditSink = sink
+
+	pl := &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+	}
+	_, err := svc.Create(pl)
+	require.NoError(t, err)
+	_, err = svc.Update(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"user": "app"},
+	})
+	require.NoError(t, err)
+
+	_, err = svc.Rollback("vault1", "secret1", 1)
+	require.NoError(t, err)
+	event := sink.last(t)
+	require.Equal(t, OpRollback, event.Operation)
+	require.Equal(t, []string{"user"}, event.ChangedKeys)
+	require.Empty(t, event.Error)
+
+	_, err = svc.replaceItem(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"token": "abc"},
+	})
+	require.NoError(t, err)
+	event = sink.last(t)
+	require.Equal(t, OpReplace, event.Operation)
+	require.Equal(t, []string{"password", "token"}, event.ChangedKeys)
+}
+
+func TestFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileAuditSink(path)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Audit(&AuditEvent{Operation: OpCreate, Vault: "vault1", Item: "secret1"}))
+	require.NoError(t, sink.Audit(&AuditEvent{Operation: OpDelete, Vault: "vault1"}))
+	require.NoError(t, sink.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var ops []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event AuditEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		ops = append(ops, event.Operation)
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []string{OpCreate, OpDelete}, ops)
+}
+
+func TestWebhookAuditSink(t *testing.T) {
+	secret := []byte("webhook-secret")
+	var gotBody []byte
+	var gotSignature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(WebhookSignatureHeader)
+		if strings.Contains(string(gotBody), "rejected") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	sink := &WebhookAuditSink{URL: srv.URL, Secret: secret}
+	require.NoError(t, sink.Audit(&AuditEvent{Operation: OpUpdate, Vault: "vault1", Item: "secret1"}))
+
+	var event AuditEvent
+	require.NoError(t, json.Unmarshal(gotBody, &event))
+	require.Equal(t, OpUpdate, event.Operation)
+	require.Equal(t, SignWebhook(secret, gotBody), gotSignature)
+	require.True(t, strings.HasPrefix(gotSignature, "sha256="))
+
+	err := sink.Audit(&AuditEvent{Operation: OpUpdate, Vault: "rejected"})
+	require.ErrorContains(t, err, "403")
+}
@@ -0,0 +1,238 @@
+package vault
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+)
+
+// Backend is the Chef Server API a Service runs against: data bag storage, actor public keys, client
+// lookup and partial search, together with the identity the Service acts as.
+//
+// Each request runs under the context it is given and should be abandoned once the context is done.
+// Missing and conflicting objects must be reported with errors recognised by cheferr.IsNotFound and
+// cheferr.IsConflict; backends that do not talk to a Chef Server can build them with cheferr.New.
+// ChefBackend, the implementation used by NewService, wraps a go-chef client.
+type Backend interface {
+	// ListDataBags lists the data bags, keyed by name.
+	ListDataBags(ctx context.Context) (*chef.DataBagListResult, error)
+
+	// CreateDataBag creates an empty data bag.
+	CreateDataBag(ctx context.Context, name string) error
+
+	// DeleteDataBag deletes a data bag and its items.
+	DeleteDataBag(ctx context.Context, name string) error
+
+	// ListDataBagItems lists the items of a data bag, keyed by id.
+	ListDataBagItems(ctx context.Context, bag string) (*chef.DataBagListResult, error)
+
+	// GetDataBagItem reads a data bag item.
+	GetDataBagItem(ctx context.Context, bag, id string) (chef.DataBagItem, error)
+
+	// CreateDataBagItem stores a new data bag item.
+	CreateDataBagItem(ctx context.Context, bag string, item chef.DataBagItem) error
+
+	// UpdateDataBagItem replaces an existing data bag item.
+	UpdateDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error
+
+	// DeleteDataBagItem deletes a data bag item.
+	DeleteDataBagItem(ctx context.Context, bag, id string) error
+
+	// UserKey returns the default public key of a user.
+	UserKey(ctx context.Context, name string) (chef.AccessKey, error)
+
+	// ClientKey returns the default public key of a client.
+	ClientKey(ctx context.Context, name string) (chef.AccessKey, error)
+
+	// ClientExists reports whether a client exists.
+	ClientExists(ctx context.Context, name string) (bool, error)
+
+	// PartialSearch returns the page of index rows matching query that starts at start, each row
+	// holding the fields selected by fields.
+	PartialSearch(ctx context.Context, index, query string, start int, fields map[string]interface{}) (*SearchResult, error)
+
+	// ActorName is the client or user name the Service acts as.
+	ActorName() string
+
+	// PrivateKey is the private key of the actor, used to decrypt the shared secrets of vault items.
+	PrivateKey() *rsa.PrivateKey
+
+	// BaseURL is the organization URL vault item URIs are resolved against.
+	BaseURL() *url.URL
+}
+
+// itemPutter is implemented by Backend wrappers that write a create-or-update of a data bag item as a unit.
+type itemPutter interface {
+	putDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error
+}
+
+// putDataBagItem creates a data bag item with b or, if it already exists, updates it.
+func putDataBagItem(ctx context.Context, b Backend, bag, id string, item chef.DataBagItem) error {
+	if p, ok := b.(itemPutter); ok {
+		return p.putDataBagItem(ctx, bag, id, item)
+	}
+
+	err := b.CreateDataBagItem(ctx, bag, item)
+	if cheferr.IsConflict(err) {
+		err = b.UpdateDataBagItem(ctx, bag, id, item)
+	}
+	return err
+}
+
+// SearchResult is one page of a partial search.
+type SearchResult struct {
+	// Total is the number of rows matching the query across all pages.
+	Total int
+
+	// Rows holds the selected fields of each row of the page.
+	Rows []json.RawMessage
+}
+
+// ChefBackend is the Backend backed by a go-chef client. It issues the requests of the go-chef
+// services it mirrors, bound to the context of each call.
+type ChefBackend struct {
+	Client *chef.Client
+}
+
+// NewChefBackend returns a Backend issuing its requests with client.
+func NewChefBackend(client *chef.Client) *ChefBackend {
+	return &ChefBackend{Client: client}
+}
+
+// do sends a signed request for path, with in encoded as its JSON body when not nil, and decodes the
+// response into out when not nil.
+func (b *ChefBackend) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		var err error
+		if body, err = chef.JSONReader(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := b.Client.NewRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+
+	res, err := b.Client.Do(req.WithContext(ctx), out)
+	if res != nil {
+		defer func() { _ = res.Body.Close() }()
+	}
+	return err
+}
+
+// ListDataBags implements Backend.
+func (b *ChefBackend) ListDataBags(ctx context.Context) (data *chef.DataBagListResult, err error) {
+	err = b.do(ctx, http.MethodGet, "data", nil, &data)
+	return data, err
+}
+
+// CreateDataBag implements Backend.
+func (b *ChefBackend) CreateDataBag(ctx context.Context, name string) error {
+	return b.do(ctx, http.MethodPost, "data", &chef.DataBag{Name: name}, nil)
+}
+
+// DeleteDataBag implements Backend.
+func (b *ChefBackend) DeleteDataBag(ctx context.Context, name string) error {
+	return b.do(ctx, http.MethodDelete, fmt.Sprintf("data/%s", name), nil, nil)
+}
+
+// ListDataBagItems implements Backend.
+func (b *ChefBackend) ListDataBagItems(ctx context.Context, bag string) (data *chef.DataBagListResult, err error) {
+	err = b.do(ctx, http.MethodGet, fmt.Sprintf("data/%s", bag), nil, &data)
+	return data, err
+}
+
+// GetDataBagItem implements Backend.
+func (b *ChefBackend) GetDataBagItem(ctx context.Context, bag, id string) (item chef.DataBagItem, err error) {
+	err = b.do(ctx, http.MethodGet, fmt.Sprintf("data/%s/%s", bag, id), nil, &item)
+	return item, err
+}
+
+// CreateDataBagItem implements Backend.
+func (b *ChefBackend) CreateDataBagItem(ctx context.Context, bag string, item chef.DataBagItem) error {
+	return b.do(ctx, http.MethodPost, fmt.Sprintf("data/%s", bag), item, nil)
+}
+
+// UpdateDataBagItem implements Backend.
+func (b *ChefBackend) UpdateDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error {
+	return b.do(ctx, http.MethodPut, fmt.Sprintf("data/%s/%s", bag, id), item, nil)
+}
+
+// DeleteDataBagItem implements Backend.
+func (b *ChefBackend) DeleteDataBagItem(ctx context.Context, bag, id string) error {
+	return b.do(ctx, http.MethodDelete, fmt.Sprintf("data/%s/%s", bag, id), nil, nil)
+}
+
+// UserKey implements Backend.
+func (b *ChefBackend) UserKey(ctx context.Context, name string) (key chef.AccessKey, err error) {
+	err = b.do(ctx, http.MethodGet, fmt.Sprintf("users/%s/keys/default", name), nil, &key)
+	return key, err
+}
+
+// ClientKey implements Backend.
+func (b *ChefBackend) ClientKey(ctx context.Context, name string) (key chef.AccessKey, err error) {
+	err = b.do(ctx, http.MethodGet, fmt.Sprintf("clients/%s/keys/default", name), nil, &key)
+	return key, err
+}
+
+// ClientExists implements Backend.
+func (b *ChefBackend) ClientExists(ctx context.Context, name string) (bool, error) {
+	var client chef.ApiClient
+	err := b.do(ctx, http.MethodGet, fmt.Sprintf("clients/%s", name), nil, &client)
+	if err == nil {
+		return true, nil
+	}
+
+	if cheferr.IsNotFound(err) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// PartialSearch implements Backend.
+func (b *ChefBackend) PartialSearch(ctx context.Context, index, query string, start int, fields map[string]interface{}) (*SearchResult, error) {
+	q, err := b.Client.Search.NewQuery(index, query)
+	if err != nil {
+		return nil, err
+	}
+	q.Start = start
+
+	var result chef.JSearchResult
+	if err := b.do(ctx, http.MethodPost, fmt.Sprintf("search/%s", q), fields, &result); err != nil {
+		return nil, err
+	}
+
+	out := &SearchResult{
+		Total: result.Total,
+		Rows:  make([]json.RawMessage, 0, len(result.Rows)),
+	}
+	for _, row := range result.Rows {
+		out.Rows = append(out.Rows, row.Data)
+	}
+	return out, nil
+}
+
+// ActorName implements Backend.
+func (b *ChefBackend) ActorName() string {
+	return b.Client.Auth.ClientName
+}
+
+// PrivateKey implements Backend.
+func (b *ChefBackend) PrivateKey() *rsa.PrivateKey {
+	return b.Client.Auth.PrivateKey
+}
+
+// BaseURL implements Backend.
+func (b *ChefBackend) BaseURL() *url.URL {
+	return b.Client.BaseURL
+}
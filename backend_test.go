@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Backend = (*ChefBackend)(nil)
+
+func TestService_WithBackend(t *testing.T) {
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+	query := "name:*"
+
+	res, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+		SearchQuery:   &query,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "memory://chef/data/vault1", res.URI)
+
+	keys, err := backend.GetDataBagItem(context.Background(), "vault1", "secret1_keys")
+	require.NoError(t, err)
+	require.Contains(t, keys, "node1")
+	require.Contains(t, keys, userid)
+
+	got, err := svc.GetItem("vault1", "secret1")
+	require.NoError(t, err)
+	content, err := item.DataBagItemMap(got)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", content["password"])
+
+	_, err = svc.Update(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"user": "app"},
+	})
+	require.NoError(t, err)
+
+	got, err = svc.GetItem("vault1", "secret1")
+	require.NoError(t, err)
+	content, err = item.DataBagItemMap(got)
+	require.NoError(t, err)
+	require.Equal(t, "app", content["user"])
+
+	_, err = svc.DeleteItem("vault1", "secret1")
+	require.NoError(t, err)
+	_, err = svc.GetItem("vault1", "secret1")
+	require.True(t, cheferr.IsNotFound(err))
+
+	require.Contains(t, backend.calls, "PartialSearch")
+	require.Contains(t, backend.calls, "UserKey")
+	require.Contains(t, backend.calls, "ClientKey")
+}
+
+func TestService_BackendDefaultsToClient(t *testing.T) {
+	setupStubs(t)
+
+	svc := &Service{Client: client}
+	require.IsType(t, &ChefBackend{}, svc.backend())
+	require.Equal(t, userid, svc.backend().ActorName())
+
+	query := "name:testhost*"
+	clients, err := svc.getClientsFromSearch(context.Background(), &Payload{SearchQuery: &query})
+	require.NoError(t, err)
+	require.Equal(t, []string{"testhost", "testhost3", "testhost4"}, clients)
+}
+
+func TestChefBackend_ClientExists(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	mux.HandleFunc("/clients/node1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"node1"}`))
+	})
+
+	b := NewChefBackend(client)
+
+	ok, err := b.ClientExists(context.Background(), "node1")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = b.ClientExists(context.Background(), "node2")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestChefBackend_Context(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	release := make(chan struct{})
+	mux.HandleFunc("/data/vault1/secret1", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	t.Cleanup(func() { close(release) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := NewChefBackend(client).GetDataBagItem(ctx, "vault1", "secret1")
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,456 @@
+package vault
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+)
+
+const (
+	// backupFormat identifies a backup archive.
+	backupFormat = "go-chef-vault-backup"
+
+	// backupVersion is the archive format version written by Backup.
+	backupVersion = 1
+)
+
+// BackupResponse represents the structure of the response from a Backup operation.
+type BackupResponse struct {
+	Vaults  int      `json:"vaults"`
+	Items   int      `json:"items"`
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// RestoreOptions controls which items Restore recreates and how.
+type RestoreOptions struct {
+	// Include restricts the restore to items whose "vault/item" name matches one of these path.Match patterns.
+	Include []string
+
+	// Exclude skips items whose "vault/item" name matches one of these path.Match patterns.
+	Exclude []string
+
+	// Admins are granted access to every restored item in addition to the admins recorded in the backup,
+	// which may not exist on the target server.
+	Admins []string
+
+	// DryRun reports what would be created or updated without writing anything.
+	DryRun bool
+}
+
+// RestoreResponse represents the structure of the response from a Restore operation.
+type RestoreResponse struct {
+	ImportResponse
+	BackupCreatedAt time.Time `json:"backup_created_at"`
+	DryRun          bool      `json:"dry_run,omitempty"`
+}
+
+// backupEnvelope is the outer, plaintext structure of a backup archive. The archive is sealed with
+// AES-256-GCM under a random data key, which is wrapped with RSA-OAEP for each recipient.
+type backupEnvelope struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Recipients []backupRecipient `json:"recipients"`
+	Nonce      []byte            `json:"nonce"`
+	Ciphertext []byte            `json:"ciphertext"`
+}
+
+// backupRecipient is the data key wrapped for a single backup public key.
+type backupRecipient struct {
+	Fingerprint string `json:"fingerprint"`
+	Key         []byte `json:"key"`
+}
+
+// backupArchive is the decrypted content of a backup archive.
+type backupArchive struct {
+	CreatedAt time.Time     `json:"created_at"`
+	Server    string        `json:"server"`
+	Actor     string        `json:"actor"`
+	Vaults    []backupVault `json:"vaults"`
+}
+
+// backupVault is a vault and the items captured from it.
+type backupVault struct {
+	Name  string       `json:"name"`
+	Items []backupItem `json:"items"`
+}
+
+// backupItem is the decrypted content and access metadata of a vault item.
+type backupItem struct {
+	Name        string                 `json:"name"`
+	Content     map[string]interface{} `json:"content"`
+	Admins      []string               `json:"admins"`
+	Clients     []string               `json:"clients"`
+	SearchQuery *string                `json:"search_query,omitempty"`
+	KeysMode    item_keys.KeysMode     `json:"mode"`
+}
+
+// Backup decrypts every vault item the caller can read and writes them, with their admins, clients,
+// search query, and keys mode, to w as an archive encrypted to the given RSA public keys. Items the
+// caller is not an actor of are listed in BackupResponse.Skipped.
+//
+// The archive can only be read by Restore with the private key of one of the recipients.
+func (s *Service) Backup(w io.Writer, recipients []*rsa.PublicKey) (resp *BackupResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.backup", Attribute{"recipients", len(recipients)})
+	defer sp.end(&err)
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("vault: backup requires at least one recipient")
+	}
+
+	archive, result, err := s.collectBackup(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, err := sealBackup(archive, recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.NewEncoder(w).Encode(envelope); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Restore decrypts a Backup archive with privateKey and recreates its vault items on the server
+// this Service is connected to, creating missing items and updating those that differ as Import does.
+func (s *Service) Restore(r io.Reader, privateKey *rsa.PrivateKey, opts *RestoreOptions) (resp *RestoreResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.restore")
+	defer sp.end(&err)
+
+	if privateKey == nil {
+		return nil, fmt.Errorf("vault: restore requires a private key")
+	}
+
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+
+	ops := importOps{
+		loadKeys: s.loadKeysCurrentState,
+		getItem:  s.GetItemContext,
+		create:   s.CreateContext,
+		replace:  s.replaceItem,
+	}
+	if opts.DryRun {
+		ops.create = func(_ context.Context, p *Payload) (*CreateResponse, error) {
+			return &CreateResponse{
+				Data: &CreateDataResponse{URI: fmt.Sprintf("%s/%s", s.vaultURL(p.VaultName), p.VaultItemName)},
+			}, nil
+		}
+		ops.replace = func(context.Context, *Payload) (*UpdateResponse, error) {
+			return &UpdateResponse{}, nil
+		}
+	}
+
+	return s.restore(ctx, r, privateKey, opts, ops)
+}
+
+// restore is the worker called by the public API with the operational methods to complete the restore request.
+func (s *Service) restore(ctx context.Context, r io.Reader, privateKey *rsa.PrivateKey, opts *RestoreOptions, ops importOps) (*RestoreResponse, error) {
+	var envelope backupEnvelope
+	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	archive, err := openBackup(&envelope, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := restoreManifest(archive, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	imported, err := s.importManifest(ctx, manifest, ops)
+	if imported == nil {
+		return nil, err
+	}
+
+	return &RestoreResponse{
+		ImportResponse:  *imported,
+		BackupCreatedAt: archive.CreatedAt,
+		DryRun:          opts.DryRun,
+	}, err
+}
+
+// collectBackup reads and decrypts every vault item the caller is an actor of.
+func (s *Service) collectBackup(ctx context.Context) (*backupArchive, *BackupResponse, error) {
+	vaults, err := s.ListContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	archive := &backupArchive{
+		CreatedAt: time.Now().UTC(),
+		Server:    s.backend().BaseURL().String(),
+		Actor:     s.backend().ActorName(),
+	}
+	result := &BackupResponse{}
+
+	for _, vaultName := range slices.Sorted(maps.Keys(*vaults)) {
+		items, err := s.ListItemsContext(ctx, vaultName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		bv := backupVault{Name: vaultName, Items: make([]backupItem, 0)}
+		for _, itemName := range slices.Sorted(maps.Keys(*items)) {
+			bi, reason, err := s.readBackupItem(ctx, vaultName, itemName)
+			if err != nil {
+				return nil, nil, err
+			}
+			if bi == nil {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s/%s: %s", vaultName, itemName, reason))
+				continue
+			}
+			bv.Items = append(bv.Items, *bi)
+		}
+
+		if len(bv.Items) != 0 {
+			archive.Vaults = append(archive.Vaults, bv)
+			result.Vaults++
+			result.Items += len(bv.Items)
+		}
+	}
+
+	return archive, result, nil
+}
+
+// readBackupItem decrypts a single vault item, returning a nil item and the reason when it cannot be backed up.
+func (s *Service) readBackupItem(ctx context.Context, vaultName, itemName string) (*backupItem, string, error) {
+	pl := &Payload{VaultName: vaultName, VaultItemName: itemName}
+
+	keyState, err := s.loadKeysCurrentState(ctx, pl)
+	if err != nil {
+		if cheferr.IsNotFound(err) {
+			return nil, "not a vault item", nil
+		}
+		return nil, "", err
+	}
+
+	actor := s.backend().ActorName()
+	if !slices.Contains(keyState.Admins, actor) && !slices.Contains(keyState.Clients, actor) {
+		return nil, "not encrypted for " + actor, nil
+	}
+
+	current, err := s.GetItemContext(ctx, vaultName, itemName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	content, err := item.DataBagItemMap(current)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mode := keyState.Mode
+	if mode == "" {
+		mode = item_keys.KeysModeDefault
+	}
+
+	return &backupItem{
+		Name:        itemName,
+		Content:     content,
+		Admins:      keyState.Admins,
+		Clients:     keyState.Clients,
+		SearchQuery: item_keys.NormalizeSearchQuery(keyState.SearchQuery),
+		KeysMode:    mode,
+	}, "", nil
+}
+
+// restoreManifest converts the selected archive items into an import manifest.
+func restoreManifest(archive *backupArchive, opts *RestoreOptions) (*Manifest, error) {
+	m := &Manifest{}
+	for _, bv := range archive.Vaults {
+		mv := ManifestVault{Name: bv.Name, Admins: opts.Admins}
+		for _, bi := range bv.Items {
+			selected, err := restoreSelected(bv.Name+"/"+bi.Name, opts)
+			if err != nil {
+				return nil, err
+			}
+			if !selected {
+				continue
+			}
+
+			mv.Items = append(mv.Items, ManifestItem{
+				Name:        bi.Name,
+				Content:     bi.Content,
+				Admins:      bi.Admins,
+				Clients:     bi.Clients,
+				SearchQuery: bi.SearchQuery,
+				KeysMode:    bi.KeysMode,
+			})
+		}
+		if len(mv.Items) != 0 {
+			m.Vaults = append(m.Vaults, mv)
+		}
+	}
+	return m, nil
+}
+
+// restoreSelected applies the include and exclude patterns to a "vault/item" name.
+func restoreSelected(name string, opts *RestoreOptions) (bool, error) {
+	for _, pattern := range opts.Exclude {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return false, nil
+		}
+	}
+
+	if len(opts.Include) == 0 {
+		return true, nil
+	}
+
+	for _, pattern := range opts.Include {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sealBackup compresses and encrypts the archive, wrapping the data key for each recipient.
+func sealBackup(archive *backupArchive, recipients []*rsa.PublicKey) (*backupEnvelope, error) {
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	if err := json.NewEncoder(zw).Encode(archive); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	dataKey, err := item_keys.GenSecret(32)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newBackupCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := item_keys.GenSecret(gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &backupEnvelope{
+		Format:     backupFormat,
+		Version:    backupVersion,
+		CreatedAt:  archive.CreatedAt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plain.Bytes(), []byte(backupFormat)),
+	}
+
+	for _, pub := range recipients {
+		fingerprint, err := keyFingerprint(pub)
+		if err != nil {
+			return nil, err
+		}
+
+		wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, []byte(backupFormat))
+		if err != nil {
+			return nil, err
+		}
+		envelope.Recipients = append(envelope.Recipients, backupRecipient{Fingerprint: fingerprint, Key: wrapped})
+	}
+
+	return envelope, nil
+}
+
+// openBackup unwraps the data key with privateKey and decrypts the archive.
+func openBackup(envelope *backupEnvelope, privateKey *rsa.PrivateKey) (*backupArchive, error) {
+	if envelope.Format != backupFormat || envelope.Version != backupVersion {
+		return nil, fmt.Errorf("vault: unsupported backup format %q version %d", envelope.Format, envelope.Version)
+	}
+
+	fingerprint, err := keyFingerprint(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var dataKey []byte
+	for _, r := range envelope.Recipients {
+		if r.Fingerprint == fingerprint {
+			dataKey, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, r.Key, []byte(backupFormat))
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if dataKey == nil {
+		return nil, ErrNotBackupRecipient
+	}
+
+	gcm, err := newBackupCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := gcm.Open(nil, envelope.Nonce, envelope.Ciphertext, []byte(backupFormat))
+	if err != nil {
+		return nil, fmt.Errorf("vault: backup archive is corrupt: %w", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var archive backupArchive
+	if err := json.NewDecoder(zr).Decode(&archive); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// newBackupCipher returns the AES-256-GCM cipher for a data key.
+func newBackupCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyFingerprint returns the hex SHA-256 of the PKIX encoding of a public key.
+func keyFingerprint(pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
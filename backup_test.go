@@ -0,0 +1,141 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/stretchr/testify/require"
+)
+
+// putVaultItem stores a vault item in the memory backend encrypted for the listed admins,
+// only the backend's actor receiving a usable key.
+func putVaultItem(t *testing.T, store *memoryBackend, bag, name string, content map[string]interface{}, admins ...string) {
+	t.Helper()
+
+	secret, err := item_keys.GenSecret(32)
+	require.NoError(t, err)
+
+	encrypted, err := item.Encrypt(name, content, secret)
+	require.NoError(t, err)
+	store.seed(bag, name, encrypted)
+
+	keys := map[string]interface{}{
+		"id":           name + "_keys",
+		"admins":       admins,
+		"clients":      []string{"node1"},
+		"search_query": "role:web",
+		"mode":         "default",
+	}
+	if slices.Contains(admins, userid) {
+		keys[userid], err = item_keys.EncryptSharedSecret(store.publicKeyPEM(t), secret)
+		require.NoError(t, err)
+	}
+	store.seed(bag, name+"_keys", keys)
+}
+
+func TestService_BackupRestore_RoundTrip(t *testing.T) {
+	store := newMemoryBackend(t)
+	svc := NewServiceWithBackend(store)
+
+	putVaultItem(t, store, "vault1", "db", map[string]interface{}{"pass": "plaintext-password"}, userid)
+	putVaultItem(t, store, "vault1", "other", map[string]interface{}{"pass": "p2"}, "someone-else")
+	putVaultItem(t, store, "vault2", "api", map[string]interface{}{"token": "t1"}, userid, "ops")
+	store.seed("plain", "config", map[string]interface{}{"id": "config"})
+
+	recipient, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	resp, err := svc.Backup(&buf, []*rsa.PublicKey{&recipient.PublicKey})
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Vaults)
+	require.Equal(t, 2, resp.Items)
+	require.Equal(t, []string{"vault1/other: not encrypted for tester"}, resp.Skipped)
+	require.NotContains(t, buf.String(), "plaintext-password")
+
+	var envelope backupEnvelope
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &envelope))
+	archive, err := openBackup(&envelope, recipient)
+	require.NoError(t, err)
+	require.Len(t, archive.Vaults, 2)
+
+	api := archive.Vaults[1].Items[0]
+	require.Equal(t, "api", api.Name)
+	require.Equal(t, "t1", api.Content["token"])
+	require.ElementsMatch(t, []string{userid, "ops"}, api.Admins)
+	require.Equal(t, []string{"node1"}, api.Clients)
+	require.Equal(t, "role:web", *api.SearchQuery)
+	require.Equal(t, item_keys.KeysModeDefault, api.KeysMode)
+
+	rec := &importRecorder{keys: map[string]*item_keys.VaultItemKeys{}, content: map[string]chef.DataBagItem{}}
+	restored, err := svc.restore(context.Background(), bytes.NewReader(buf.Bytes()), recipient, &RestoreOptions{
+		Include: []string{"vault2/*"},
+		Admins:  []string{"restorer"},
+	}, rec.ops())
+	require.NoError(t, err)
+	require.Equal(t, 1, restored.Created)
+	require.Equal(t, archive.CreatedAt, restored.BackupCreatedAt)
+
+	require.Len(t, rec.created, 1)
+	created := rec.created[0]
+	require.Equal(t, "vault2", created.VaultName)
+	require.Equal(t, "api", created.VaultItemName)
+	require.ElementsMatch(t, []string{userid, "ops", "restorer"}, created.Admins)
+	require.Equal(t, "role:web", *created.SearchQuery)
+	require.Equal(t, map[string]interface{}{"id": "api", "token": "t1"}, created.Content)
+}
+
+func TestService_Restore_DryRun(t *testing.T) {
+	store := newMemoryBackend(t)
+	svc := NewServiceWithBackend(store)
+
+	recipient, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	envelope, err := sealBackup(&backupArchive{Vaults: []backupVault{
+		{Name: "vault1", Items: []backupItem{
+			{Name: "db", Content: map[string]interface{}{"pass": "p1"}, Admins: []string{userid}},
+			{Name: "skip", Content: map[string]interface{}{}, Admins: []string{userid}},
+		}},
+	}}, []*rsa.PublicKey{&recipient.PublicKey})
+	require.NoError(t, err)
+	b, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	resp, err := svc.Restore(bytes.NewReader(b), recipient, &RestoreOptions{Exclude: []string{"*/skip"}, DryRun: true})
+	require.NoError(t, err)
+	require.True(t, resp.DryRun)
+	require.Equal(t, 1, resp.Created)
+	require.Equal(t, "vault1", resp.Items[0].VaultName)
+	require.False(t, store.has("vault1", "db"))
+}
+
+func TestService_Restore_WrongKey(t *testing.T) {
+	recipient, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	envelope, err := sealBackup(&backupArchive{}, []*rsa.PublicKey{&recipient.PublicKey})
+	require.NoError(t, err)
+
+	_, err = openBackup(envelope, other)
+	require.ErrorIs(t, err, ErrNotBackupRecipient)
+
+	envelope.Ciphertext[0] ^= 0xff
+	_, err = openBackup(envelope, recipient)
+	require.ErrorContains(t, err, "corrupt")
+}
+
+func TestService_Backup_NoRecipients(t *testing.T) {
+	_, err := service.Backup(&bytes.Buffer{}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,347 @@
+package vault
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chef/chef"
+)
+
+const (
+	// DefaultCacheTTL is how long a cached shared secret is trusted when CacheOptions.TTL is zero.
+	DefaultCacheTTL = 5 * time.Minute
+
+	// DefaultCacheSize is the number of items cached when CacheOptions.MaxEntries is zero.
+	DefaultCacheSize = 1000
+)
+
+// CacheOptions configures an ItemCache.
+type CacheOptions struct {
+	// TTL bounds how long an item's decrypted shared secret is reused before the keys are read and
+	// decrypted again, and so how long a revoked actor may keep reading through the cache. Defaults
+	// to DefaultCacheTTL.
+	TTL time.Duration
+
+	// MaxEntries is the number of items kept; the least recently used item is evicted first.
+	// Defaults to DefaultCacheSize.
+	MaxEntries int
+}
+
+// CacheStats counts the outcomes of cached reads.
+type CacheStats struct {
+	// Hits are reads answered without decrypting anything, the encrypted item being unchanged.
+	Hits int
+
+	// Revalidations are reads of a changed item decrypted with the cached shared secret.
+	Revalidations int
+
+	// Misses are reads that loaded and decrypted the item's keys.
+	Misses int
+
+	// Evictions are entries dropped to stay within MaxEntries.
+	Evictions int
+}
+
+// ItemCache is a read-through cache of decrypted vault items, used by a Service whose Cache is set.
+//
+// Each entry holds an item's decrypted shared secret and content together with a digest of the
+// encrypted item. A cached read still fetches the encrypted item, but skips the keys items and the
+// RSA decryption: an unchanged item is answered from the cache, and a changed one is decrypted with
+// the cached secret. Writes made through the Service invalidate the items they touch; Invalidate,
+// InvalidateVault and Purge cover writes made elsewhere.
+type ItemCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   CacheStats
+}
+
+// cacheEntry is a cached vault item.
+type cacheEntry struct {
+	vaultName string
+	vaultItem string
+	secret    []byte
+	digest    [sha256.Size]byte
+	content   []byte
+	expires   time.Time
+}
+
+// NewItemCache returns an empty ItemCache.
+func NewItemCache(opts *CacheOptions) *ItemCache {
+	c := &ItemCache{
+		ttl:        DefaultCacheTTL,
+		maxEntries: DefaultCacheSize,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+
+	if opts != nil && opts.TTL > 0 {
+		c.ttl = opts.TTL
+	}
+	if opts != nil && opts.MaxEntries > 0 {
+		c.maxEntries = opts.MaxEntries
+	}
+	return c
+}
+
+// Invalidate drops a vault item from the cache.
+func (c *ItemCache) Invalidate(vaultName, vaultItem string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[cacheKey(vaultName, vaultItem)]; ok {
+		c.remove(el)
+	}
+}
+
+// InvalidateVault drops every item of a vault from the cache.
+func (c *ItemCache) InvalidateVault(vaultName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, el := range c.entries {
+		if el.Value.(*cacheEntry).vaultName == vaultName {
+			c.remove(el)
+		}
+	}
+}
+
+// Purge empties the cache.
+func (c *ItemCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns the number of cached items.
+func (c *ItemCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the counts of cached reads so far.
+func (c *ItemCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// lookup returns an unexpired entry for a vault item, marking it recently used.
+func (c *ItemCache) lookup(vaultName, vaultItem string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[cacheKey(vaultName, vaultItem)]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*cacheEntry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+// store caches a vault item, evicting the least recently used items beyond maxEntries. A
+// revalidated entry keeps the expiry of the secret it was decrypted with.
+func (c *ItemCache) store(e *cacheEntry, revalidated bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if revalidated {
+		c.stats.Revalidations++
+	} else {
+		c.stats.Misses++
+		e.expires = c.now().Add(c.ttl)
+	}
+
+	key := cacheKey(e.vaultName, e.vaultItem)
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// hit records a read answered from the cache.
+func (c *ItemCache) hit() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Hits++
+}
+
+// invalidateWrite drops the cached items a write to a data bag item affects: the item itself, or the
+// item whose _keys or _key_<actor> item was written.
+func (c *ItemCache) invalidateWrite(vaultName, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, el := range c.entries {
+		e := el.Value.(*cacheEntry)
+		if e.vaultName != vaultName {
+			continue
+		}
+		if id == e.vaultItem || id == e.vaultItem+"_keys" || strings.HasPrefix(id, e.vaultItem+"_key_") {
+			c.remove(el)
+		}
+	}
+}
+
+// remove drops an entry. It is called with c.mu held.
+func (c *ItemCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, cacheKey(e.vaultName, e.vaultItem))
+}
+
+// cacheKey returns the key of a vault item in the cache.
+func cacheKey(vaultName, vaultItem string) string {
+	return vaultName + "/" + vaultItem
+}
+
+// getCachedItem reads a vault item through s.Cache. The encrypted item is always fetched; its keys
+// are read and decrypted only when the item is not cached, the cached entry has expired, or the
+// cached secret no longer decrypts it.
+func (s *Service) getCachedItem(ctx context.Context, vaultName, vaultItem string, ops getOps) (chef.DataBagItem, error) {
+	rawItem, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(rawItem)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(encoded)
+
+	if cached, ok := s.Cache.lookup(vaultName, vaultItem); ok {
+		if cached.digest == digest {
+			s.Cache.hit()
+			return decodeCached(cached.content)
+		}
+
+		// the item was rewritten; it is usually still encrypted with the same shared secret.
+		if decrypted, err := ops.decrypt(rawItem, cached.secret); err == nil {
+			return s.cacheItem(&cacheEntry{
+				vaultName: vaultName,
+				vaultItem: vaultItem,
+				secret:    cached.secret,
+				digest:    digest,
+				expires:   cached.expires,
+			}, decrypted, true)
+		}
+	}
+
+	actorKey, err := s.loadActorKey(ctx, vaultName, vaultItem)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ops.deriveAESKey(actorKey, s.backend().PrivateKey())
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := ops.decrypt(rawItem, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cacheItem(&cacheEntry{
+		vaultName: vaultName,
+		vaultItem: vaultItem,
+		secret:    secret,
+		digest:    digest,
+	}, decrypted, false)
+}
+
+// cacheItem stores the decrypted content in e, caches e, and returns a copy of the content for the caller.
+func (s *Service) cacheItem(e *cacheEntry, decrypted chef.DataBagItem, revalidated bool) (chef.DataBagItem, error) {
+	content, err := json.Marshal(decrypted)
+	if err != nil {
+		return nil, err
+	}
+	e.content = content
+	s.Cache.store(e, revalidated)
+
+	return decodeCached(content)
+}
+
+// decodeCached returns a fresh copy of cached content, so callers cannot modify the cache.
+func decodeCached(content []byte) (chef.DataBagItem, error) {
+	var out map[string]interface{}
+	if err := json.Unmarshal(content, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// cacheBackend is the Backend of a Service with a Cache. It invalidates the cached items its writes affect.
+type cacheBackend struct {
+	Backend
+	cache *ItemCache
+}
+
+// DeleteDataBag implements Backend.
+func (b *cacheBackend) DeleteDataBag(ctx context.Context, name string) error {
+	defer b.cache.InvalidateVault(name)
+	return b.Backend.DeleteDataBag(ctx, name)
+}
+
+// CreateDataBagItem implements Backend.
+func (b *cacheBackend) CreateDataBagItem(ctx context.Context, bag string, item chef.DataBagItem) error {
+	defer b.cache.invalidateWrite(bag, dataBagItemID(item))
+	return b.Backend.CreateDataBagItem(ctx, bag, item)
+}
+
+// UpdateDataBagItem implements Backend.
+func (b *cacheBackend) UpdateDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error {
+	defer b.cache.invalidateWrite(bag, id)
+	return b.Backend.UpdateDataBagItem(ctx, bag, id, item)
+}
+
+// DeleteDataBagItem implements Backend.
+func (b *cacheBackend) DeleteDataBagItem(ctx context.Context, bag, id string) error {
+	defer b.cache.invalidateWrite(bag, id)
+	return b.Backend.DeleteDataBagItem(ctx, bag, id)
+}
+
+// putDataBagItem implements itemPutter.
+func (b *cacheBackend) putDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error {
+	defer b.cache.invalidateWrite(bag, id)
+	return putDataBagItem(ctx, b.Backend, bag, id, item)
+}
+
+// dataBagItemID returns the id of a data bag item, which callers pass as maps, pointers to maps or structs.
+func dataBagItemID(item chef.DataBagItem) string {
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		return ""
+	}
+
+	var v struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(encoded, &v)
+	return v.ID
+}
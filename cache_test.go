@@ -0,0 +1,178 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/stretchr/testify/require"
+)
+
+// newCachedService returns a Service with a cache driven by a fake clock, and a vault1/secret1 item
+// created through it.
+func newCachedService(t *testing.T, opts *CacheOptions) (*Service, *memoryBackend, *time.Time) {
+	t.Helper()
+
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.Cache = NewItemCache(opts)
+	svc.Cache.now = func() time.Time { return now }
+
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+	})
+	require.NoError(t, err)
+	return svc, backend, &now
+}
+
+func requireCachedContent(t *testing.T, svc *Service, vaultItem, key, want string) {
+	t.Helper()
+
+	got, err := svc.GetItem("vault1", vaultItem)
+	require.NoError(t, err)
+	content, err := item.DataBagItemMap(got)
+	require.NoError(t, err)
+	require.Equal(t, want, content[key])
+}
+
+func TestItemCache_ReadThrough(t *testing.T) {
+	svc, backend, _ := newCachedService(t, nil)
+
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+	require.Equal(t, CacheStats{Misses: 1}, svc.Cache.Stats())
+
+	// a hit fetches the encrypted item only, not its keys.
+	backend.calls = nil
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+	require.Equal(t, CacheStats{Hits: 1, Misses: 1}, svc.Cache.Stats())
+	require.Equal(t, []string{"GetDataBagItem"}, backend.calls)
+
+	// callers cannot modify the cached content.
+	got, err := svc.GetItem("vault1", "secret1")
+	require.NoError(t, err)
+	got.(map[string]interface{})["password"] = "changed"
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+}
+
+func TestItemCache_Revalidate(t *testing.T) {
+	svc, backend, _ := newCachedService(t, nil)
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+
+	// the item is rewritten elsewhere with the same shared secret.
+	secret := svc.Cache.entries[cacheKey("vault1", "secret1")].Value.(*cacheEntry).secret
+	encrypted, err := item.Encrypt("secret1", map[string]interface{}{"password": "swordfish"}, secret)
+	require.NoError(t, err)
+	require.NoError(t, backend.UpdateDataBagItem(context.Background(), "vault1", "secret1", encrypted))
+
+	backend.calls = nil
+	requireCachedContent(t, svc, "secret1", "password", "swordfish")
+	require.Equal(t, CacheStats{Misses: 1, Revalidations: 1}, svc.Cache.Stats())
+	require.Equal(t, []string{"GetDataBagItem"}, backend.calls)
+
+	// rewritten elsewhere with a new secret, the item's keys are read again.
+	other := NewServiceWithBackend(backend)
+	_, err = other.Update(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "letmein"},
+	})
+	require.NoError(t, err)
+
+	requireCachedContent(t, svc, "secret1", "password", "letmein")
+	require.Equal(t, CacheStats{Misses: 2, Revalidations: 1}, svc.Cache.Stats())
+}
+
+func TestItemCache_TTL(t *testing.T) {
+	svc, _, now := newCachedService(t, &CacheOptions{TTL: time.Minute})
+
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+	*now = now.Add(59 * time.Second)
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+	require.Equal(t, CacheStats{Hits: 1, Misses: 1}, svc.Cache.Stats())
+
+	*now = now.Add(time.Second)
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+	require.Equal(t, CacheStats{Hits: 1, Misses: 2}, svc.Cache.Stats())
+}
+
+func TestItemCache_Eviction(t *testing.T) {
+	svc, _, _ := newCachedService(t, &CacheOptions{MaxEntries: 2})
+
+	for _, name := range []string{"secret2", "secret3"} {
+		_, err := svc.Create(&Payload{
+			VaultName:     "vault1",
+			VaultItemName: name,
+			Content:       map[string]interface{}{"password": name},
+			Admins:        []string{userid},
+		})
+		require.NoError(t, err)
+	}
+
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+	requireCachedContent(t, svc, "secret2", "password", "secret2")
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+	requireCachedContent(t, svc, "secret3", "password", "secret3")
+
+	// secret2 was the least recently used.
+	require.Equal(t, 2, svc.Cache.Len())
+	require.Equal(t, 1, svc.Cache.Stats().Evictions)
+	_, ok := svc.Cache.lookup("vault1", "secret2")
+	require.False(t, ok)
+	_, ok = svc.Cache.lookup("vault1", "secret1")
+	require.True(t, ok)
+}
+
+func TestItemCache_Invalidation(t *testing.T) {
+	svc, _, _ := newCachedService(t, nil)
+
+	requireCachedContent(t, svc, "secret1", "password", "hunter2")
+	require.Equal(t, 1, svc.Cache.Len())
+
+	_, err := svc.Update(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "swordfish"},
+	})
+	require.NoError(t, err)
+	require.Zero(t, svc.Cache.Len())
+
+	requireCachedContent(t, svc, "secret1", "password", "swordfish")
+	require.Equal(t, 1, svc.Cache.Len())
+
+	_, err = svc.DeleteItem("vault1", "secret1")
+	require.NoError(t, err)
+	require.Zero(t, svc.Cache.Len())
+
+	_, err = svc.GetItem("vault1", "secret1")
+	require.True(t, cheferr.IsNotFound(err))
+}
+
+func TestItemCache_InvalidateWrite(t *testing.T) {
+	c := NewItemCache(nil)
+	for _, name := range []string{"db", "db_admin", "web"} {
+		c.store(&cacheEntry{vaultName: "vault1", vaultItem: name}, false)
+	}
+	c.store(&cacheEntry{vaultName: "vault2", vaultItem: "db"}, false)
+
+	c.invalidateWrite("vault1", "db_keys")
+	require.Equal(t, 3, c.Len())
+	_, ok := c.lookup("vault1", "db")
+	require.False(t, ok)
+
+	c.invalidateWrite("vault1", "db_admin_key_tester")
+	_, ok = c.lookup("vault1", "db_admin")
+	require.False(t, ok)
+
+	c.Invalidate("vault1", "web")
+	require.Equal(t, 1, c.Len())
+
+	c.InvalidateVault("vault2")
+	require.Zero(t, c.Len())
+}
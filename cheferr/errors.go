@@ -2,11 +2,30 @@ package cheferr
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/go-chef/chef"
 )
 
+// Error classes returned by Classify.
+const (
+	ClassNotFound     = "not_found"
+	ClassConflict     = "conflict"
+	ClassUnauthorized = "unauthorized"
+	ClassForbidden    = "forbidden"
+	ClassClientError  = "client_error"
+	ClassServerError  = "server_error"
+
+	// ClassOther covers errors that did not come from the Chef Server, such as network failures or
+	// invalid input.
+	ClassOther = "other"
+)
+
 // AsChefError attempts to extract a *chef.ErrorResponse from err.
 //
 // It first unwraps err using errors.As to support wrapped errors (%w),
@@ -45,3 +64,95 @@ func IsConflict(err error) bool {
 	}
 	return false
 }
+
+// Classify returns the class of err, for reporting failures by kind: one of the Class constants, or
+// "" when err is nil.
+func Classify(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	ce, ok := AsChefError(err)
+	if !ok || ce.Response == nil {
+		return ClassOther
+	}
+
+	switch code := ce.Response.StatusCode; {
+	case code == http.StatusNotFound:
+		return ClassNotFound
+	case code == http.StatusConflict:
+		return ClassConflict
+	case code == http.StatusUnauthorized:
+		return ClassUnauthorized
+	case code == http.StatusForbidden:
+		return ClassForbidden
+	case code >= 500:
+		return ClassServerError
+	case code >= 400:
+		return ClassClientError
+	default:
+		return ClassOther
+	}
+}
+
+// IsTransient reports whether err is a failure that may succeed if the request is repeated: a 429, 502,
+// 503 or 504 response, or a network error reaching the Chef Server.
+func IsTransient(err error) bool {
+	if ce, ok := AsChefError(err); ok {
+		if ce.Response == nil {
+			return false
+		}
+		switch ce.Response.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+// IsUnprocessed reports whether err is a 429 or 503 response, with which a server declines a request
+// without acting on it, so that even a non-idempotent request can safely be repeated.
+func IsUnprocessed(err error) bool {
+	if ce, ok := AsChefError(err); ok && ce.Response != nil {
+		code := ce.Response.StatusCode
+		return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of a 429 or 503 response, given in
+// seconds or as an HTTP date. The boolean is false when err carries no such header.
+func RetryAfter(err error) (time.Duration, bool) {
+	if !IsUnprocessed(err) {
+		return 0, false
+	}
+	ce, _ := AsChefError(err)
+	v := ce.Response.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// New returns a *chef.ErrorResponse for a request to path that failed with status, for backends that
+// do not talk to a Chef Server but must report missing or conflicting objects the way one does.
+// Its message matches the errors go-chef returns, such as "GET data/vault1/secret1: 404".
+func New(status int, method, path string) error {
+	return &chef.ErrorResponse{
+		Response: &http.Response{
+			StatusCode: status,
+			Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			Request:    &http.Request{Method: method, URL: &url.URL{Path: path}},
+		},
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/go-chef/chef"
 )
@@ -27,3 +29,82 @@ func TestAsChefError_Wrapped(t *testing.T) {
 		t.Fatalf("expected original Chef error, got different instance")
 	}
 }
+
+func TestNew(t *testing.T) {
+	err := New(http.StatusNotFound, http.MethodGet, "data/vault1/secret1")
+
+	if !IsNotFound(err) || IsConflict(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	if got, want := err.Error(), "GET data/vault1/secret1: 404"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	if !IsConflict(fmt.Errorf("wrapped: %w", New(http.StatusConflict, http.MethodPost, "data"))) {
+		t.Fatalf("expected a wrapped conflict to be recognized")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := map[error]string{
+		nil: "",
+		New(http.StatusNotFound, http.MethodGet, "data/x"):                           ClassNotFound,
+		fmt.Errorf("wrapped: %w", New(http.StatusConflict, http.MethodPost, "data")): ClassConflict,
+		New(http.StatusUnauthorized, http.MethodGet, "data"):                         ClassUnauthorized,
+		New(http.StatusForbidden, http.MethodGet, "data"):                            ClassForbidden,
+		New(http.StatusBadRequest, http.MethodGet, "search/node"):                    ClassClientError,
+		New(http.StatusBadGateway, http.MethodPut, "data/x/y"):                       ClassServerError,
+		errors.New("connection refused"):                                             ClassOther,
+	}
+
+	for err, want := range cases {
+		if got := Classify(err); got != want {
+			t.Errorf("Classify(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	cases := map[error]bool{
+		nil: false,
+		New(http.StatusBadGateway, http.MethodPut, "data/x/y"):                          true,
+		fmt.Errorf("wrapped: %w", New(http.StatusTooManyRequests, http.MethodGet, "")):  true,
+		New(http.StatusServiceUnavailable, http.MethodGet, "data"):                      true,
+		New(http.StatusGatewayTimeout, http.MethodGet, "data"):                          true,
+		New(http.StatusInternalServerError, http.MethodGet, "data"):                     false,
+		New(http.StatusNotFound, http.MethodGet, "data/x"):                              false,
+		&url.Error{Op: "Get", URL: "https://chef", Err: errors.New("connection reset")}: true,
+		errors.New("invalid payload"):                                                   false,
+	}
+
+	for err, want := range cases {
+		if got := IsTransient(err); got != want {
+			t.Errorf("IsTransient(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	withHeader := func(status int, value string) error {
+		err := New(status, http.MethodGet, "data").(*chef.ErrorResponse)
+		err.Response.Header = http.Header{"Retry-After": []string{value}}
+		return err
+	}
+
+	if d, ok := RetryAfter(withHeader(http.StatusTooManyRequests, "3")); !ok || d != 3*time.Second {
+		t.Fatalf("expected a 3s delay, got %v %v", d, ok)
+	}
+
+	at := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d, ok := RetryAfter(withHeader(http.StatusServiceUnavailable, at)); !ok || d <= 0 || d > time.Minute {
+		t.Fatalf("expected a delay of up to a minute, got %v %v", d, ok)
+	}
+
+	if _, ok := RetryAfter(withHeader(http.StatusBadGateway, "3")); ok {
+		t.Fatalf("expected Retry-After to be ignored on a 502")
+	}
+	if _, ok := RetryAfter(New(http.StatusTooManyRequests, http.MethodGet, "data")); ok {
+		t.Fatalf("expected no delay without a Retry-After header")
+	}
+}
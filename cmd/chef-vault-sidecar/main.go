@@ -0,0 +1,208 @@
+// Command chef-vault-sidecar serves decrypted vault items to local applications over HTTP, acting as
+// the node's Chef client.
+//
+// Usage:
+//
+//	chef-vault-sidecar [--listen unix:PATH|HOST:PORT] [--socket-mode MODE] [--callers FILE]
+//	                   [--allow VAULT/ITEM ...] [--cache-ttl DURATION] [connection flags]
+//
+// Applications read secrets with GET /v1/VAULT/ITEM[/PATH], as described in package sidecar. The
+// callers file is a JSON array of sidecar.Caller objects:
+//
+//	[{"name": "web", "uids": [1001], "allow": ["apps/web"]},
+//	 {"name": "jobs", "token": "s3cret", "allow": ["database/*"]}]
+//
+// --allow grants any process able to connect to the Unix socket access to the given items. On a TCP
+// address every caller must have a token, and requests must name a loopback Host. Connection
+// settings are resolved as chef-vault resolves them, with -c, --profile, -u, -k, -s, -M and
+// --chef-repo-path. Access records are written to stderr as JSON. SIGHUP empties the item cache;
+// SIGINT and SIGTERM shut the sidecar down.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/profile"
+	"github.com/justintsteele/go-chef-vault/sidecar"
+)
+
+// DefaultListen is the address the sidecar listens on when --listen is not given.
+const DefaultListen = "unix:/run/chef-vault/sidecar.sock"
+
+// config is the parsed command line.
+type config struct {
+	profile    profile.Options
+	listen     string
+	socketMode os.FileMode
+	callers    []sidecar.Caller
+	cacheTTL   time.Duration
+}
+
+// stringList is a flag that may be repeated, collecting each value.
+type stringList []string
+
+// String implements flag.Value.
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set implements flag.Value.
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	cfg, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "chef-vault-sidecar: %v\n", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, logger); err != nil {
+		logger.Error("sidecar stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+// parseFlags parses the command line.
+func parseFlags(args []string, stderr io.Writer) (*config, error) {
+	fs := flag.NewFlagSet("chef-vault-sidecar", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	cfg := &config{}
+	var (
+		callersFile string
+		socketMode  string
+		allow       stringList
+	)
+
+	fs.StringVar(&cfg.listen, "listen", DefaultListen, "unix:PATH socket, or loopback HOST:PORT, to listen on")
+	fs.StringVar(&socketMode, "socket-mode", "0660", "permission of the Unix socket, in octal")
+	fs.StringVar(&callersFile, "callers", "", "JSON file listing the callers and the items each may read")
+	fs.Var(&allow, "allow", "VAULT/ITEM pattern any local process may read; may be repeated")
+	fs.DurationVar(&cfg.cacheTTL, "cache-ttl", sidecar.DefaultCacheTTL, "how long items are served from memory; negative disables caching")
+
+	fs.StringVar(&cfg.profile.ConfigFile, "c", "", "path to config.rb or knife.rb, instead of searching ~/.chef")
+	fs.StringVar(&cfg.profile.ConfigFile, "config", "", "path to config.rb or knife.rb, instead of searching ~/.chef")
+	fs.StringVar(&cfg.profile.Profile, "profile", "", "~/.chef/credentials profile, overriding CHEF_PROFILE")
+	fs.StringVar(&cfg.profile.NodeName, "u", "", "API client name, overriding node_name")
+	fs.StringVar(&cfg.profile.NodeName, "user", "", "API client name, overriding node_name")
+	fs.StringVar(&cfg.profile.ClientKeyFile, "k", "", "API client key file, overriding client_key")
+	fs.StringVar(&cfg.profile.ClientKeyFile, "key", "", "API client key file, overriding client_key")
+	fs.StringVar(&cfg.profile.ChefServerURL, "s", "", "Chef server URL, overriding chef_server_url")
+	fs.StringVar(&cfg.profile.ChefServerURL, "server-url", "", "Chef server URL, overriding chef_server_url")
+	fs.StringVar(&cfg.profile.VaultMode, "M", "", "vault mode: client, or solo to use the data_bags of the local chef-repo, overriding knife[:vault_mode]")
+	fs.StringVar(&cfg.profile.VaultMode, "mode", "", "vault mode: client, or solo to use the data_bags of the local chef-repo, overriding knife[:vault_mode]")
+	fs.StringVar(&cfg.profile.ChefRepoPath, "chef-repo-path", "", "chef-repo directory used in solo mode, overriding chef_repo_path")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	mode, err := strconv.ParseUint(socketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return nil, fmt.Errorf("invalid --socket-mode %q", socketMode)
+	}
+	cfg.socketMode = os.FileMode(mode)
+
+	if callersFile != "" {
+		data, err := os.ReadFile(callersFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &cfg.callers); err != nil {
+			return nil, fmt.Errorf("%s: %w", callersFile, err)
+		}
+	}
+	if len(allow) > 0 {
+		cfg.callers = append(cfg.callers, sidecar.Caller{Name: "local", Allow: allow})
+	}
+	if len(cfg.callers) == 0 {
+		return nil, errors.New("no callers: give --callers or --allow")
+	}
+	if !strings.HasPrefix(cfg.listen, "unix:") {
+		for _, c := range cfg.callers {
+			if c.Token == "" {
+				return nil, fmt.Errorf("caller %s has no token: on a TCP address every caller needs one", c.Name)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// run serves secrets until ctx is done.
+func run(ctx context.Context, cfg *config, logger *slog.Logger) error {
+	p, err := profile.Load(&cfg.profile)
+	if err != nil {
+		return err
+	}
+	svc, err := p.NewService()
+	if err != nil {
+		return err
+	}
+	// items expiring from the sidecar's cache are revalidated without decrypting their keys again.
+	svc.Cache = vault.NewItemCache(nil)
+
+	s, err := sidecar.New(&sidecar.Options{
+		Source:   svc,
+		Callers:  cfg.callers,
+		CacheTTL: cfg.cacheTTL,
+		Logger:   logger,
+	})
+	if err != nil {
+		return err
+	}
+
+	ln, err := listen(cfg)
+	if err != nil {
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			s.Purge()
+			svc.Cache.Purge()
+			logger.Info("cache purged")
+		}
+	}()
+
+	logger.Info("sidecar listening", "address", ln.Addr().String(), "actor", p.NodeName, "callers", len(cfg.callers))
+	return s.Serve(ctx, ln)
+}
+
+// listen opens the listener named by --listen.
+func listen(cfg *config) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(cfg.listen, "unix:"); ok {
+		return sidecar.ListenUnix(path, cfg.socketMode)
+	}
+	return sidecar.ListenLocal(cfg.listen)
+}
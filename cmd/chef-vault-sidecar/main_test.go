@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justintsteele/go-chef-vault/profile"
+	"github.com/justintsteele/go-chef-vault/sidecar"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlags(t *testing.T) {
+	callersFile := filepath.Join(t.TempDir(), "callers.json")
+	require.NoError(t, os.WriteFile(callersFile, []byte(`[{"name":"web","uids":[1001],"allow":["apps/web"]}]`), 0o600))
+
+	cfg, err := parseFlags([]string{
+		"--callers", callersFile,
+		"--allow", "database/*", "--allow", "apps/*",
+		"--listen", "unix:/run/app/sidecar.sock",
+		"--socket-mode", "0600",
+		"--cache-ttl", "30s",
+		"-M", "solo", "--chef-repo-path", "/srv/chef-repo",
+		"-u", "web1",
+	}, io.Discard)
+	require.NoError(t, err)
+
+	require.Equal(t, []sidecar.Caller{
+		{Name: "web", UIDs: []int{1001}, Allow: []string{"apps/web"}},
+		{Name: "local", Allow: []string{"database/*", "apps/*"}},
+	}, cfg.callers)
+	require.Equal(t, "unix:/run/app/sidecar.sock", cfg.listen)
+	require.Equal(t, os.FileMode(0o600), cfg.socketMode)
+	require.Equal(t, "30s", cfg.cacheTTL.String())
+	require.Equal(t, profile.VaultModeSolo, cfg.profile.VaultMode)
+	require.Equal(t, "/srv/chef-repo", cfg.profile.ChefRepoPath)
+	require.Equal(t, "web1", cfg.profile.NodeName)
+
+	cfg, err = parseFlags([]string{"--allow", "*/*"}, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, DefaultListen, cfg.listen)
+	require.Equal(t, os.FileMode(0o660), cfg.socketMode)
+	require.Equal(t, sidecar.DefaultCacheTTL, cfg.cacheTTL)
+}
+
+func TestParseFlags_Errors(t *testing.T) {
+	for name, args := range map[string][]string{
+		"no callers":  {},
+		"socket mode": {"--allow", "*/*", "--socket-mode", "rw"},
+		"callers":     {"--callers", filepath.Join(t.TempDir(), "missing.json")},
+		"argument":    {"--allow", "*/*", "serve"},
+		"tcp allow":   {"--allow", "*/*", "--listen", "127.0.0.1:8200"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseFlags(args, io.Discard)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "s.sock")
+	ln, err := listen(&config{listen: "unix:" + sock, socketMode: 0o640})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	fi, err := os.Stat(sock)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+
+	_, err = listen(&config{listen: "192.0.2.1:8200"})
+	require.ErrorContains(t, err, "not a loopback address")
+}
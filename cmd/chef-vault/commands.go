@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/agent"
+)
+
+// command is a chef-vault subcommand.
+type command struct {
+	usage string
+	min   int
+	max   int
+	flags []string
+
+	// passthrough stops flag parsing at the first positional argument, so that the remaining
+	// arguments, flags included, are passed on untouched.
+	passthrough bool
+
+	run func(c *cli, svc *vault.Service, args []string, a *accessOptions) error
+}
+
+// commands maps subcommand names, including the two-word rotate commands, to their implementation.
+var commands = map[string]*command{
+	"create": {
+		usage: "create VAULT ITEM [VALUES] [-A admins] [-C clients] [-S query] [-K keys-mode] [-J file] [--file FILE]",
+		min:   2,
+		max:   3,
+		flags: []string{"A", "C", "S", "K", "J", "file"},
+		run:   runCreate,
+	},
+	"show": {
+		usage: "show VAULT [ITEM] [KEYS]",
+		min:   1,
+		max:   3,
+		run:   runShow,
+	},
+	"update": {
+		usage: "update VAULT ITEM [VALUES] [-A admins] [-C clients] [-S query] [-K keys-mode] [-J file] [--file FILE] [--clean]",
+		min:   2,
+		max:   3,
+		flags: []string{"A", "C", "S", "K", "J", "file", "clean"},
+		run:   runUpdate,
+	},
+	"remove": {
+		usage: "remove VAULT ITEM [VALUES] [-A admins] [-C clients] [-S query] [-J file] [--clean-unknown-clients]",
+		min:   2,
+		max:   3,
+		flags: []string{"A", "C", "S", "J", "clean-unknown-clients"},
+		run:   runRemove,
+	},
+	"exec": {
+		usage:       "exec -E [NAME=]VAULT/ITEM[:PATH] [-E ...] [--] COMMAND [ARGS...]",
+		min:         1,
+		max:         -1,
+		flags:       []string{"E"},
+		passthrough: true,
+		run:         runExec,
+	},
+	"agent": {
+		usage: "agent --sink FILE=VAULT/ITEM[:PATH] [--sink ...] [--interval DURATION] [--reload COMMAND]",
+		flags: []string{"sink", "interval", "reload"},
+		run:   runAgent,
+	},
+	"edit": {
+		usage: "edit VAULT ITEM [-y]",
+		min:   2,
+		max:   2,
+		flags: []string{"y"},
+		run:   runEdit,
+	},
+	"delete": {
+		usage: "delete VAULT ITEM",
+		min:   2,
+		max:   2,
+		run:   runDelete,
+	},
+	"list": {
+		usage: "list",
+		run:   runList,
+	},
+	"rotate keys": {
+		usage: "rotate keys VAULT ITEM [--clean-unknown-clients]",
+		min:   2,
+		max:   2,
+		flags: []string{"clean-unknown-clients"},
+		run:   runRotateKeys,
+	},
+	"rotate all keys": {
+		usage: "rotate all keys",
+		run:   runRotateAllKeys,
+	},
+	"refresh": {
+		usage: "refresh VAULT ITEM [--clean-unknown-clients] [--skip-reencryption]",
+		min:   2,
+		max:   2,
+		flags: []string{"clean-unknown-clients", "skip-reencryption"},
+		run:   runRefresh,
+	},
+	"isvault": {
+		usage: "isvault VAULT ITEM",
+		min:   2,
+		max:   2,
+		run:   runIsVault,
+	},
+	"itemtype": {
+		usage: "itemtype VAULT ITEM",
+		min:   2,
+		max:   2,
+		run:   runItemType,
+	},
+}
+
+// commandNames returns the subcommand names in order.
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exec parses the subcommand arguments, connects to the Chef server, and runs the command.
+func (cmd *command) exec(c *cli, name string, args []string) error {
+	g := &globalOptions{}
+	a := &accessOptions{}
+
+	fs := newFlagSet(name, c, g)
+	registerAccess(fs, a, cmd.flags...)
+
+	var positional []string
+	var err error
+	if cmd.passthrough {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		positional = fs.Args()
+	} else if positional, err = parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	if len(positional) < cmd.min || (cmd.max >= 0 && len(positional) > cmd.max) {
+		return fmt.Errorf("usage: chef-vault %s", cmd.usage)
+	}
+
+	svc, err := c.newService(g)
+	if err != nil {
+		return err
+	}
+	a.defaultAdmins = g.vaultAdmins
+	return cmd.run(c, svc, positional, a)
+}
+
+// print writes v to stdout as indented JSON.
+func (c *cli) print(v interface{}) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func runCreate(c *cli, svc *vault.Service, args []string, a *accessOptions) error {
+	content, err := c.readContent(positionalAt(args, 2), a)
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		content = map[string]interface{}{}
+	}
+
+	pl := &vault.Payload{VaultName: args[0], VaultItemName: args[1], Content: content}
+	if err := a.apply(pl); err != nil {
+		return err
+	}
+	if len(pl.Admins) == 0 {
+		pl.Admins = a.defaultAdmins
+	}
+	if len(pl.Admins) == 0 {
+		return errors.New("at least one admin is required (-A or knife[:vault_admins])")
+	}
+
+	resp, err := svc.Create(pl)
+	if err != nil {
+		return err
+	}
+	return c.print(resp)
+}
+
+func runShow(c *cli, svc *vault.Service, args []string, _ *accessOptions) error {
+	if len(args) == 1 {
+		items, err := svc.ListItems(args[0])
+		if err != nil {
+			return err
+		}
+		return c.print(items)
+	}
+
+	content, err := svc.GetItem(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	keys := splitList(positionalAt(args, 2))
+	if len(keys) == 0 {
+		return c.print(content)
+	}
+
+	m, ok := content.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s/%s is not a JSON object", args[0], args[1])
+	}
+
+	selected := map[string]interface{}{"id": args[1]}
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			selected[k] = v
+		}
+	}
+	return c.print(selected)
+}
+
+func runUpdate(c *cli, svc *vault.Service, args []string, a *accessOptions) error {
+	content, err := c.readContent(positionalAt(args, 2), a)
+	if err != nil {
+		return err
+	}
+
+	pl := &vault.Payload{VaultName: args[0], VaultItemName: args[1], Content: content}
+	if err := a.apply(pl); err != nil {
+		return err
+	}
+
+	resp, err := svc.Update(pl)
+	if err != nil {
+		return err
+	}
+	return c.print(resp)
+}
+
+func runRemove(c *cli, svc *vault.Service, args []string, a *accessOptions) error {
+	values, err := c.readValues(positionalAt(args, 2), a)
+	if err != nil {
+		return err
+	}
+
+	pl := &vault.Payload{VaultName: args[0], VaultItemName: args[1]}
+	if err := a.apply(pl); err != nil {
+		return err
+	}
+
+	// VALUES mirrors the shape of the data to remove, or lists paths to remove.
+	switch v := values.(type) {
+	case nil:
+	case map[string]interface{}:
+		pl.Content = v
+	case []interface{}:
+		for _, p := range v {
+			path, ok := p.(string)
+			if !ok {
+				return errors.New("VALUES array must contain only paths")
+			}
+			pl.RemovePaths = append(pl.RemovePaths, path)
+		}
+	default:
+		return errors.New("VALUES must be a JSON object or an array of paths")
+	}
+
+	resp, err := svc.Remove(pl)
+	if err != nil {
+		return err
+	}
+	return c.print(resp)
+}
+
+// runEdit opens the decrypted item in $VISUAL or $EDITOR and, once the redacted diff is confirmed, applies it.
+func runEdit(c *cli, svc *vault.Service, args []string, a *accessOptions) error {
+	opts := &vault.EditOptions{}
+	if !a.yes {
+		opts.Confirm = c.confirmEdit
+	}
+
+	resp, err := svc.Edit(args[0], args[1], opts)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Changes) == 0 {
+		_, err = fmt.Fprintln(c.stderr, "no changes")
+		return err
+	}
+	return c.print(resp)
+}
+
+// confirmEdit prints the redacted changes and asks on stdin whether to apply them.
+func (c *cli) confirmEdit(changes []vault.DiffEntry) (bool, error) {
+	for _, ch := range changes {
+		_, _ = fmt.Fprintf(c.stderr, "  %-8s %s\n", ch.Op, ch.Path)
+	}
+	_, _ = fmt.Fprintf(c.stderr, "Apply %d change(s)? [y/N] ", len(changes))
+
+	answer, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	_, _ = fmt.Fprintln(c.stderr, "not applied")
+	return false, nil
+}
+
+// runExec runs a command with the mapped secrets in its environment and exits with its status.
+func runExec(c *cli, svc *vault.Service, args []string, a *accessOptions) error {
+	if len(a.env) == 0 {
+		return errors.New("at least one secret mapping is required (-E)")
+	}
+
+	opts := &vault.ExecOptions{
+		Stdin:  c.stdin,
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	}
+	for _, spec := range a.env {
+		m, err := vault.ParseEnvMapping(spec)
+		if err != nil {
+			return err
+		}
+		opts.Mappings = append(opts.Mappings, m)
+	}
+
+	code, err := svc.Exec(context.Background(), args, opts)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return &exitError{code: code}
+	}
+	return nil
+}
+
+func runAgent(c *cli, svc *vault.Service, _ []string, a *accessOptions) error {
+	if len(a.sinks) == 0 {
+		return errors.New("at least one sink is required (--sink)")
+	}
+
+	opts := &agent.Options{
+		Interval: a.interval,
+		Stdout:   c.stdout,
+		Stderr:   c.stderr,
+		Logger:   slog.New(slog.NewTextHandler(c.stderr, nil)),
+	}
+	for _, spec := range a.sinks {
+		s, err := agent.ParseSink(spec)
+		if err != nil {
+			return err
+		}
+		opts.Sinks = append(opts.Sinks, s)
+	}
+	if a.reload != "" {
+		opts.Reload = []string{"/bin/sh", "-c", a.reload}
+	}
+
+	ag, err := agent.New(svc, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return ag.Run(ctx)
+}
+
+func runDelete(c *cli, svc *vault.Service, args []string, _ *accessOptions) error {
+	resp, err := svc.DeleteItem(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	return c.print(resp)
+}
+
+func runList(c *cli, svc *vault.Service, _ []string, _ *accessOptions) error {
+	vaults, err := svc.List()
+	if err != nil {
+		return err
+	}
+	return c.print(vaults)
+}
+
+func runRotateKeys(c *cli, svc *vault.Service, args []string, a *accessOptions) error {
+	pl := &vault.Payload{VaultName: args[0], VaultItemName: args[1]}
+	if err := a.apply(pl); err != nil {
+		return err
+	}
+
+	resp, err := svc.RotateKeys(pl)
+	if err != nil {
+		return err
+	}
+	return c.print(resp)
+}
+
+func runRotateAllKeys(c *cli, svc *vault.Service, _ []string, _ *accessOptions) error {
+	resp, err := svc.RotateAllKeys()
+	if err != nil {
+		return err
+	}
+	return c.print(resp)
+}
+
+func runRefresh(c *cli, svc *vault.Service, args []string, a *accessOptions) error {
+	pl := &vault.Payload{VaultName: args[0], VaultItemName: args[1]}
+	if err := a.apply(pl); err != nil {
+		return err
+	}
+
+	resp, err := svc.Refresh(pl)
+	if err != nil {
+		return err
+	}
+	return c.print(resp)
+}
+
+// runIsVault reports its answer through the exit status, as knife vault isvault does.
+func runIsVault(_ *cli, svc *vault.Service, args []string, _ *accessOptions) error {
+	ok, err := svc.IsVault(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+func runItemType(c *cli, svc *vault.Service, args []string, _ *accessOptions) error {
+	itemType, err := svc.ItemType(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, itemType)
+	return err
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-chef/chef"
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/profile"
+)
+
+// newService builds a vault.Service from the Chef configuration and the connection flag overrides,
+// and records the configured vault admins in g. In solo mode the service operates on the local
+// chef-repo instead of a Chef server.
+func newService(g *globalOptions) (*vault.Service, error) {
+	p, err := loadProfile(g)
+	if err != nil {
+		return nil, err
+	}
+	g.vaultAdmins = p.VaultAdmins
+	return p.NewService()
+}
+
+// loadKnifeConfig resolves the Chef configuration the way knife does and applies the connection
+// flag overrides.
+func loadKnifeConfig(g *globalOptions) (*chef.Config, error) {
+	p, err := loadProfile(g)
+	if err != nil {
+		return nil, err
+	}
+	return p.ChefConfig(), nil
+}
+
+// loadProfile resolves the profile selected by the connection flags.
+func loadProfile(g *globalOptions) (*profile.Profile, error) {
+	switch g.vaultMode {
+	case "", "client", profile.VaultModeSolo:
+	default:
+		return nil, fmt.Errorf("invalid vault mode %q: must be client or solo", g.vaultMode)
+	}
+
+	opts := &profile.Options{
+		ConfigFile:    g.config,
+		Profile:       g.profile,
+		NodeName:      g.user,
+		ClientKeyFile: g.key,
+		ChefServerURL: g.serverURL,
+		VaultMode:     g.vaultMode,
+		ChefRepoPath:  g.repoPath,
+	}
+	return profile.Load(opts)
+}
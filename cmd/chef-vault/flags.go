@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/agent"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+)
+
+// globalOptions are the connection flags accepted by every subcommand.
+type globalOptions struct {
+	config    string
+	profile   string
+	user      string
+	key       string
+	serverURL string
+	vaultMode string
+	repoPath  string
+
+	// vaultAdmins is knife[:vault_admins] of the loaded configuration, set by newService.
+	vaultAdmins []string
+}
+
+// accessOptions are the knife vault flags that describe who may read a vault item.
+type accessOptions struct {
+	admins        string
+	clients       string
+	search        string
+	keysMode      string
+	json          string
+	file          string
+	clean         bool
+	cleanUnknown  bool
+	skipReencrypt bool
+	yes           bool
+	env           stringList
+	sinks         stringList
+	interval      time.Duration
+	reload        string
+
+	// defaultAdmins are the admins of items created without -A.
+	defaultAdmins []string
+}
+
+// stringList is a flag that may be repeated, collecting each value.
+type stringList []string
+
+// String implements flag.Value.
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set implements flag.Value.
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// newFlagSet returns a flag set for a subcommand with the connection flags registered.
+func newFlagSet(name string, c *cli, g *globalOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("chef-vault "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	fs.StringVar(&g.config, "c", "", "path to config.rb or knife.rb, instead of searching ~/.chef")
+	fs.StringVar(&g.config, "config", "", "path to config.rb or knife.rb, instead of searching ~/.chef")
+	fs.StringVar(&g.profile, "profile", "", "~/.chef/credentials profile, overriding CHEF_PROFILE")
+	fs.StringVar(&g.user, "u", "", "API client name, overriding node_name")
+	fs.StringVar(&g.user, "user", "", "API client name, overriding node_name")
+	fs.StringVar(&g.key, "k", "", "API client key file, overriding client_key")
+	fs.StringVar(&g.key, "key", "", "API client key file, overriding client_key")
+	fs.StringVar(&g.serverURL, "s", "", "Chef server URL, overriding chef_server_url")
+	fs.StringVar(&g.serverURL, "server-url", "", "Chef server URL, overriding chef_server_url")
+	fs.StringVar(&g.vaultMode, "M", "", "vault mode: client, or solo to use the data_bags of the local chef-repo, overriding knife[:vault_mode]")
+	fs.StringVar(&g.vaultMode, "mode", "", "vault mode: client, or solo to use the data_bags of the local chef-repo, overriding knife[:vault_mode]")
+	fs.StringVar(&g.repoPath, "chef-repo-path", "", "chef-repo directory used in solo mode, overriding chef_repo_path")
+	return fs
+}
+
+// registerAccess registers the access flags named in flags on fs.
+func registerAccess(fs *flag.FlagSet, a *accessOptions, flags ...string) {
+	for _, name := range flags {
+		switch name {
+		case "A":
+			fs.StringVar(&a.admins, "A", "", "comma-separated admins")
+			fs.StringVar(&a.admins, "admins", "", "comma-separated admins")
+		case "C":
+			fs.StringVar(&a.clients, "C", "", "comma-separated clients")
+			fs.StringVar(&a.clients, "clients", "", "comma-separated clients")
+		case "S":
+			fs.StringVar(&a.search, "S", "", "client search query")
+			fs.StringVar(&a.search, "search", "", "client search query")
+		case "K":
+			fs.StringVar(&a.keysMode, "K", "", "keys mode: default or sparse")
+			fs.StringVar(&a.keysMode, "keys-mode", "", "keys mode: default or sparse")
+		case "J":
+			fs.StringVar(&a.json, "J", "", "JSON file with the item content, or - for stdin")
+			fs.StringVar(&a.json, "json", "", "JSON file with the item content, or - for stdin")
+		case "file":
+			fs.StringVar(&a.file, "file", "", "store a file's contents in the item, as file-name and file-content")
+		case "clean":
+			fs.BoolVar(&a.clean, "clean", false, "remove all clients before adding those given")
+		case "clean-unknown-clients":
+			fs.BoolVar(&a.cleanUnknown, "clean-unknown-clients", false, "remove clients that no longer exist")
+		case "skip-reencryption":
+			fs.BoolVar(&a.skipReencrypt, "skip-reencryption", false, "add new clients without re-encrypting the item")
+		case "E":
+			fs.Var(&a.env, "E", "secret mapping [NAME=]VAULT/ITEM[:PATH]; may be repeated")
+			fs.Var(&a.env, "env", "secret mapping [NAME=]VAULT/ITEM[:PATH]; may be repeated")
+		case "sink":
+			fs.Var(&a.sinks, "sink", "file kept in sync FILE=VAULT/ITEM[:PATH]; may be repeated")
+		case "interval":
+			fs.DurationVar(&a.interval, "interval", agent.DefaultInterval, "how often vault items are polled")
+		case "reload":
+			fs.StringVar(&a.reload, "reload", "", "shell command run after files change")
+		case "y":
+			fs.BoolVar(&a.yes, "y", false, "apply without asking for confirmation")
+			fs.BoolVar(&a.yes, "yes", false, "apply without asking for confirmation")
+		}
+	}
+}
+
+// parseArgs parses flags that may appear before, between, or after positional arguments,
+// as knife allows, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// apply copies the access flags onto a payload.
+func (a *accessOptions) apply(pl *vault.Payload) error {
+	pl.Admins = splitList(a.admins)
+	pl.Clients = splitList(a.clients)
+	pl.Clean = a.clean
+	pl.CleanUnknown = a.cleanUnknown
+	pl.SkipReencrypt = a.skipReencrypt
+
+	if a.search != "" {
+		query := a.search
+		pl.SearchQuery = &query
+	}
+
+	if a.file != "" {
+		f, err := vault.ReadFile(a.file)
+		if err != nil {
+			return err
+		}
+		pl.Files = map[string]*vault.File{vault.RootFileKey: f}
+	}
+
+	switch item_keys.KeysMode(a.keysMode) {
+	case "":
+	case item_keys.KeysModeDefault, item_keys.KeysModeSparse:
+		mode := item_keys.KeysMode(a.keysMode)
+		pl.KeysMode = &mode
+	default:
+		return fmt.Errorf("invalid keys mode %q: must be default or sparse", a.keysMode)
+	}
+	return nil
+}
+
+// readValues decodes the VALUES argument, the -J file, or stdin when either is "-". It returns nil
+// when no values were given.
+func (c *cli) readValues(inline string, a *accessOptions) (interface{}, error) {
+	if inline != "" && a.json != "" {
+		return nil, errors.New("VALUES and -J are mutually exclusive")
+	}
+
+	var raw []byte
+	var err error
+	switch {
+	case inline == "-" || a.json == "-":
+		raw, err = io.ReadAll(c.stdin)
+	case a.json != "":
+		raw, err = os.ReadFile(a.json)
+	case inline != "":
+		raw = []byte(inline)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("VALUES must be JSON: %w", err)
+	}
+	return v, nil
+}
+
+// readContent reads the item content, which must be a JSON object.
+func (c *cli) readContent(inline string, a *accessOptions) (map[string]interface{}, error) {
+	v, err := c.readValues(inline, a)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	content, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("VALUES must be a JSON object")
+	}
+	return content, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// positionalAt returns the positional argument at i, or "" when absent.
+func positionalAt(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
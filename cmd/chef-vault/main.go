@@ -0,0 +1,110 @@
+// Command chef-vault manages Chef Vault items with the subcommands and flags of knife vault.
+//
+// Usage:
+//
+//	chef-vault create VAULT ITEM [VALUES] [-A admins] [-C clients] [-S query] [-K keys-mode] [-J file] [--file FILE]
+//	chef-vault show VAULT [ITEM] [KEYS]
+//	chef-vault update VAULT ITEM [VALUES] [-A admins] [-C clients] [-S query] [-K keys-mode] [-J file] [--file FILE] [--clean]
+//	chef-vault remove VAULT ITEM [VALUES] [-A admins] [-C clients] [-S query] [-J file] [--clean-unknown-clients]
+//	chef-vault edit VAULT ITEM [-y]
+//	chef-vault exec -E [NAME=]VAULT/ITEM[:PATH] [-E ...] [--] COMMAND [ARGS...]
+//	chef-vault agent --sink FILE=VAULT/ITEM[:PATH] [--sink ...] [--interval DURATION] [--reload COMMAND]
+//	chef-vault delete VAULT ITEM
+//	chef-vault list
+//	chef-vault rotate keys VAULT ITEM [--clean-unknown-clients]
+//	chef-vault rotate all keys
+//	chef-vault refresh VAULT ITEM [--clean-unknown-clients] [--skip-reencryption]
+//	chef-vault isvault VAULT ITEM
+//	chef-vault itemtype VAULT ITEM
+//
+// VALUES is a JSON document given inline, or read from stdin when "-". It may also be read from a
+// file with -J, or from stdin with -J -. Connection settings are resolved as knife does, from a
+// ~/.chef/credentials profile (selected with --profile or CHEF_PROFILE) or from ~/.chef/config.rb or
+// knife.rb. Every subcommand accepts -c to select a config file, -u, -k and -s to override the node
+// name, client key and server URL, and -M solo to work on the data bags of a local chef-repo.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	vault "github.com/justintsteele/go-chef-vault"
+)
+
+// exitError carries a process exit status without an error message, as isvault reports its answer.
+type exitError struct {
+	code int
+}
+
+// Error implements the error interface.
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// cli holds the streams and service factory used by the subcommands.
+type cli struct {
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+	newService func(*globalOptions) (*vault.Service, error)
+}
+
+func main() {
+	c := &cli{
+		stdin:      os.Stdin,
+		stdout:     os.Stdout,
+		stderr:     os.Stderr,
+		newService: newService,
+	}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// run dispatches a command line and returns the process exit status.
+func (c *cli) run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage()
+		return 2
+	}
+
+	name, rest := args[0], args[1:]
+	if name == "rotate" {
+		switch {
+		case len(rest) >= 1 && rest[0] == "keys":
+			name, rest = "rotate keys", rest[1:]
+		case len(rest) >= 2 && rest[0] == "all" && rest[1] == "keys":
+			name, rest = "rotate all keys", rest[2:]
+		}
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		_, _ = fmt.Fprintf(c.stderr, "chef-vault: unknown command %q\n", name)
+		c.usage()
+		return 2
+	}
+
+	if err := cmd.exec(c, name, rest); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			return exit.code
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		_, _ = fmt.Fprintf(c.stderr, "chef-vault %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// usage prints the list of subcommands.
+func (c *cli) usage() {
+	_, _ = fmt.Fprintln(c.stderr, "usage: chef-vault <command> [arguments] [flags]")
+	_, _ = fmt.Fprintln(c.stderr, "\ncommands:")
+	for _, name := range commandNames() {
+		_, _ = fmt.Fprintf(c.stderr, "  %s\n", commands[name].usage)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chef/chef"
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/justintsteele/go-chef-vault/profile"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCLI returns a cli whose service talks to a test server backed by mux.
+func newTestCLI(t *testing.T, mux *http.ServeMux, stdin string) (*cli, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	client, err := chef.NewClient(&chef.Config{
+		Name:                  "tester",
+		Key:                   string(keyPEM),
+		BaseURL:               server.URL,
+		AuthenticationVersion: "1.0",
+	})
+	require.NoError(t, err)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	return &cli{
+		stdin:  strings.NewReader(stdin),
+		stdout: stdout,
+		stderr: stderr,
+		newService: func(*globalOptions) (*vault.Service, error) {
+			return vault.NewService(client), nil
+		},
+	}, stdout, stderr
+}
+
+func vaultMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"vault1": "http://localhost/data/vault1", "plain": "http://localhost/data/plain"}`)
+	})
+	mux.HandleFunc("/data/vault1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"secret1": "u", "secret1_keys": "u"}`)
+	})
+	mux.HandleFunc("/data/plain", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"config": "u"}`)
+	})
+	return mux
+}
+
+func TestRun_ListAndShow(t *testing.T) {
+	c, stdout, _ := newTestCLI(t, vaultMux(), "")
+
+	require.Equal(t, 0, c.run([]string{"list"}))
+	require.Contains(t, stdout.String(), "vault1")
+	require.NotContains(t, stdout.String(), "plain")
+
+	stdout.Reset()
+	require.Equal(t, 0, c.run([]string{"show", "vault1"}))
+	require.Contains(t, stdout.String(), `"secret1"`)
+	require.NotContains(t, stdout.String(), "secret1_keys")
+}
+
+func TestRun_IsVaultAndItemType(t *testing.T) {
+	c, stdout, _ := newTestCLI(t, vaultMux(), "")
+
+	require.Equal(t, 0, c.run([]string{"isvault", "vault1", "secret1"}))
+	require.Equal(t, 1, c.run([]string{"isvault", "plain", "config"}))
+
+	require.Equal(t, 0, c.run([]string{"itemtype", "vault1", "secret1"}))
+	require.Equal(t, "vault\n", stdout.String())
+}
+
+func TestRun_Errors(t *testing.T) {
+	c, _, stderr := newTestCLI(t, vaultMux(), "")
+
+	require.Equal(t, 2, c.run(nil))
+	require.Equal(t, 2, c.run([]string{"frobnicate"}))
+	require.Contains(t, stderr.String(), `unknown command "frobnicate"`)
+
+	stderr.Reset()
+	require.Equal(t, 1, c.run([]string{"delete", "vault1"}))
+	require.Contains(t, stderr.String(), "usage: chef-vault delete VAULT ITEM")
+
+	stderr.Reset()
+	require.Equal(t, 1, c.run([]string{"create", "vault1", "secret2", `{"a": 1}`}))
+	require.Contains(t, stderr.String(), "at least one admin is required")
+
+	stderr.Reset()
+	require.Equal(t, 1, c.run([]string{"create", "vault1", "secret2", "-A", "tester", "-K", "dense"}))
+	require.Contains(t, stderr.String(), `invalid keys mode "dense"`)
+}
+
+func TestParseArgs_Interleaved(t *testing.T) {
+	c := &cli{stderr: &bytes.Buffer{}}
+	g := &globalOptions{}
+	a := &accessOptions{}
+	fs := newFlagSet("update", c, g)
+	registerAccess(fs, a, "A", "C", "S", "K", "clean")
+
+	args, err := parseArgs(fs, []string{"-A", "alice,bob", "vault1", "--clean", "secret1", "-S", "role:web", `{"k": "v"}`, "--keys-mode", "sparse"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"vault1", "secret1", `{"k": "v"}`}, args)
+
+	pl := &vault.Payload{}
+	require.NoError(t, a.apply(pl))
+	require.Equal(t, []string{"alice", "bob"}, pl.Admins)
+	require.True(t, pl.Clean)
+	require.Equal(t, "role:web", *pl.SearchQuery)
+	require.Equal(t, item_keys.KeysModeSparse, *pl.KeysMode)
+}
+
+func TestAccessOptions_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.pem")
+	require.NoError(t, os.WriteFile(path, []byte("PEM"), 0o600))
+
+	pl := &vault.Payload{}
+	require.NoError(t, (&accessOptions{file: path}).apply(pl))
+	require.Equal(t, "web.pem", pl.Files[vault.RootFileKey].Name)
+	require.Equal(t, []byte("PEM"), pl.Files[vault.RootFileKey].Content)
+
+	require.Error(t, (&accessOptions{file: path + ".missing"}).apply(&vault.Payload{}))
+}
+
+func TestParseArgs_Help(t *testing.T) {
+	c := &cli{stderr: &bytes.Buffer{}}
+	_, err := parseArgs(newFlagSet("list", c, &globalOptions{}), []string{"-h"})
+	require.ErrorIs(t, err, flag.ErrHelp)
+}
+
+func TestReadValues_Sources(t *testing.T) {
+	c := &cli{stdin: strings.NewReader(`["db.password"]`)}
+
+	v, err := c.readValues(`{"a": 1}`, &accessOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": float64(1)}, v)
+
+	v, err = c.readValues("-", &accessOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"db.password"}, v)
+
+	file := filepath.Join(t.TempDir(), "content.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"b": "two"}`), 0o600))
+	content, err := c.readContent("", &accessOptions{json: file})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"b": "two"}, content)
+
+	v, err = c.readValues("", &accessOptions{})
+	require.NoError(t, err)
+	require.Nil(t, v)
+
+	_, err = c.readValues(`{}`, &accessOptions{json: file})
+	require.Error(t, err)
+
+	_, err = c.readContent(`[1]`, &accessOptions{})
+	require.ErrorContains(t, err, "JSON object")
+
+	_, err = c.readValues(`not json`, &accessOptions{})
+	require.ErrorContains(t, err, "must be JSON")
+}
+
+func TestLoadKnifeConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tester.pem"), []byte("PEM"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "knife.rb"), []byte(`current_dir = File.dirname(__FILE__)
+node_name "tester"
+client_key "#{current_dir}/tester.pem"
+chef_server_url "https://chef.example.com/organizations/acme"
+`), 0o600))
+
+	cfg, err := loadKnifeConfig(&globalOptions{config: filepath.Join(dir, "knife.rb")})
+	require.NoError(t, err)
+	require.Equal(t, "tester", cfg.Name)
+	require.Equal(t, "PEM", cfg.Key)
+	require.Equal(t, "https://chef.example.com/organizations/acme/", cfg.BaseURL)
+
+	cfg, err = loadKnifeConfig(&globalOptions{config: filepath.Join(dir, "knife.rb"), user: "other", serverURL: "https://other/"})
+	require.NoError(t, err)
+	require.Equal(t, "other", cfg.Name)
+	require.Equal(t, "https://other/", cfg.BaseURL)
+
+	_, err = loadKnifeConfig(&globalOptions{config: filepath.Join(dir, "missing.rb")})
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.rb"), nil, 0o600))
+	_, err = loadKnifeConfig(&globalOptions{config: filepath.Join(dir, "empty.rb")})
+	require.ErrorContains(t, err, "node_name")
+}
+
+func TestLoadProfile_LocalMode(t *testing.T) {
+	dir := t.TempDir()
+	repo := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tester.pem"), []byte("PEM"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "knife.rb"), []byte(`node_name "tester"
+client_key "tester.pem"
+`), 0o600))
+
+	_, err := loadProfile(&globalOptions{config: filepath.Join(dir, "knife.rb")})
+	require.ErrorContains(t, err, "chef_server_url")
+
+	_, err = loadProfile(&globalOptions{config: filepath.Join(dir, "knife.rb"), vaultMode: "local"})
+	require.ErrorContains(t, err, `invalid vault mode "local"`)
+
+	p, err := loadProfile(&globalOptions{config: filepath.Join(dir, "knife.rb"), vaultMode: "solo", repoPath: repo})
+	require.NoError(t, err)
+	require.Equal(t, profile.VaultModeSolo, p.VaultMode)
+	require.Equal(t, repo, p.ChefRepoPath)
+}
+
+func TestRun_CreateDefaultAdmins(t *testing.T) {
+	dir := t.TempDir()
+	repo := t.TempDir()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "users"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "users", "tester.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tester.pem"), pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "knife.rb"), []byte(`node_name "tester"
+client_key "tester.pem"
+knife[:vault_admins] = "tester"
+`), 0o600))
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	c := &cli{stdin: strings.NewReader(""), stdout: stdout, stderr: stderr, newService: newService}
+
+	code := c.run([]string{"create", "vault1", "secret1", `{"a": 1}`, "-c", filepath.Join(dir, "knife.rb"), "-M", "solo", "--chef-repo-path", repo})
+	require.Equal(t, 0, code, stderr.String())
+	require.FileExists(t, filepath.Join(repo, "data_bags", "vault1", "secret1_keys.json"))
+
+	keys, err := os.ReadFile(filepath.Join(repo, "data_bags", "vault1", "secret1_keys.json"))
+	require.NoError(t, err)
+	require.Contains(t, string(keys), `"tester"`)
+}
+
+func TestConfirmEdit(t *testing.T) {
+	changes := []vault.DiffEntry{{Path: "/pass", Op: "changed"}, {Path: "/old", Op: "removed"}}
+
+	c, _, stderr := newTestCLI(t, vaultMux(), "y\n")
+	ok, err := c.confirmEdit(changes)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Contains(t, stderr.String(), "changed  /pass")
+	require.Contains(t, stderr.String(), "removed  /old")
+	require.Contains(t, stderr.String(), "Apply 2 change(s)?")
+
+	for _, answer := range []string{"n\n", "\n", ""} {
+		c, _, _ = newTestCLI(t, vaultMux(), answer)
+		ok, err = c.confirmEdit(changes)
+		require.NoError(t, err)
+		require.False(t, ok, answer)
+	}
+}
+
+func TestRun_Exec(t *testing.T) {
+	c, _, stderr := newTestCLI(t, vaultMux(), "")
+
+	require.Equal(t, 1, c.run([]string{"exec", "--", "true"}))
+	require.Contains(t, stderr.String(), "at least one secret mapping is required")
+
+	stderr.Reset()
+	require.Equal(t, 1, c.run([]string{"exec", "-E", "not-a-spec", "true"}))
+	require.Contains(t, stderr.String(), "invalid environment mapping")
+
+	stderr.Reset()
+	require.Equal(t, 1, c.run([]string{"exec", "-E", "vault1/secret1"}))
+	require.Contains(t, stderr.String(), "usage: chef-vault exec")
+}
+
+func TestRun_Agent(t *testing.T) {
+	c, _, stderr := newTestCLI(t, vaultMux(), "")
+
+	require.Equal(t, 1, c.run([]string{"agent", "--reload", "true"}))
+	require.Contains(t, stderr.String(), "at least one sink is required")
+
+	stderr.Reset()
+	require.Equal(t, 1, c.run([]string{"agent", "--sink", "vault1/secret1"}))
+	require.Contains(t, stderr.String(), "invalid sink")
+
+	stderr.Reset()
+	require.Equal(t, 1, c.run([]string{"agent", "--sink", "out=vault1/secret1", "--interval", "-1s"}))
+	require.Contains(t, stderr.String(), "invalid watch interval")
+}
+
+func TestCommand_PassthroughArgs(t *testing.T) {
+	var got []string
+	var env stringList
+	cmd := &command{
+		min:         1,
+		max:         -1,
+		flags:       []string{"E"},
+		passthrough: true,
+		run: func(_ *cli, _ *vault.Service, args []string, a *accessOptions) error {
+			got, env = args, a.env
+			return nil
+		},
+	}
+
+	c, _, _ := newTestCLI(t, vaultMux(), "")
+	require.NoError(t, cmd.exec(c, "exec", []string{"-E", "a/b", "-E", "C=d/e:f", "--", "env", "-i", "FOO=bar"}))
+	require.Equal(t, []string{"env", "-i", "FOO=bar"}, got)
+	require.Equal(t, stringList{"a/b", "C=d/e:f"}, env)
+
+	require.NoError(t, cmd.exec(c, "exec", []string{"-E", "a/b", "ls", "-l"}))
+	require.Equal(t, []string{"ls", "-l"}, got)
+}
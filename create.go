@@ -1,10 +1,11 @@
 package vault
 
 import (
+	"context"
 	"fmt"
+	"time"
 
-	"github.com/go-chef/chef"
-	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/cheferr"
 	"github.com/justintsteele/go-chef-vault/item_keys"
 )
 
@@ -22,34 +23,54 @@ type CreateDataResponse struct {
 
 // createOps defines the callable operations required to execute an Create request.
 type createOps struct {
-	createKeysDataBag func(*Payload, *item_keys.KeysModeState, []byte) (*item_keys.VaultItemKeysResult, error)
+	createKeysDataBag func(context.Context, *Payload, *item_keys.KeysModeState, []byte) (*item_keys.VaultItemKeysResult, error)
 }
 
 // Create adds a vault item and its associated keys to the Chef server.
 //
+// When Payload.Schema is set, or the vault carries a stored schema, the content is validated
+// before anything is written and ErrSchemaValidation is returned if it does not conform.
+// Payload.Files are stored alongside the content as knife vault --file stores them.
+//
 // References:
 //   - Chef API Docs: https://docs.chef.io/server/api_chef_server/#post-9
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_create.rb
 func (s *Service) Create(payload *Payload) (*CreateResponse, error) {
+	return s.CreateContext(context.Background(), payload)
+}
+
+// CreateContext is Create with requests made under ctx.
+func (s *Service) CreateContext(ctx context.Context, payload *Payload) (resp *CreateResponse, err error) {
+	defer s.observe(OpCreate, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpCreate, payload.traceAttributes()...)
+	defer sp.end(&err)
+
 	if err := payload.validatePayload(); err != nil {
 		return nil, err
 	}
 
+	payload, err = payload.withFiles()
+	if err != nil {
+		return nil, err
+	}
+
 	ops := createOps{
 		createKeysDataBag: s.createKeysDataBag,
 	}
 
-	return s.create(payload, ops)
+	return withAudit(ctx, s, OpCreate, payload.VaultName, payload.VaultItemName, func() (*CreateResponse, error) {
+		return s.create(ctx, payload, ops)
+	})
 }
 
 // create is the worker called by the public API with the operational methods to complete the create request.
-func (s *Service) create(payload *Payload, ops createOps) (*CreateResponse, error) {
-	vaultDataBag := chef.DataBag{
-		Name: payload.VaultName,
+func (s *Service) create(ctx context.Context, payload *Payload, ops createOps) (*CreateResponse, error) {
+	if err := s.validateContent(ctx, payload, payload.Content); err != nil {
+		return nil, err
 	}
 
-	_, err := s.Client.DataBags.Create(&vaultDataBag)
-	if err != nil {
+	// the vault may already hold other items.
+	if err := s.backend().CreateDataBag(ctx, payload.VaultName); err != nil && !cheferr.IsConflict(err) {
 		return nil, err
 	}
 
@@ -69,19 +90,24 @@ func (s *Service) create(payload *Payload, ops createOps) (*CreateResponse, erro
 		Desired: payload.effectiveKeysMode(),
 	}
 
-	keys, err := ops.createKeysDataBag(payload, keysModeState, secret)
+	keys, err := ops.createKeysDataBag(ctx, payload, keysModeState, secret)
 	if err != nil {
 		return nil, err
 	}
 
 	result.KeysURIs = append(result.KeysURIs, keys.URIs...)
 
-	eDB, err := item.Encrypt(payload.VaultItemName, payload.Content, secret)
+	eDB, err := s.encryptItem(ctx, payload, secret)
 	if err != nil {
 		return nil, err
 	}
+	s.logger().Info("item encrypted", "vault", payload.VaultName, "item", payload.VaultItemName, "keys", len(payload.Content))
+
+	if err := s.backend().CreateDataBagItem(ctx, payload.VaultName, &eDB); err != nil {
+		return nil, err
+	}
 
-	if err := s.Client.DataBags.CreateItem(payload.VaultName, &eDB); err != nil {
+	if err := s.startHistory(ctx, payload); err != nil {
 		return nil, err
 	}
 
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -17,7 +18,7 @@ type createRecorder struct {
 
 func (r *createRecorder) ops() createOps {
 	return createOps{
-		createKeysDataBag: func(_ *Payload, keys *item_keys.KeysModeState, secret []byte) (*item_keys.VaultItemKeysResult, error) {
+		createKeysDataBag: func(_ context.Context, _ *Payload, keys *item_keys.KeysModeState, secret []byte) (*item_keys.VaultItemKeysResult, error) {
 			r.calls = append(r.calls, "createKeysDataBag")
 			r.wrote.keysModeState = keys
 			return &item_keys.VaultItemKeysResult{
@@ -32,7 +33,7 @@ func TestCreate_WithDefaultPayload(t *testing.T) {
 
 	rec := &createRecorder{}
 
-	_, err := service.create(&Payload{
+	_, err := service.create(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 	}, rec.ops())
@@ -53,7 +54,7 @@ func TestCreate_WithSparsePayload(t *testing.T) {
 	}
 
 	mode := item_keys.KeysModeSparse
-	_, err := service.create(&Payload{
+	_, err := service.create(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 		KeysMode:      &mode,
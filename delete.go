@@ -1,8 +1,11 @@
 package vault
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/justintsteele/go-chef-vault/cheferr"
 	"github.com/justintsteele/go-chef-vault/item_keys"
 )
 
@@ -17,20 +20,30 @@ type DeleteResponse struct {
 // References:
 //   - Chef API Docs: https://docs.chef.io/api_chef_server/#delete-9
 func (s *Service) Delete(vaultName string) (*DeleteResponse, error) {
+	return s.DeleteContext(context.Background(), vaultName)
+}
+
+// DeleteContext is Delete with requests made under ctx.
+func (s *Service) DeleteContext(ctx context.Context, vaultName string) (resp *DeleteResponse, err error) {
+	defer s.observe(OpDelete, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpDelete, Attribute{"vault", vaultName})
+	defer sp.end(&err)
+
 	if vaultName == "" {
 		return nil, ErrMissingVaultName
 	}
 
-	vaultUri := s.vaultURL(vaultName)
-	_, err := s.Client.DataBags.Delete(vaultName)
-	if err != nil {
-		return nil, err
-	}
-	return &DeleteResponse{
-		Response: Response{
-			vaultUri,
-		},
-	}, nil
+	return withAudit(ctx, s, OpDelete, vaultName, "", func() (*DeleteResponse, error) {
+		vaultUri := s.vaultURL(vaultName)
+		if err := s.backend().DeleteDataBag(ctx, vaultName); err != nil {
+			return nil, err
+		}
+		return &DeleteResponse{
+			Response: Response{
+				vaultUri,
+			},
+		}, nil
+	})
 }
 
 // DeleteItem destroys a specified vault item and its keys.
@@ -39,6 +52,15 @@ func (s *Service) Delete(vaultName string) (*DeleteResponse, error) {
 //   - Chef API Docs: https://docs.chef.io/api_chef_server/#delete-10
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_delete.rb
 func (s *Service) DeleteItem(vaultName, vaultItem string) (*DeleteResponse, error) {
+	return s.DeleteItemContext(context.Background(), vaultName, vaultItem)
+}
+
+// DeleteItemContext is DeleteItem with requests made under ctx.
+func (s *Service) DeleteItemContext(ctx context.Context, vaultName, vaultItem string) (resp *DeleteResponse, err error) {
+	defer s.observe(OpDeleteItem, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpDeleteItem, Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
 	pl := &Payload{
 		VaultName:     vaultName,
 		VaultItemName: vaultItem,
@@ -48,13 +70,22 @@ func (s *Service) DeleteItem(vaultName, vaultItem string) (*DeleteResponse, erro
 		return nil, err
 	}
 
-	keyState, err := s.loadKeysCurrentState(pl)
+	return withItemLock(ctx, s, pl, func(ctx context.Context) (*DeleteResponse, error) {
+		return withAudit(ctx, s, OpDeleteItem, pl.VaultName, pl.VaultItemName, func() (*DeleteResponse, error) {
+			return s.deleteItem(ctx, pl)
+		})
+	})
+}
+
+// deleteItem is the worker called by the public API to delete a vault item, its keys, and its companion items.
+func (s *Service) deleteItem(ctx context.Context, pl *Payload) (*DeleteResponse, error) {
+	keyState, err := s.loadKeysCurrentState(ctx, pl)
 	if err != nil {
 		return nil, err
 	}
 
 	// the vault item is deleted first, followed by best-effort key cleanup.
-	resp, err := s.deleteVaultItem(pl.VaultName, pl.VaultItemName)
+	resp, err := s.deleteVaultItem(ctx, pl.VaultName, pl.VaultItemName)
 	if err != nil {
 		return nil, err
 	}
@@ -63,22 +94,30 @@ func (s *Service) DeleteItem(vaultName, vaultItem string) (*DeleteResponse, erro
 		actors := make([]string, len(keyState.Admins)+len(keyState.Clients))
 		actors = append(actors, keyState.Admins...)
 		actors = append(actors, keyState.Clients...)
-		if err := s.deleteSparseKeys(pl.VaultName, pl.VaultItemName, actors, resp); err != nil {
+		if err := s.deleteSparseKeys(ctx, pl.VaultName, pl.VaultItemName, actors, resp); err != nil {
 			return nil, err
 		}
 	}
 
-	if err := s.deleteDefaultKeys(pl.VaultName, pl.VaultItemName, resp); err != nil {
+	if err := s.deleteDefaultKeys(ctx, pl.VaultName, pl.VaultItemName, resp); err != nil {
 		return nil, err
 	}
 
+	for _, companion := range []string{schemaItemSuffix, historyItemSuffix} {
+		if err := s.backend().DeleteDataBagItem(ctx, pl.VaultName, pl.VaultItemName+companion); err != nil {
+			if !cheferr.IsNotFound(err) {
+				return nil, err
+			}
+		}
+	}
+
 	return resp, nil
 }
 
 // deleteVaultItem removes the encrypted data bag portion of the vault.
-func (s *Service) deleteVaultItem(vaultName, vaultItem string) (*DeleteResponse, error) {
+func (s *Service) deleteVaultItem(ctx context.Context, vaultName, vaultItem string) (*DeleteResponse, error) {
 	itemUri := fmt.Sprintf("%s/%s", s.vaultURL(vaultName), vaultItem)
-	if err := s.Client.DataBags.DeleteItem(vaultName, vaultItem); err != nil {
+	if err := s.backend().DeleteDataBagItem(ctx, vaultName, vaultItem); err != nil {
 		return nil, err
 	}
 	return &DeleteResponse{
@@ -0,0 +1,191 @@
+package vault
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+)
+
+// diffSaltSize is the size in bytes of the random salt generated when hashes are requested without one.
+const diffSaltSize = 16
+
+// DiffOptions controls how values are reported by Diff and DiffVersions. Values are redacted
+// unless ShowValues or ShowHashes is set.
+type DiffOptions struct {
+	// ShowValues includes the plaintext old and new values of each change.
+	ShowValues bool
+
+	// ShowHashes includes a salted HMAC-SHA256 of the old and new values of each change.
+	ShowHashes bool
+
+	// Salt keys the value hashes. A random salt is generated when empty; supply a fixed salt
+	// to compare hashes across diffs.
+	Salt []byte
+}
+
+// DiffEntry describes a single added, removed, or changed path. Path is a JSON Pointer into the item content.
+type DiffEntry struct {
+	Path    string        `json:"path"`
+	Op      item.ChangeOp `json:"op"`
+	Old     interface{}   `json:"old,omitempty"`
+	New     interface{}   `json:"new,omitempty"`
+	OldHash string        `json:"old_hash,omitempty"`
+	NewHash string        `json:"new_hash,omitempty"`
+}
+
+// DiffResponse represents the structure of the response from a Diff or DiffVersions operation.
+type DiffResponse struct {
+	Response
+	Changes []DiffEntry `json:"changes"`
+	Salt    string      `json:"salt,omitempty"`
+}
+
+// Diff compares the current decrypted content of a vault item with the content an Update with proposed
+// would store: proposed is merged over the current top-level keys, so keys it omits are kept.
+func (s *Service) Diff(vaultName, vaultItem string, proposed map[string]interface{}, opts *DiffOptions) (resp *DiffResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.diff", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	current, err := s.GetItemContext(ctx, pl.VaultName, pl.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+
+	currMap, err := item.DataBagItemMap(current)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := resolveContent(currMap, proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.diff(pl, current, merged, opts)
+}
+
+// DiffVersions compares two stored versions of a vault item, as numbered by History.
+func (s *Service) DiffVersions(vaultName, vaultItem string, from, to int, opts *DiffOptions) (resp *DiffResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.diff_versions",
+		Attribute{"vault", vaultName}, Attribute{"item", vaultItem}, Attribute{"from", from}, Attribute{"to", to})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	older, err := s.getItemVersion(ctx, pl.VaultName, pl.VaultItemName, from)
+	if err != nil {
+		return nil, err
+	}
+
+	newer, err := s.getItemVersion(ctx, pl.VaultName, pl.VaultItemName, to)
+	if err != nil {
+		return nil, err
+	}
+
+	newerMap, err := item.DataBagItemMap(newer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.diff(pl, older, newerMap, opts)
+}
+
+// diff compares two versions of item content and reports the changes according to opts.
+func (s *Service) diff(payload *Payload, from chef.DataBagItem, to map[string]interface{}, opts *DiffOptions) (*DiffResponse, error) {
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+
+	fromMap, err := item.DataBagItemMap(from)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := normalizeContent(fromMap)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := normalizeContent(to)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := opts.Salt
+	if opts.ShowHashes && len(salt) == 0 {
+		if salt, err = item_keys.GenSecret(diffSaltSize); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &DiffResponse{
+		Response: Response{
+			URI: fmt.Sprintf("%s/%s", s.vaultURL(payload.VaultName), payload.VaultItemName),
+		},
+		Changes: make([]DiffEntry, 0),
+	}
+	if opts.ShowHashes {
+		result.Salt = hex.EncodeToString(salt)
+	}
+
+	for _, c := range item.Diff(a, b) {
+		entry := DiffEntry{Path: c.Path, Op: c.Op}
+
+		if opts.ShowValues {
+			entry.Old = c.Old
+			entry.New = c.New
+		}
+
+		if opts.ShowHashes {
+			if c.Op != item.ChangeAdded {
+				if entry.OldHash, err = hashValue(salt, c.Old); err != nil {
+					return nil, err
+				}
+			}
+			if c.Op != item.ChangeRemoved {
+				if entry.NewHash, err = hashValue(salt, c.New); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		result.Changes = append(result.Changes, entry)
+	}
+
+	return result, nil
+}
+
+// hashValue returns the hex HMAC-SHA256 of the JSON encoding of v keyed by salt.
+func hashValue(salt []byte, v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Diff_RedactsByDefault(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+	stubEncryptedItem(t, map[string]interface{}{"user": "admin", "pass": "old"})
+
+	resp, err := service.Diff("vault2", "secret2", map[string]interface{}{"user": "admin", "pass": "new", "port": 5432}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []DiffEntry{
+		{Path: "/pass", Op: "changed"},
+		{Path: "/port", Op: "added"},
+	}, resp.Changes)
+	require.Empty(t, resp.Salt)
+
+	b, err := json.Marshal(resp)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "old")
+	require.NotContains(t, string(b), "new")
+}
+
+func TestService_Diff_ShowValues(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+	stubEncryptedItem(t, map[string]interface{}{"pass": "old"})
+
+	resp, err := service.Diff("vault2", "secret2", map[string]interface{}{"pass": "new"}, &DiffOptions{ShowValues: true})
+	require.NoError(t, err)
+	require.Equal(t, []DiffEntry{{Path: "/pass", Op: "changed", Old: "old", New: "new"}}, resp.Changes)
+}
+
+func TestService_Diff_MergesLikeUpdate(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+	stubEncryptedItem(t, map[string]interface{}{"user": "admin", "pass": "old", "db": map[string]interface{}{"host": "a", "port": 1}})
+
+	// omitted keys are kept, and a nested object is replaced as a whole.
+	resp, err := service.Diff("vault2", "secret2", map[string]interface{}{"db": map[string]interface{}{"host": "b"}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []DiffEntry{
+		{Path: "/db/host", Op: "changed"},
+		{Path: "/db/port", Op: "removed"},
+	}, resp.Changes)
+}
+
+func TestService_Diff_ShowHashes(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+	stubEncryptedItem(t, map[string]interface{}{"pass": "old", "user": "root"})
+
+	salt := []byte("fixed-salt")
+	resp, err := service.Diff("vault2", "secret2", map[string]interface{}{"pass": "new", "user": "admin"}, &DiffOptions{ShowHashes: true, Salt: salt})
+	require.NoError(t, err)
+	require.Equal(t, "66697865642d73616c74", resp.Salt)
+	require.Len(t, resp.Changes, 2)
+
+	oldHash, err := hashValue(salt, "old")
+	require.NoError(t, err)
+	newHash, err := hashValue(salt, "new")
+	require.NoError(t, err)
+
+	pass := resp.Changes[0]
+	require.Equal(t, "/pass", pass.Path)
+	require.Nil(t, pass.Old)
+	require.Nil(t, pass.New)
+	require.Equal(t, oldHash, pass.OldHash)
+	require.Equal(t, newHash, pass.NewHash)
+
+	random, err := service.Diff("vault2", "secret2", map[string]interface{}{"pass": "new", "user": "root"}, &DiffOptions{ShowHashes: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, random.Salt)
+	require.NotEqual(t, newHash, random.Changes[0].NewHash)
+}
+
+func TestService_DiffVersions(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+	secret := stubEncryptedItem(t, map[string]interface{}{"pass": "three"})
+
+	stored, err := encodeHistory("secret2_history", &itemHistory{
+		Limit:   5,
+		Current: HistoryEntry{Version: 3},
+		Versions: []historyVersion{
+			{HistoryEntry: HistoryEntry{Version: 2}, Content: map[string]interface{}{"pass": "two"}},
+			{HistoryEntry: HistoryEntry{Version: 1}, Content: map[string]interface{}{"pass": "one", "old": true}},
+		},
+	}, secret)
+	require.NoError(t, err)
+	mux.HandleFunc("/data/vault2/secret2_history", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(stored)
+	})
+
+	resp, err := service.DiffVersions("vault2", "secret2", 1, 3, &DiffOptions{ShowValues: true})
+	require.NoError(t, err)
+	require.Equal(t, []DiffEntry{
+		{Path: "/old", Op: "removed", Old: true},
+		{Path: "/pass", Op: "changed", Old: "one", New: "three"},
+	}, resp.Changes)
+
+	_, err = service.DiffVersions("vault2", "secret2", 1, 9, nil)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+func TestService_Diff_InvalidPayload(t *testing.T) {
+	_, err := service.Diff("", "secret1", nil, nil)
+	require.ErrorIs(t, err, ErrMissingVaultName)
+
+	_, err = service.DiffVersions("vault1", "", 1, 2, nil)
+	require.ErrorIs(t, err, ErrMissingVaultItemName)
+}
@@ -0,0 +1,227 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/item"
+)
+
+// Editor edits the file at path in place.
+type Editor func(path string) error
+
+// EditOptions controls the Edit workflow.
+type EditOptions struct {
+	// Editor edits the decrypted item. Defaults to EditorCommand with $VISUAL, $EDITOR, or vi.
+	Editor Editor
+
+	// Confirm is shown the redacted diff of the edit and decides whether it is applied.
+	// When nil, every edit with changes is applied.
+	Confirm func(changes []DiffEntry) (bool, error)
+
+	// TempDir is the directory for the decrypted temp file. Defaults to os.TempDir.
+	TempDir string
+}
+
+// EditResponse represents the structure of the response from an Edit operation.
+type EditResponse struct {
+	Response
+	Changes []DiffEntry     `json:"changes"`
+	Applied bool            `json:"applied"`
+	Update  *UpdateResponse `json:"update,omitempty"`
+}
+
+// editOps defines the callable operations required to execute an Edit request.
+type editOps struct {
+	getItem func(ctx context.Context, vaultName, vaultItem string) (chef.DataBagItem, string, error)
+	replace func(ctx context.Context, payload *Payload) (*UpdateResponse, error)
+}
+
+// Edit decrypts a vault item into a private temp file, opens it in an editor, and applies the result,
+// as knife vault edit does.
+//
+// The edited file must hold a JSON object whose id, if present, is unchanged. It replaces the item's
+// content in a single write pinned to the revision that was read: if the item changed on the server
+// during the edit, nothing is applied and the error is ErrConflict. The temp file is overwritten and
+// removed before Edit returns.
+//
+// References:
+//   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_edit.rb
+func (s *Service) Edit(vaultName, vaultItem string, opts *EditOptions) (resp *EditResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.edit", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	ops := editOps{
+		getItem: s.getItemWithRevision,
+		replace: s.replaceItem,
+	}
+	return s.edit(ctx, pl, opts, ops)
+}
+
+// edit is the worker called by the public API with the operational methods to complete the Edit request.
+func (s *Service) edit(ctx context.Context, payload *Payload, opts *EditOptions, ops editOps) (*EditResponse, error) {
+	if opts == nil {
+		opts = &EditOptions{}
+	}
+
+	editor := opts.Editor
+	if editor == nil {
+		editor = EditorCommand(defaultEditor())
+	}
+
+	current, revision, err := ops.getItem(ctx, payload.VaultName, payload.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+
+	currMap, err := item.DataBagItemMap(current)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := normalizeContent(currMap)
+	if err != nil {
+		return nil, err
+	}
+
+	edited, err := editContent(payload, original, opts.TempDir, editor)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := s.diff(payload, original, edited, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EditResponse{
+		Response: diff.Response,
+		Changes:  diff.Changes,
+	}
+	if len(result.Changes) == 0 {
+		return result, nil
+	}
+
+	if opts.Confirm != nil {
+		ok, err := opts.Confirm(result.Changes)
+		if err != nil || !ok {
+			return result, err
+		}
+	}
+
+	result.Update, err = ops.replace(ctx, &Payload{
+		VaultName:     payload.VaultName,
+		VaultItemName: payload.VaultItemName,
+		Content:       edited,
+		Revision:      revision,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Applied = true
+
+	return result, nil
+}
+
+// editContent writes content to a private temp file, runs editor on it, and returns the edited content.
+// The temp file is shredded before returning.
+func editContent(payload *Payload, content map[string]interface{}, dir string, editor Editor) (map[string]interface{}, error) {
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// CreateTemp opens the file with mode 0600, so only the current user can read the decrypted item.
+	f, err := os.CreateTemp(dir, fmt.Sprintf("%s-%s-*.json", payload.VaultName, payload.VaultItemName))
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	defer shredFile(path)
+
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := editor(path); err != nil {
+		return nil, fmt.Errorf("vault: editor failed: %w", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var edited map[string]interface{}
+	if err := json.Unmarshal(raw, &edited); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEdit, err)
+	}
+	if edited == nil {
+		return nil, fmt.Errorf("%w: content must be a JSON object", ErrInvalidEdit)
+	}
+
+	if id, ok := edited["id"]; ok && id != payload.VaultItemName {
+		return nil, fmt.Errorf("%w: id must remain %q", ErrInvalidEdit, payload.VaultItemName)
+	}
+	edited["id"] = payload.VaultItemName
+
+	return edited, nil
+}
+
+// shredFile overwrites a file with zeros before removing it.
+func shredFile(path string) {
+	if info, err := os.Stat(path); err == nil {
+		if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+			_, _ = f.Write(make([]byte, info.Size()))
+			_ = f.Sync()
+			_ = f.Close()
+		}
+	}
+	_ = os.Remove(path)
+}
+
+// EditorCommand returns an Editor that runs command, which may include arguments, with the file
+// path appended and the process's terminal attached.
+func EditorCommand(command string) Editor {
+	return func(path string) error {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			return errors.New("no editor command")
+		}
+
+		cmd := exec.Command(args[0], append(args[1:], path)...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+}
+
+// defaultEditor returns the editor named by $VISUAL or $EDITOR, or vi.
+func defaultEditor() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return "vi"
+}
@@ -0,0 +1,208 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chef/chef"
+	"github.com/stretchr/testify/require"
+)
+
+type editRecorder struct {
+	content  map[string]interface{}
+	revision string
+	replaced []*Payload
+}
+
+func (r *editRecorder) ops() editOps {
+	return editOps{
+		getItem: func(_ context.Context, _, _ string) (chef.DataBagItem, string, error) {
+			return r.content, r.revision, nil
+		},
+		replace: func(_ context.Context, p *Payload) (*UpdateResponse, error) {
+			if p.Revision != r.revision {
+				return nil, &ConflictError{VaultName: p.VaultName, VaultItemName: p.VaultItemName, Expected: p.Revision, Actual: r.revision}
+			}
+			r.replaced = append(r.replaced, p)
+			return &UpdateResponse{}, nil
+		},
+	}
+}
+
+// writeEditor returns an Editor that replaces the file with content and records the file it was given.
+func writeEditor(content string, path *string) Editor {
+	return func(p string) error {
+		*path = p
+		return os.WriteFile(p, []byte(content), 0o600)
+	}
+}
+
+func TestService_Edit_ReplacesContent(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	rec := &editRecorder{
+		content: map[string]interface{}{
+			"id":   "secret2",
+			"user": "admin",
+			"pass": "old",
+			"db":   map[string]interface{}{"host": "db1", "port": 5432},
+			"old":  "gone",
+		},
+		revision: "rev1",
+	}
+
+	var edited string
+	var shown []DiffEntry
+	opts := &EditOptions{
+		Editor:  writeEditor(`{"id": "secret2", "user": "admin", "pass": "new", "db": {"host": "db1", "port": 5432}, "extra": true}`, &edited),
+		TempDir: t.TempDir(),
+		Confirm: func(changes []DiffEntry) (bool, error) {
+			shown = changes
+			return true, nil
+		},
+	}
+
+	resp, err := service.edit(context.Background(), &Payload{VaultName: "vault2", VaultItemName: "secret2"}, opts, rec.ops())
+	require.NoError(t, err)
+	require.True(t, resp.Applied)
+	require.Equal(t, []DiffEntry{
+		{Path: "/extra", Op: "added"},
+		{Path: "/old", Op: "removed"},
+		{Path: "/pass", Op: "changed"},
+	}, resp.Changes)
+	require.Equal(t, resp.Changes, shown)
+
+	require.Len(t, rec.replaced, 1)
+	require.Equal(t, map[string]interface{}{
+		"id":    "secret2",
+		"user":  "admin",
+		"pass":  "new",
+		"db":    map[string]interface{}{"host": "db1", "port": float64(5432)},
+		"extra": true,
+	}, rec.replaced[0].Content)
+	require.Equal(t, "rev1", rec.replaced[0].Revision)
+
+	// the decrypted temp file was private and is gone
+	require.Equal(t, opts.TempDir, filepath.Dir(edited))
+	_, err = os.Stat(edited)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestService_Edit_FileIsPrivate(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	rec := &editRecorder{content: map[string]interface{}{"id": "secret2", "pass": "p"}, revision: "rev1"}
+
+	var mode os.FileMode
+	var seen []byte
+	editor := func(p string) error {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		mode = info.Mode().Perm()
+		seen, err = os.ReadFile(p)
+		return err
+	}
+
+	resp, err := service.edit(context.Background(), &Payload{VaultName: "vault2", VaultItemName: "secret2"}, &EditOptions{Editor: editor, TempDir: t.TempDir()}, rec.ops())
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), mode)
+	require.JSONEq(t, `{"id": "secret2", "pass": "p"}`, string(seen))
+	require.False(t, resp.Applied)
+	require.Empty(t, resp.Changes)
+	require.Empty(t, rec.replaced)
+}
+
+func TestService_Edit_Declined(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	rec := &editRecorder{content: map[string]interface{}{"id": "secret2", "pass": "p"}, revision: "rev1"}
+
+	var edited string
+	opts := &EditOptions{
+		Editor:  writeEditor(`{"pass": "q"}`, &edited),
+		TempDir: t.TempDir(),
+		Confirm: func([]DiffEntry) (bool, error) { return false, nil },
+	}
+
+	resp, err := service.edit(context.Background(), &Payload{VaultName: "vault2", VaultItemName: "secret2"}, opts, rec.ops())
+	require.NoError(t, err)
+	require.False(t, resp.Applied)
+	require.Len(t, resp.Changes, 1)
+	require.Empty(t, rec.replaced)
+}
+
+func TestService_Edit_Invalid(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	rec := &editRecorder{content: map[string]interface{}{"id": "secret2", "pass": "p"}, revision: "rev1"}
+	payload := &Payload{VaultName: "vault2", VaultItemName: "secret2"}
+
+	var edited string
+	for _, content := range []string{`{"pass": `, `["pass"]`, `null`, `{"id": "other"}`} {
+		_, err := service.edit(context.Background(), payload, &EditOptions{Editor: writeEditor(content, &edited), TempDir: t.TempDir()}, rec.ops())
+		require.ErrorIs(t, err, ErrInvalidEdit, content)
+		_, err = os.Stat(edited)
+		require.True(t, os.IsNotExist(err))
+	}
+
+	_, err := service.edit(context.Background(), payload, &EditOptions{Editor: func(string) error { return errors.New("exit status 1") }}, rec.ops())
+	require.ErrorContains(t, err, "editor failed")
+	require.Empty(t, rec.replaced)
+}
+
+func TestService_Edit_Conflict(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	rec := &editRecorder{content: map[string]interface{}{"id": "secret2", "pass": "p"}, revision: "rev1"}
+	ops := rec.ops()
+	getItem := ops.getItem
+	ops.getItem = func(ctx context.Context, v, i string) (chef.DataBagItem, string, error) {
+		content, revision, err := getItem(ctx, v, i)
+		// the item changes on the server while it is being edited
+		rec.revision = "rev2"
+		return content, revision, err
+	}
+
+	var edited string
+	_, err := service.edit(context.Background(), &Payload{VaultName: "vault2", VaultItemName: "secret2"}, &EditOptions{Editor: writeEditor(`{"pass": "q"}`, &edited)}, ops)
+	require.ErrorIs(t, err, ErrConflict)
+	require.Empty(t, rec.replaced)
+}
+
+func TestService_Edit_MissingNames(t *testing.T) {
+	_, err := service.Edit("", "secret2", nil)
+	require.ErrorIs(t, err, ErrMissingVaultName)
+
+	_, err = service.Edit("vault2", "", nil)
+	require.ErrorIs(t, err, ErrMissingVaultItemName)
+}
+
+func TestService_Edit_RemovesKeys(t *testing.T) {
+	svc := NewServiceWithBackend(newMemoryBackend(t))
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"user": "app", "pass": "old"},
+		Admins:        []string{userid},
+	})
+	require.NoError(t, err)
+
+	var edited string
+	resp, err := svc.Edit("vault1", "secret1", &EditOptions{Editor: writeEditor(`{"pass": "new"}`, &edited), TempDir: t.TempDir()})
+	require.NoError(t, err)
+	require.True(t, resp.Applied)
+
+	got, err := svc.GetItem("vault1", "secret1")
+	require.NoError(t, err)
+	require.Equal(t, chef.DataBagItem(map[string]interface{}{"id": "secret1", "pass": "new"}), got)
+}
@@ -0,0 +1,329 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/item"
+)
+
+// execSignals are relayed from the wrapper to the child process started by Exec.
+var execSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// EnvMapping maps the content of a vault item to environment variables.
+//
+// Path selects a value with a JSON Pointer or dotted path, as Payload.RemovePaths does; when empty
+// the whole item, less its id, is mapped. Objects are flattened into one variable per leaf, named
+// by joining Name and the upper-cased keys with underscores. Name defaults to the upper-cased Path.
+type EnvMapping struct {
+	Name          string
+	VaultName     string
+	VaultItemName string
+	Path          string
+}
+
+// ExecOptions controls the process started by Exec.
+type ExecOptions struct {
+	// Mappings selects the secrets injected into the child's environment.
+	Mappings []EnvMapping
+
+	// Env is the base environment of the child. Defaults to os.Environ. Secrets override variables of the same name.
+	Env []string
+
+	// Dir is the working directory of the child. Defaults to the current directory.
+	Dir string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// execOps defines the callable operations required to execute an Exec request.
+type execOps struct {
+	getItem func(ctx context.Context, vaultName, vaultItem string) (chef.DataBagItem, error)
+}
+
+// ParseEnvMapping parses a mapping spec of the form [NAME=]VAULT/ITEM[:PATH].
+//
+//	DB_PASSWORD=database/postgres:password   one variable
+//	DB=database/postgres:/conn               DB_HOST, DB_PORT, ... from the conn object
+//	APP=apps/web                             APP_ prefixed variables for every field
+//	apps/web                                 a variable for every field
+//
+// The text before the first "=" is a NAME only when it contains no "/", ":" or "[", so that paths
+// such as apps/web:hosts[name=web1].port may select array elements by value.
+func ParseEnvMapping(spec string) (EnvMapping, error) {
+	var m EnvMapping
+
+	target := spec
+	if name, rest, ok := strings.Cut(spec, "="); ok && !strings.ContainsAny(name, "/:[") {
+		m.Name, target = name, rest
+		if !validEnvName(m.Name) {
+			return m, fmt.Errorf("%w: %q is not a valid variable name", ErrInvalidEnvMapping, m.Name)
+		}
+	}
+
+	target, m.Path, _ = strings.Cut(target, ":")
+	m.VaultName, m.VaultItemName, _ = strings.Cut(target, "/")
+	if m.VaultName == "" || m.VaultItemName == "" || strings.Contains(m.VaultItemName, "/") {
+		return m, fmt.Errorf("%w: %q must be [NAME=]VAULT/ITEM[:PATH]", ErrInvalidEnvMapping, spec)
+	}
+	return m, nil
+}
+
+// SecretEnv reads the vault items named by mappings and returns the mapped variables as sorted NAME=value pairs.
+// Each item is read once, however many mappings refer to it. Two mappings or two fields producing the same
+// variable fail with ErrInvalidEnvMapping.
+func (s *Service) SecretEnv(mappings []EnvMapping) (env []string, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.secret_env", Attribute{"mappings", len(mappings)})
+	defer sp.end(&err)
+
+	ops := execOps{
+		getItem: s.GetItemContext,
+	}
+	return s.secretEnv(ctx, mappings, ops)
+}
+
+// Exec runs argv with the secrets selected by opts.Mappings added to its environment and returns its exit status.
+//
+// Secrets are decrypted in memory and passed to the child through its environment only; nothing is written
+// to disk. Interrupt, SIGTERM, SIGHUP and SIGQUIT received by the caller are relayed to the child, and a child
+// killed by a signal is reported as 128 plus the signal number, as shells do. Cancelling ctx kills the child.
+func (s *Service) Exec(ctx context.Context, argv []string, opts *ExecOptions) (status int, err error) {
+	ctx, sp := s.startSpan(ctx, "vault.exec")
+	defer sp.end(&err)
+
+	if len(argv) == 0 {
+		return -1, errors.New("vault: exec requires a command")
+	}
+	if opts == nil {
+		opts = &ExecOptions{}
+	}
+
+	secrets, err := s.secretEnv(ctx, opts.Mappings, execOps{getItem: s.GetItemContext})
+	if err != nil {
+		return -1, err
+	}
+
+	env := opts.Env
+	if env == nil {
+		env = os.Environ()
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = mergeEnv(env, secrets)
+	cmd.Dir = opts.Dir
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+
+	return runRelayingSignals(cmd)
+}
+
+// secretEnv is the worker called by the public API with the operational methods to resolve the mappings.
+func (s *Service) secretEnv(ctx context.Context, mappings []EnvMapping, ops execOps) ([]string, error) {
+	items := make(map[string]map[string]interface{})
+	vars := make(map[string]string)
+	sources := make(map[string]string)
+
+	for _, m := range mappings {
+		pl := &Payload{VaultName: m.VaultName, VaultItemName: m.VaultItemName}
+		if err := pl.validatePayload(); err != nil {
+			return nil, err
+		}
+
+		key := m.VaultName + "/" + m.VaultItemName
+		content, ok := items[key]
+		if !ok {
+			raw, err := ops.getItem(ctx, m.VaultName, m.VaultItemName)
+			if err != nil {
+				return nil, err
+			}
+			if content, err = item.DataBagItemMap(raw); err != nil {
+				return nil, err
+			}
+			items[key] = content
+		}
+
+		value, name, err := m.resolve(content)
+		if err != nil {
+			return nil, err
+		}
+
+		flat := make(map[string]string)
+		if err := flattenEnv(name, value, flat); err != nil {
+			return nil, err
+		}
+
+		for k, v := range flat {
+			if prev, ok := sources[k]; ok {
+				return nil, fmt.Errorf("%w: %s is set by both %s and %s", ErrInvalidEnvMapping, k, prev, key)
+			}
+			sources[k] = key
+			vars[k] = v
+		}
+	}
+
+	out := make([]string, 0, len(vars))
+	for k, v := range vars {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// resolve selects the mapped value from the item content and returns it with its variable name or prefix.
+func (m EnvMapping) resolve(content map[string]interface{}) (interface{}, string, error) {
+	if m.Path == "" {
+		fields := make(map[string]interface{}, len(content))
+		for k, v := range content {
+			if k != "id" {
+				fields[k] = v
+			}
+		}
+		return fields, m.Name, nil
+	}
+
+	p, err := item.ParsePath(m.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	v, ok := item.Lookup(content, p)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s in %s/%s", ErrPathNotFound, m.Path, m.VaultName, m.VaultItemName)
+	}
+
+	name := m.Name
+	if name == "" {
+		name = envName(strings.TrimPrefix(m.Path, "/"))
+	}
+	return v, name, nil
+}
+
+// flattenEnv adds the variables for v to out. Objects contribute one variable per leaf; arrays are
+// JSON encoded, strings are used as-is, and null becomes an empty value. Fields whose names map to
+// the same variable, such as db-host and db_host, fail with ErrInvalidEnvMapping.
+func flattenEnv(name string, v interface{}, out map[string]string) error {
+	if obj, ok := v.(map[string]interface{}); ok {
+		for k, child := range obj {
+			childName := envName(k)
+			if name != "" {
+				childName = name + "_" + childName
+			}
+			if err := flattenEnv(childName, child, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if name == "" {
+		return fmt.Errorf("%w: a scalar value needs a variable name", ErrInvalidEnvMapping)
+	}
+	if _, ok := out[name]; ok {
+		return fmt.Errorf("%w: %s is set by more than one field", ErrInvalidEnvMapping, name)
+	}
+
+	switch val := v.(type) {
+	case nil:
+		out[name] = ""
+	case string:
+		out[name] = val
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+		out[name] = string(b)
+	}
+	return nil
+}
+
+// envName upper-cases s and replaces characters not allowed in environment variable names with underscores.
+func envName(s string) string {
+	b := []byte(strings.ToUpper(s))
+	for i, c := range b {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			b[i] = '_'
+		}
+	}
+	if len(b) > 0 && b[0] >= '0' && b[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+// validEnvName reports whether s is a portable environment variable name.
+func validEnvName(s string) bool {
+	for i, c := range s {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// mergeEnv returns base with the NAME=value pairs in overrides replacing variables of the same name.
+func mergeEnv(base, overrides []string) []string {
+	names := make(map[string]bool, len(overrides))
+	for _, kv := range overrides {
+		name, _, _ := strings.Cut(kv, "=")
+		names[name] = true
+	}
+
+	out := make([]string, 0, len(base)+len(overrides))
+	for _, kv := range base {
+		if name, _, _ := strings.Cut(kv, "="); !names[name] {
+			out = append(out, kv)
+		}
+	}
+	return append(out, overrides...)
+}
+
+// runRelayingSignals runs cmd, relaying execSignals to it, and returns its exit status.
+func runRelayingSignals(cmd *exec.Cmd) (int, error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, execSignals...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	return -1, err
+}
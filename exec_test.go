@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/go-chef/chef"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnvMapping(t *testing.T) {
+	cases := map[string]EnvMapping{
+		"DB_PASSWORD=database/postgres:password": {Name: "DB_PASSWORD", VaultName: "database", VaultItemName: "postgres", Path: "password"},
+		"DB=database/postgres:/conn":             {Name: "DB", VaultName: "database", VaultItemName: "postgres", Path: "/conn"},
+		"apps/web":                               {VaultName: "apps", VaultItemName: "web"},
+		"apps/web:hosts[0]":                      {VaultName: "apps", VaultItemName: "web", Path: "hosts[0]"},
+		"apps/web:hosts[name=web1].port":         {VaultName: "apps", VaultItemName: "web", Path: "hosts[name=web1].port"},
+		"PORT=apps/web:hosts[name=web1].port":    {Name: "PORT", VaultName: "apps", VaultItemName: "web", Path: "hosts[name=web1].port"},
+	}
+	for spec, want := range cases {
+		got, err := ParseEnvMapping(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, want, got, spec)
+	}
+
+	for _, spec := range []string{"", "apps", "apps/", "/web", "a/b/c", "=apps/web", "1DB=apps/web", "DB-X=apps/web"} {
+		_, err := ParseEnvMapping(spec)
+		require.ErrorIs(t, err, ErrInvalidEnvMapping, spec)
+	}
+}
+
+func TestSecretEnv_Mappings(t *testing.T) {
+	reads := 0
+	ops := execOps{
+		getItem: func(_ context.Context, _, name string) (chef.DataBagItem, error) {
+			reads++
+			return map[string]interface{}{
+				"id":       name,
+				"password": "s3cr3t",
+				"conn":     map[string]interface{}{"host": "db1", "port": 5432, "ssl-mode": "require"},
+				"hosts":    []interface{}{"a", "b"},
+				"empty":    nil,
+			}, nil
+		},
+	}
+
+	env, err := service.secretEnv(context.Background(), []EnvMapping{
+		{Name: "DB_PASSWORD", VaultName: "database", VaultItemName: "postgres", Path: "password"},
+		{Name: "DB", VaultName: "database", VaultItemName: "postgres", Path: "/conn"},
+		{VaultName: "database", VaultItemName: "postgres", Path: "hosts"},
+		{Name: "ALL", VaultName: "database", VaultItemName: "postgres"},
+	}, ops)
+	require.NoError(t, err)
+	require.Equal(t, 1, reads)
+	require.Equal(t, []string{
+		"ALL_CONN_HOST=db1",
+		"ALL_CONN_PORT=5432",
+		"ALL_CONN_SSL_MODE=require",
+		"ALL_EMPTY=",
+		"ALL_HOSTS=[\"a\",\"b\"]",
+		"ALL_PASSWORD=s3cr3t",
+		"DB_HOST=db1",
+		"DB_PASSWORD=s3cr3t",
+		"DB_PORT=5432",
+		"DB_SSL_MODE=require",
+		"HOSTS=[\"a\",\"b\"]",
+	}, env)
+}
+
+func TestSecretEnv_Errors(t *testing.T) {
+	ops := execOps{
+		getItem: func(_ context.Context, _, name string) (chef.DataBagItem, error) {
+			if name == "missing" {
+				return nil, errors.New("not found")
+			}
+			return map[string]interface{}{"id": name, "password": "p", "user": "u"}, nil
+		},
+	}
+
+	_, err := service.secretEnv(context.Background(), []EnvMapping{
+		{Name: "PASSWORD", VaultName: "v", VaultItemName: "a", Path: "user"},
+		{VaultName: "v", VaultItemName: "b"},
+	}, ops)
+	require.ErrorIs(t, err, ErrInvalidEnvMapping)
+	require.ErrorContains(t, err, "PASSWORD is set by both v/a and v/b")
+
+	_, err = service.secretEnv(context.Background(), []EnvMapping{{VaultName: "v", VaultItemName: "a", Path: "nope"}}, ops)
+	require.ErrorIs(t, err, ErrPathNotFound)
+
+	_, err = service.secretEnv(context.Background(), []EnvMapping{{VaultName: "v", VaultItemName: "missing"}}, ops)
+	require.ErrorContains(t, err, "not found")
+
+	_, err = service.secretEnv(context.Background(), []EnvMapping{{VaultItemName: "a"}}, ops)
+	require.ErrorIs(t, err, ErrMissingVaultName)
+}
+
+func TestFlattenEnv_Collisions(t *testing.T) {
+	for _, v := range []map[string]interface{}{
+		{"db-host": "a", "db_host": "b"},
+		{"a_b": "top", "a": map[string]interface{}{"b": "nested"}},
+	} {
+		err := flattenEnv("APP", v, make(map[string]string))
+		require.ErrorIs(t, err, ErrInvalidEnvMapping, v)
+		require.ErrorContains(t, err, "is set by more than one field")
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	require.Equal(t,
+		[]string{"HOME=/root", "PASSWORD=new"},
+		mergeEnv([]string{"HOME=/root", "PASSWORD=old"}, []string{"PASSWORD=new"}),
+	)
+}
+
+func TestService_Exec(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	setup(t)
+	t.Cleanup(teardown)
+	stubEncryptedItem(t, map[string]interface{}{"pass": "s3cr3t"})
+
+	var stdout bytes.Buffer
+	code, err := service.Exec(context.Background(), []string{"sh", "-c", `echo "$DB_PASS:$KEEP"; exit 3`}, &ExecOptions{
+		Mappings: []EnvMapping{{Name: "DB_PASS", VaultName: "vault2", VaultItemName: "secret2", Path: "pass"}},
+		Env:      []string{"KEEP=kept", "DB_PASS=stale"},
+		Stdout:   &stdout,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, code)
+	require.Equal(t, "s3cr3t:kept", strings.TrimSpace(stdout.String()))
+
+	code, err = service.Exec(context.Background(), []string{"sh", "-c", `kill -TERM $$`}, &ExecOptions{Env: []string{}})
+	require.NoError(t, err)
+	require.Equal(t, 128+15, code)
+
+	_, err = service.Exec(context.Background(), nil, nil)
+	require.Error(t, err)
+
+	_, err = service.Exec(context.Background(), []string{"/nonexistent/command"}, &ExecOptions{})
+	require.Error(t, err)
+}
+
+// readyWriter closes ready on its first write.
+type readyWriter struct {
+	once  sync.Once
+	ready chan struct{}
+}
+
+func (w *readyWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.ready) })
+	return len(p), nil
+}
+
+func TestService_Exec_RelaysSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals are not relayed on windows")
+	}
+
+	w := &readyWriter{ready: make(chan struct{})}
+	var code int
+	var execErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		code, execErr = service.Exec(context.Background(), []string{"sh", "-c", `trap 'exit 7' TERM; echo ready; while :; do sleep 0.1; done`}, &ExecOptions{Stdout: w})
+	}()
+
+	<-w.ready
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, self.Signal(syscall.SIGTERM))
+
+	<-done
+	require.NoError(t, execErr)
+	require.Equal(t, 7, code)
+}
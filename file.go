@@ -0,0 +1,243 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/item"
+)
+
+// Keys of a stored file. file-name and file-content are the keys knife vault --file writes.
+const (
+	fileNameKey     = "file-name"
+	fileContentKey  = "file-content"
+	fileEncodingKey = "file-encoding"
+	fileModeKey     = "file-mode"
+	fileSHA256Key   = "file-sha256"
+
+	fileEncodingText   = "text"
+	fileEncodingBase64 = "base64"
+)
+
+// RootFileKey is the Payload.Files key of a file stored at the top level of the item, as knife vault --file does.
+const RootFileKey = ""
+
+// File is a whole file stored in a vault item.
+//
+// Files are stored as an object of file-name and file-content, the layout knife vault --file uses,
+// together with file-encoding, file-sha256 and, when Mode is set, file-mode. Content that is not
+// valid UTF-8 text is base64 encoded.
+type File struct {
+	Name    string
+	Content []byte
+
+	// Mode is the permission the file should be written with. Zero stores no mode.
+	Mode os.FileMode
+}
+
+// FileResponse describes a file written out by GetFile.
+type FileResponse struct {
+	Response
+	Name   string      `json:"name"`
+	Mode   os.FileMode `json:"mode,omitempty"`
+	Size   int         `json:"size"`
+	SHA256 string      `json:"sha256"`
+}
+
+// ReadFile reads a file from disk into a File named by its base name and carrying its permissions.
+func ReadFile(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Name:    filepath.Base(path),
+		Content: content,
+		Mode:    info.Mode().Perm(),
+	}, nil
+}
+
+// GetFile writes a file stored in a vault item to w. An empty key reads the file knife vault --file
+// stores at the top level of the item. When the stored file carries a checksum it is verified before
+// anything is written, and a mismatch fails with ErrChecksumMismatch.
+func (s *Service) GetFile(vaultName, vaultItem, key string, w io.Writer) (resp *FileResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.get_file", Attribute{"vault", vaultName}, Attribute{"item", vaultItem}, Attribute{"key", key})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	current, err := s.GetItemContext(ctx, pl.VaultName, pl.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getFile(pl, current, key, w)
+}
+
+// getFile decodes and verifies the file at key in the decrypted item and writes it to w.
+func (s *Service) getFile(payload *Payload, current chef.DataBagItem, key string, w io.Writer) (*FileResponse, error) {
+	content, err := item.DataBagItemMap(current)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := content
+	if key != RootFileKey {
+		stored, _ = content[key].(map[string]interface{})
+	}
+
+	f, sum, err := decodeFile(stored)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s %q: %w", payload.VaultName, payload.VaultItemName, key, err)
+	}
+
+	actual := sha256.Sum256(f.Content)
+	if sum != "" && sum != hex.EncodeToString(actual[:]) {
+		return nil, fmt.Errorf("%w: %s/%s %q", ErrChecksumMismatch, payload.VaultName, payload.VaultItemName, key)
+	}
+
+	if _, err := w.Write(f.Content); err != nil {
+		return nil, err
+	}
+
+	return &FileResponse{
+		Response: Response{
+			URI: fmt.Sprintf("%s/%s", s.vaultURL(payload.VaultName), payload.VaultItemName),
+		},
+		Name:   f.Name,
+		Mode:   f.Mode,
+		Size:   len(f.Content),
+		SHA256: hex.EncodeToString(actual[:]),
+	}, nil
+}
+
+// withFiles returns the payload with Payload.Files encoded into a copy of its content, or the payload
+// itself when it carries no files. A root file without a Mode clears the file-mode of the file it
+// replaces, which Update's top-level merge would otherwise keep.
+func (p *Payload) withFiles() (*Payload, error) {
+	if len(p.Files) == 0 {
+		return p, nil
+	}
+
+	content := make(map[string]interface{}, len(p.Content)+len(p.Files))
+	for k, v := range p.Content {
+		content[k] = v
+	}
+
+	var clearKeys []string
+	for key, f := range p.Files {
+		if f == nil {
+			return nil, fmt.Errorf("%w: file %q is nil", ErrInvalidFile, key)
+		}
+		encoded := encodeFile(f)
+
+		if key == RootFileKey {
+			for k, v := range encoded {
+				if _, ok := p.Content[k]; ok {
+					return nil, fmt.Errorf("%w: %q is set by both Content and Files", ErrInvalidFile, k)
+				}
+				content[k] = v
+			}
+			if _, ok := encoded[fileModeKey]; !ok {
+				if _, ok := p.Content[fileModeKey]; !ok {
+					clearKeys = append(clearKeys, fileModeKey)
+				}
+			}
+			continue
+		}
+
+		if key == "id" {
+			return nil, fmt.Errorf("%w: a file cannot be stored under id", ErrInvalidFile)
+		}
+		if _, ok := p.Content[key]; ok {
+			return nil, fmt.Errorf("%w: %q is set by both Content and Files", ErrInvalidFile, key)
+		}
+		content[key] = encoded
+	}
+
+	out := *p
+	out.Content = content
+	out.Files = nil
+	out.clearKeys = clearKeys
+	return &out, nil
+}
+
+// encodeFile returns the stored representation of a file.
+func encodeFile(f *File) map[string]interface{} {
+	sum := sha256.Sum256(f.Content)
+	out := map[string]interface{}{
+		fileNameKey:   f.Name,
+		fileSHA256Key: hex.EncodeToString(sum[:]),
+	}
+
+	if utf8.Valid(f.Content) && !bytes.ContainsRune(f.Content, 0) {
+		out[fileContentKey] = string(f.Content)
+		out[fileEncodingKey] = fileEncodingText
+	} else {
+		out[fileContentKey] = base64.StdEncoding.EncodeToString(f.Content)
+		out[fileEncodingKey] = fileEncodingBase64
+	}
+
+	if f.Mode != 0 {
+		out[fileModeKey] = fmt.Sprintf("%04o", f.Mode.Perm())
+	}
+	return out
+}
+
+// decodeFile reads a stored file and returns it with its recorded checksum, if any.
+func decodeFile(stored map[string]interface{}) (*File, string, error) {
+	raw, ok := stored[fileContentKey].(string)
+	if !ok {
+		return nil, "", ErrFileNotFound
+	}
+
+	f := &File{}
+	f.Name, _ = stored[fileNameKey].(string)
+
+	switch encoding, _ := stored[fileEncodingKey].(string); encoding {
+	case "", fileEncodingText:
+		f.Content = []byte(raw)
+	case fileEncodingBase64:
+		content, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		f.Content = content
+	default:
+		return nil, "", fmt.Errorf("%w: unknown encoding %q", ErrInvalidFile, encoding)
+	}
+
+	if mode, ok := stored[fileModeKey].(string); ok {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: invalid mode %q", ErrInvalidFile, mode)
+		}
+		f.Mode = os.FileMode(m).Perm()
+	}
+
+	sum, _ := stored[fileSHA256Key].(string)
+	return f, sum, nil
+}
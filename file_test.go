@@ -0,0 +1,191 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayload_WithFiles(t *testing.T) {
+	binary := []byte{0x00, 0xff, 0x10, 0x80}
+	pl := &Payload{
+		VaultName:     "certs",
+		VaultItemName: "web",
+		Content:       map[string]interface{}{"user": "app"},
+		Files: map[string]*File{
+			RootFileKey: {Name: "web.pem", Content: []byte("-----BEGIN CERTIFICATE-----\n"), Mode: 0o640},
+			"keytab":    {Name: "app.keytab", Content: binary},
+		},
+	}
+
+	out, err := pl.withFiles()
+	require.NoError(t, err)
+	require.NotSame(t, pl, out)
+	require.Nil(t, out.Files)
+	require.Len(t, pl.Content, 1, "the caller's content is not modified")
+
+	pemSum := sha256.Sum256([]byte("-----BEGIN CERTIFICATE-----\n"))
+	binSum := sha256.Sum256(binary)
+	require.Equal(t, map[string]interface{}{
+		"user":          "app",
+		"file-name":     "web.pem",
+		"file-content":  "-----BEGIN CERTIFICATE-----\n",
+		"file-encoding": "text",
+		"file-mode":     "0640",
+		"file-sha256":   hex.EncodeToString(pemSum[:]),
+		"keytab": map[string]interface{}{
+			"file-name":     "app.keytab",
+			"file-content":  "AP8QgA==",
+			"file-encoding": "base64",
+			"file-sha256":   hex.EncodeToString(binSum[:]),
+		},
+	}, out.Content)
+
+	same, err := (&Payload{Content: map[string]interface{}{"a": 1}}).withFiles()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": 1}, same.Content)
+}
+
+func TestPayload_WithFiles_Conflicts(t *testing.T) {
+	f := &File{Name: "a", Content: []byte("a")}
+
+	_, err := (&Payload{Content: map[string]interface{}{"file-name": "x"}, Files: map[string]*File{RootFileKey: f}}).withFiles()
+	require.ErrorIs(t, err, ErrInvalidFile)
+
+	_, err = (&Payload{Content: map[string]interface{}{"cert": "x"}, Files: map[string]*File{"cert": f}}).withFiles()
+	require.ErrorIs(t, err, ErrInvalidFile)
+
+	_, err = (&Payload{Files: map[string]*File{"id": f}}).withFiles()
+	require.ErrorIs(t, err, ErrInvalidFile)
+
+	_, err = (&Payload{Files: map[string]*File{"cert": nil}}).withFiles()
+	require.ErrorIs(t, err, ErrInvalidFile)
+}
+
+func TestService_GetFile(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	binary := []byte{0x00, 0xff, 0x10, 0x80}
+	pl, err := (&Payload{Files: map[string]*File{
+		RootFileKey: {Name: "web.pem", Content: []byte("PEM"), Mode: 0o600},
+		"keytab":    {Name: "app.keytab", Content: binary},
+	}}).withFiles()
+	require.NoError(t, err)
+	stubEncryptedItem(t, pl.Content)
+
+	var buf bytes.Buffer
+	resp, err := service.GetFile("vault2", "secret2", RootFileKey, &buf)
+	require.NoError(t, err)
+	require.Equal(t, "PEM", buf.String())
+	require.Equal(t, "web.pem", resp.Name)
+	require.Equal(t, os.FileMode(0o600), resp.Mode)
+	require.Equal(t, 3, resp.Size)
+
+	buf.Reset()
+	resp, err = service.GetFile("vault2", "secret2", "keytab", &buf)
+	require.NoError(t, err)
+	require.Equal(t, binary, buf.Bytes())
+	require.Equal(t, "app.keytab", resp.Name)
+	require.Zero(t, resp.Mode)
+
+	_, err = service.GetFile("vault2", "secret2", "missing", &buf)
+	require.ErrorIs(t, err, ErrFileNotFound)
+
+	_, err = service.GetFile("", "secret2", RootFileKey, &buf)
+	require.ErrorIs(t, err, ErrMissingVaultName)
+}
+
+func TestService_UpdateRootFile_ClearsMode(t *testing.T) {
+	svc := NewServiceWithBackend(newMemoryBackend(t))
+
+	_, err := svc.Create(&Payload{
+		VaultName:     "certs",
+		VaultItemName: "web",
+		Files:         map[string]*File{RootFileKey: {Name: "web.pem", Content: []byte("old"), Mode: 0o600}},
+		Admins:        []string{userid},
+	})
+	require.NoError(t, err)
+
+	_, err = svc.Update(&Payload{
+		VaultName:     "certs",
+		VaultItemName: "web",
+		Files:         map[string]*File{RootFileKey: {Name: "web.pem", Content: []byte("new")}},
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	resp, err := svc.GetFile("certs", "web", RootFileKey, &buf)
+	require.NoError(t, err)
+	require.Equal(t, "new", buf.String())
+	require.Zero(t, resp.Mode)
+
+	got, err := svc.GetItem("certs", "web")
+	require.NoError(t, err)
+	require.NotContains(t, got, "file-mode")
+}
+
+func TestService_GetFile_Ruby(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	// knife vault create --file stores only the name and raw content.
+	stubEncryptedItem(t, map[string]interface{}{"file-name": "license.txt", "file-content": "licensed"})
+
+	var buf bytes.Buffer
+	resp, err := service.GetFile("vault2", "secret2", RootFileKey, &buf)
+	require.NoError(t, err)
+	require.Equal(t, "licensed", buf.String())
+	require.Equal(t, "license.txt", resp.Name)
+}
+
+func TestService_GetFile_ChecksumMismatch(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+
+	stubEncryptedItem(t, map[string]interface{}{
+		"cert": map[string]interface{}{
+			"file-name":    "web.pem",
+			"file-content": "tampered",
+			"file-sha256":  hex.EncodeToString(make([]byte, sha256.Size)),
+		},
+	})
+
+	var buf bytes.Buffer
+	_, err := service.GetFile("vault2", "secret2", "cert", &buf)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	require.Zero(t, buf.Len(), "nothing is written when the checksum does not match")
+}
+
+func TestDecodeFile_Invalid(t *testing.T) {
+	_, _, err := decodeFile(map[string]interface{}{"file-content": "%%%", "file-encoding": "base64"})
+	require.ErrorIs(t, err, ErrInvalidFile)
+
+	_, _, err = decodeFile(map[string]interface{}{"file-content": "x", "file-encoding": "rot13"})
+	require.ErrorIs(t, err, ErrInvalidFile)
+
+	_, _, err = decodeFile(map[string]interface{}{"file-content": "x", "file-mode": "rw-r--r--"})
+	require.ErrorIs(t, err, ErrInvalidFile)
+
+	_, _, err = decodeFile(nil)
+	require.ErrorIs(t, err, ErrFileNotFound)
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.keytab")
+	require.NoError(t, os.WriteFile(path, []byte("keytab"), 0o640))
+
+	f, err := ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "app.keytab", f.Name)
+	require.Equal(t, []byte("keytab"), f.Content)
+	if runtime.GOOS != "windows" {
+		require.Equal(t, os.FileMode(0o640), f.Mode)
+	}
+}
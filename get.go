@@ -1,7 +1,9 @@
 package vault
 
 import (
+	"context"
 	"crypto/rsa"
+	"time"
 
 	"github.com/go-chef/chef"
 	"github.com/justintsteele/go-chef-vault/item"
@@ -20,6 +22,15 @@ type getOps struct {
 //   - Chef API Docs: https://docs.chef.io/api_chef_server/#get-26
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_show.rb
 func (s *Service) GetItem(vaultName, vaultItem string) (chef.DataBagItem, error) {
+	return s.GetItemContext(context.Background(), vaultName, vaultItem)
+}
+
+// GetItemContext is GetItem with requests made under ctx.
+func (s *Service) GetItemContext(ctx context.Context, vaultName, vaultItem string) (content chef.DataBagItem, err error) {
+	defer s.observe(OpGet, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpGet, Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
 	pl := &Payload{
 		VaultName:     vaultName,
 		VaultItemName: vaultItem,
@@ -33,25 +44,29 @@ func (s *Service) GetItem(vaultName, vaultItem string) (chef.DataBagItem, error)
 		deriveAESKey: item_keys.DeriveAESKey,
 		decrypt:      item.Decrypt,
 	}
-	return s.getItem(pl.VaultName, pl.VaultItemName, ops)
+	return s.getItem(ctx, pl.VaultName, pl.VaultItemName, s.traceGetOps(ctx, ops))
 }
 
 // getItem is the worker called by the public API with the operational methods to complete the update request.
-func (s *Service) getItem(vaultName, vaultItem string, ops getOps) (chef.DataBagItem, error) {
-	actorKey, err := s.loadActorKey(vaultName, vaultItem)
+func (s *Service) getItem(ctx context.Context, vaultName, vaultItem string, ops getOps) (chef.DataBagItem, error) {
+	if s.Cache != nil {
+		return s.getCachedItem(ctx, vaultName, vaultItem, ops)
+	}
+
+	actorKey, err := s.loadActorKey(ctx, vaultName, vaultItem)
 	if err != nil {
 		return nil, err
 	}
 
 	aesKey, err := ops.deriveAESKey(
 		actorKey,
-		s.Client.Auth.PrivateKey,
+		s.backend().PrivateKey(),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	rawItem, err := s.Client.DataBags.GetItem(vaultName, vaultItem)
+	rawItem, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"crypto/rsa"
 	"testing"
 
@@ -26,7 +27,7 @@ func TestService_GetItem(t *testing.T) {
 		},
 	}
 
-	_, err := service.getItem("vault1", "secret1", ops)
+	_, err := service.getItem(context.Background(), "vault1", "secret1", ops)
 	require.NoError(t, err)
 	require.Equal(t, []string{"deriveAESKey", "decrypt"}, calls)
 
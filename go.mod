@@ -3,13 +3,14 @@ module github.com/justintsteele/go-chef-vault
 go 1.25
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/bhoriuchi/go-chef-crypto v1.0.0
 	github.com/go-chef/chef v0.30.1
 	github.com/stretchr/testify v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
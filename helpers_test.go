@@ -1,17 +1,23 @@
 package vault
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"slices"
+	"sync"
 	"testing"
 
 	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/stretchr/testify/require"
 )
 
 const userid = "tester"
@@ -255,3 +261,276 @@ func equalLists(a, b []string) bool {
 	slices.Sort(b)
 	return slices.Equal(a, b)
 }
+
+func publicKeyPEM(t *testing.T, c *chef.Client) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(&c.Auth.PrivateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	}))
+}
+
+// memoryBackend is the Backend shared by tests that need writes to persist: data bags held in memory,
+// a single admin and a fixed set of clients. Errors can be injected into its item writes.
+type memoryBackend struct {
+	mu      sync.Mutex
+	bags    map[string]map[string]json.RawMessage
+	key     *rsa.PrivateKey
+	clients map[string]bool
+	calls   []string
+	faults  map[string][]fault
+}
+
+// fault is an error injected into a memoryBackend item write. It is returned instead of applying the
+// write or, when lost is set, after applying it, as though the response were lost.
+type fault struct {
+	err  error
+	lost bool
+}
+
+var _ Backend = (*memoryBackend)(nil)
+
+func newMemoryBackend(t *testing.T) *memoryBackend {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return &memoryBackend{
+		bags:    make(map[string]map[string]json.RawMessage),
+		key:     key,
+		clients: map[string]bool{"node1": true},
+		faults:  make(map[string][]fault),
+	}
+}
+
+// failNext makes the next calls to the named item write fail with errs, in order, without applying them.
+// call is a method name, or a method name and item id such as "UpdateDataBagItem secret1_history" to
+// fail writes of that item only.
+func (m *memoryBackend) failNext(call string, errs ...error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, err := range errs {
+		m.faults[call] = append(m.faults[call], fault{err: err})
+	}
+}
+
+// loseNext makes the next call to the named item write apply, then fail with err.
+func (m *memoryBackend) loseNext(call string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults[call] = append(m.faults[call], fault{err: err, lost: true})
+}
+
+// inject runs apply for a call writing item id, subject to the next fault queued for the item or the call.
+func (m *memoryBackend) inject(call, id string, apply func() error) error {
+	if len(m.faults[call+" "+id]) > 0 {
+		call += " " + id
+	}
+	queue := m.faults[call]
+	if len(queue) == 0 {
+		return apply()
+	}
+	m.faults[call] = queue[1:]
+	if queue[0].lost {
+		_ = apply()
+	}
+	return queue[0].err
+}
+
+// seed stores v as item id of bag, creating the bag if needed, bypassing faults and call recording.
+func (m *memoryBackend) seed(bag, id string, v interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bags[bag] == nil {
+		m.bags[bag] = make(map[string]json.RawMessage)
+	}
+	_ = m.put(bag, id, v)
+}
+
+// has reports whether bag holds item id.
+func (m *memoryBackend) has(bag, id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.bags[bag][id]
+	return ok
+}
+
+// record locks the backend until the returned function is called, and records the call.
+func (m *memoryBackend) record(call string) func() {
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	return m.mu.Unlock
+}
+
+func (m *memoryBackend) ListDataBags(ctx context.Context) (*chef.DataBagListResult, error) {
+	defer m.record("ListDataBags")()
+	out := chef.DataBagListResult{}
+	for bag := range m.bags {
+		out[bag] = "memory://data/" + bag
+	}
+	return &out, nil
+}
+
+func (m *memoryBackend) CreateDataBag(ctx context.Context, name string) error {
+	defer m.record("CreateDataBag")()
+	if _, ok := m.bags[name]; ok {
+		return cheferr.New(http.StatusConflict, http.MethodPost, "data")
+	}
+	m.bags[name] = make(map[string]json.RawMessage)
+	return nil
+}
+
+func (m *memoryBackend) DeleteDataBag(ctx context.Context, name string) error {
+	defer m.record("DeleteDataBag")()
+	delete(m.bags, name)
+	return nil
+}
+
+func (m *memoryBackend) ListDataBagItems(ctx context.Context, bag string) (*chef.DataBagListResult, error) {
+	defer m.record("ListDataBagItems")()
+	items, ok := m.bags[bag]
+	if !ok {
+		return nil, cheferr.New(http.StatusNotFound, http.MethodGet, "data/"+bag)
+	}
+	out := chef.DataBagListResult{}
+	for id := range items {
+		out[id] = "memory://data/" + bag + "/" + id
+	}
+	return &out, nil
+}
+
+func (m *memoryBackend) GetDataBagItem(ctx context.Context, bag, id string) (chef.DataBagItem, error) {
+	defer m.record("GetDataBagItem")()
+	raw, ok := m.bags[bag][id]
+	if !ok {
+		return nil, cheferr.New(http.StatusNotFound, http.MethodGet, "data/"+bag+"/"+id)
+	}
+	var out map[string]interface{}
+	return out, json.Unmarshal(raw, &out)
+}
+
+func (m *memoryBackend) CreateDataBagItem(ctx context.Context, bag string, it chef.DataBagItem) error {
+	defer m.record("CreateDataBagItem")()
+	// callers pass items and pointers to them, which go-chef encodes alike.
+	raw, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return err
+	}
+	id, _ := content["id"].(string)
+	return m.inject("CreateDataBagItem", id, func() error {
+		if _, ok := m.bags[bag][id]; ok {
+			return cheferr.New(http.StatusConflict, http.MethodPost, "data/"+bag)
+		}
+		return m.put(bag, id, content)
+	})
+}
+
+func (m *memoryBackend) UpdateDataBagItem(ctx context.Context, bag, id string, it chef.DataBagItem) error {
+	defer m.record("UpdateDataBagItem")()
+	return m.inject("UpdateDataBagItem", id, func() error {
+		if _, ok := m.bags[bag][id]; !ok {
+			return cheferr.New(http.StatusNotFound, http.MethodPut, "data/"+bag+"/"+id)
+		}
+		return m.put(bag, id, it)
+	})
+}
+
+func (m *memoryBackend) put(bag, id string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if m.bags[bag] == nil {
+		return cheferr.New(http.StatusNotFound, http.MethodPost, "data/"+bag)
+	}
+	m.bags[bag][id] = raw
+	return nil
+}
+
+func (m *memoryBackend) DeleteDataBagItem(ctx context.Context, bag, id string) error {
+	defer m.record("DeleteDataBagItem")()
+	return m.inject("DeleteDataBagItem", id, func() error {
+		if _, ok := m.bags[bag][id]; !ok {
+			return cheferr.New(http.StatusNotFound, http.MethodDelete, "data/"+bag+"/"+id)
+		}
+		delete(m.bags[bag], id)
+		return nil
+	})
+}
+
+// publicKeyPEM returns the PEM encoding of the admin's public key.
+func (m *memoryBackend) publicKeyPEM(t *testing.T) string {
+	t.Helper()
+
+	key, err := m.publicKey()
+	require.NoError(t, err)
+	return key.PublicKey
+}
+
+func (m *memoryBackend) publicKey() (chef.AccessKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(&m.key.PublicKey)
+	if err != nil {
+		return chef.AccessKey{}, err
+	}
+	return chef.AccessKey{
+		Name:      "default",
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, nil
+}
+
+func (m *memoryBackend) UserKey(ctx context.Context, name string) (chef.AccessKey, error) {
+	defer m.record("UserKey")()
+	if name != m.ActorName() {
+		return chef.AccessKey{}, cheferr.New(http.StatusNotFound, http.MethodGet, "users/"+name)
+	}
+	return m.publicKey()
+}
+
+func (m *memoryBackend) ClientKey(ctx context.Context, name string) (chef.AccessKey, error) {
+	defer m.record("ClientKey")()
+	if !m.clients[name] {
+		return chef.AccessKey{}, cheferr.New(http.StatusNotFound, http.MethodGet, "clients/"+name)
+	}
+	return m.publicKey()
+}
+
+func (m *memoryBackend) ClientExists(ctx context.Context, name string) (bool, error) {
+	defer m.record("ClientExists")()
+	return m.clients[name], nil
+}
+
+func (m *memoryBackend) PartialSearch(ctx context.Context, _, _ string, start int, _ map[string]interface{}) (*SearchResult, error) {
+	defer m.record("PartialSearch")()
+	out := &SearchResult{Total: len(m.clients)}
+	if start > 0 {
+		return out, nil
+	}
+	for name := range m.clients {
+		row, _ := json.Marshal(map[string]string{"name": name})
+		out.Rows = append(out.Rows, row)
+	}
+	return out, nil
+}
+
+func (m *memoryBackend) ActorName() string {
+	return userid
+}
+
+func (m *memoryBackend) PrivateKey() *rsa.PrivateKey {
+	return m.key
+}
+
+func (m *memoryBackend) BaseURL() *url.URL {
+	return &url.URL{Scheme: "memory", Host: "chef"}
+}
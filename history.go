@@ -0,0 +1,462 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+)
+
+// historyItemSuffix is appended to an item name to form the id of its version history companion item.
+const historyItemSuffix = "_history"
+
+// HistoryEntry describes a single version of a vault item.
+type HistoryEntry struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Actor     string    `json:"actor"`
+	Current   bool      `json:"current,omitempty"`
+}
+
+// HistoryResponse represents the structure of the response from a History operation.
+type HistoryResponse struct {
+	Response
+	Limit    int            `json:"limit"`
+	Versions []HistoryEntry `json:"versions"`
+}
+
+// RollbackResponse represents the structure of the response from a Rollback operation, which rewrites
+// the item as an Update does.
+type RollbackResponse = UpdateResponse
+
+// itemHistory is the decrypted form of a version history item.
+type itemHistory struct {
+	Limit    int
+	Current  HistoryEntry
+	Versions []historyVersion
+}
+
+// historyVersion is a previous version of a vault item and its decrypted content.
+type historyVersion struct {
+	HistoryEntry
+	Content map[string]interface{}
+}
+
+// storedHistory is the persisted shape of a version history item. Version content is encrypted
+// with the item's current shared secret, so only the item's current actors can read it.
+type storedHistory struct {
+	Id       string          `json:"id"`
+	Limit    int             `json:"limit"`
+	Current  HistoryEntry    `json:"current"`
+	Versions []storedVersion `json:"versions"`
+}
+
+// storedVersion is a previous version of a vault item with its content still encrypted.
+type storedVersion struct {
+	HistoryEntry
+	Content interface{} `json:"content"`
+}
+
+// rollbackOps defines the callable operations required to execute a Rollback request.
+type rollbackOps struct {
+	getItemVersion func(context.Context, string, string, int) (chef.DataBagItem, error)
+	updateVault    func(context.Context, *Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
+}
+
+// History lists the versions retained for a vault item, newest first. Versions are only retained
+// once Service.HistoryLimit has been set for an item; items without history return no versions.
+func (s *Service) History(vaultName, vaultItem string) (resp *HistoryResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.history", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.loadStoredHistory(ctx, pl.VaultName, pl.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HistoryResponse{
+		Response: Response{
+			URI: fmt.Sprintf("%s/%s", s.vaultURL(pl.VaultName), pl.VaultItemName+historyItemSuffix),
+		},
+		Versions: []HistoryEntry{},
+	}
+	if stored == nil {
+		return result, nil
+	}
+
+	result.Limit = stored.Limit
+	current := stored.Current
+	current.Current = true
+	result.Versions = append(result.Versions, current)
+	for _, v := range stored.Versions {
+		result.Versions = append(result.Versions, v.HistoryEntry)
+	}
+
+	return result, nil
+}
+
+// GetItemVersion returns the decrypted content of a retained version of a vault item.
+// Requesting the current version is equivalent to GetItem.
+func (s *Service) GetItemVersion(vaultName, vaultItem string, version int) (content chef.DataBagItem, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.get_item_version",
+		Attribute{"vault", vaultName}, Attribute{"item", vaultItem}, Attribute{"version", version})
+	defer sp.end(&err)
+
+	return s.getItemVersion(ctx, vaultName, vaultItem, version)
+}
+
+// getItemVersion reads a retained version of a vault item under ctx.
+func (s *Service) getItemVersion(ctx context.Context, vaultName, vaultItem string, version int) (chef.DataBagItem, error) {
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.loadStoredHistory(ctx, pl.VaultName, pl.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("%w: %s/%s has no history", ErrVersionNotFound, pl.VaultName, pl.VaultItemName)
+	}
+
+	if version == stored.Current.Version {
+		return s.GetItemContext(ctx, pl.VaultName, pl.VaultItemName)
+	}
+
+	secret, err := s.loadSharedSecret(ctx, pl)
+	if err != nil {
+		return nil, err
+	}
+
+	hist, err := decodeHistory(stored, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range hist.Versions {
+		if v.Version == version {
+			v.Content["id"] = pl.VaultItemName
+			return v.Content, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s/%s version %d", ErrVersionNotFound, pl.VaultName, pl.VaultItemName, version)
+}
+
+// Rollback restores a retained version of a vault item. The old content is re-encrypted under a new
+// shared secret for the item's current admins and clients, so actors removed since that version
+// do not regain access. The content being replaced is itself retained as a new version.
+func (s *Service) Rollback(vaultName, vaultItem string, version int) (resp *RollbackResponse, err error) {
+	defer s.observe(OpRollback, time.Now(), &err)
+	ctx, sp := s.startSpan(context.Background(), "vault."+OpRollback,
+		Attribute{"vault", vaultName}, Attribute{"item", vaultItem}, Attribute{"version", version})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	ops := rollbackOps{
+		getItemVersion: s.getItemVersion,
+		updateVault:    s.updateVault,
+	}
+	return withItemLock(ctx, s, pl, func(ctx context.Context) (*RollbackResponse, error) {
+		return withAudit(ctx, s, OpRollback, pl.VaultName, pl.VaultItemName, func() (*RollbackResponse, error) {
+			return s.rollback(ctx, pl, version, ops)
+		})
+	})
+}
+
+// rollback is the worker called by the public API with the operational methods to complete the Rollback request.
+func (s *Service) rollback(ctx context.Context, payload *Payload, version int, ops rollbackOps) (*RollbackResponse, error) {
+	revision, err := s.expectedRevision(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := ops.getItemVersion(ctx, payload.VaultName, payload.VaultItemName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := item.DataBagItemMap(old)
+	if err != nil {
+		return nil, err
+	}
+
+	// the schema may have changed since the version was retained.
+	if err := s.validateContent(ctx, payload, content); err != nil {
+		return nil, err
+	}
+
+	keyState, err := s.loadKeysCurrentState(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	rollbackPayload := &Payload{
+		VaultName:     payload.VaultName,
+		VaultItemName: payload.VaultItemName,
+		Content:       content,
+		KeysMode:      &keyState.Mode,
+		SearchQuery:   item_keys.NormalizeSearchQuery(keyState.SearchQuery),
+		Admins:        keyState.Admins,
+		Clients:       keyState.Clients,
+	}
+
+	modeState := &item_keys.KeysModeState{
+		Current: keyState.Mode,
+		Desired: keyState.Mode,
+	}
+
+	if err := s.checkRevision(ctx, payload.VaultName, payload.VaultItemName, revision); err != nil {
+		return nil, err
+	}
+
+	keysResult, err := ops.updateVault(ctx, rollbackPayload, modeState)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RollbackResponse{
+		Response: Response{
+			URI: s.vaultURL(rollbackPayload.VaultName),
+		},
+		Data: &UpdateDataResponse{
+			URI: fmt.Sprintf("%s/%s", s.vaultURL(rollbackPayload.VaultName), rollbackPayload.VaultItemName),
+		},
+		KeysURIs: keysResult.URIs,
+	}, nil
+}
+
+// prepareHistory decrypts the existing history of an item before it is re-encrypted and, when the
+// new content differs from the current content, retains the current content as a version.
+// A nil history is returned when history is not enabled for the item, and a history that cannot be
+// decrypted with the current secret is restarted.
+func (s *Service) prepareHistory(ctx context.Context, payload *Payload) (*itemHistory, error) {
+	stored, err := s.loadStoredHistory(ctx, payload.VaultName, payload.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil && s.HistoryLimit <= 0 {
+		return nil, nil
+	}
+
+	secret, err := s.loadSharedSecret(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	hist := &itemHistory{
+		Current: HistoryEntry{Version: 1},
+	}
+	if stored != nil {
+		decoded, err := decodeHistory(stored, secret)
+		if err != nil {
+			// a history write that failed after the keys were replaced leaves the history under a
+			// secret that no longer exists; it is dropped rather than blocking every later write.
+			s.logger().Error("discarding history that cannot be decrypted", "vault", payload.VaultName,
+				"item", payload.VaultItemName, "error", err)
+			decoded = &itemHistory{Limit: stored.Limit, Current: stored.Current}
+		}
+		hist = decoded
+	}
+	if s.HistoryLimit > 0 {
+		hist.Limit = s.HistoryLimit
+	}
+
+	rawItem, err := s.backend().GetDataBagItem(ctx, payload.VaultName, payload.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := item.Decrypt(rawItem, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := item.DataBagItemMap(decrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := contentChanged(previous, payload.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		delete(previous, "id")
+		hist.Versions = append([]historyVersion{{
+			HistoryEntry: hist.Current,
+			Content:      previous,
+		}}, hist.Versions...)
+		hist.Current = HistoryEntry{
+			Version:   hist.Current.Version + 1,
+			CreatedAt: time.Now().UTC(),
+			Actor:     s.backend().ActorName(),
+		}
+	}
+
+	if len(hist.Versions) > hist.Limit {
+		hist.Versions = hist.Versions[:hist.Limit]
+	}
+
+	return hist, nil
+}
+
+// writeHistory encrypts the history with the item's new shared secret and stores it.
+func (s *Service) writeHistory(ctx context.Context, payload *Payload, hist *itemHistory, secret []byte) error {
+	stored, err := encodeHistory(payload.VaultItemName+historyItemSuffix, hist, secret)
+	if err != nil {
+		return err
+	}
+
+	return s.putHistory(ctx, payload.VaultName, stored)
+}
+
+// startHistory records the first version of a newly created item when history is enabled.
+func (s *Service) startHistory(ctx context.Context, payload *Payload) error {
+	if s.HistoryLimit <= 0 {
+		return nil
+	}
+
+	return s.putHistory(ctx, payload.VaultName, &storedHistory{
+		Id:    payload.VaultItemName + historyItemSuffix,
+		Limit: s.HistoryLimit,
+		Current: HistoryEntry{
+			Version:   1,
+			CreatedAt: time.Now().UTC(),
+			Actor:     s.backend().ActorName(),
+		},
+		Versions: []storedVersion{},
+	})
+}
+
+// putHistory creates or replaces the stored history item.
+func (s *Service) putHistory(ctx context.Context, vaultName string, stored *storedHistory) error {
+	return putDataBagItem(ctx, s.backend(), vaultName, stored.Id, stored)
+}
+
+// loadStoredHistory fetches the history item of a vault item, returning nil when it does not exist.
+func (s *Service) loadStoredHistory(ctx context.Context, vaultName, vaultItem string) (*storedHistory, error) {
+	raw, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem+historyItemSuffix)
+	if err != nil {
+		if cheferr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored storedHistory
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(stored.Versions, func(i, j int) bool {
+		return stored.Versions[i].Version > stored.Versions[j].Version
+	})
+
+	return &stored, nil
+}
+
+// decodeHistory decrypts the content of every stored version with the item's shared secret.
+func decodeHistory(stored *storedHistory, secret []byte) (*itemHistory, error) {
+	hist := &itemHistory{
+		Limit:    stored.Limit,
+		Current:  stored.Current,
+		Versions: make([]historyVersion, 0, len(stored.Versions)),
+	}
+
+	for _, v := range stored.Versions {
+		content, err := item.DecryptValue(v.Content, secret)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt version %d: %w", v.Version, err)
+		}
+
+		contentMap, ok := content.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("version %d contains unexpected content type %T", v.Version, content)
+		}
+
+		hist.Versions = append(hist.Versions, historyVersion{
+			HistoryEntry: v.HistoryEntry,
+			Content:      contentMap,
+		})
+	}
+
+	return hist, nil
+}
+
+// encodeHistory encrypts the content of every version with the given shared secret.
+func encodeHistory(id string, hist *itemHistory, secret []byte) (*storedHistory, error) {
+	stored := &storedHistory{
+		Id:       id,
+		Limit:    hist.Limit,
+		Current:  hist.Current,
+		Versions: make([]storedVersion, 0, len(hist.Versions)),
+	}
+
+	for _, v := range hist.Versions {
+		encrypted, err := item.EncryptValue(v.Content, secret)
+		if err != nil {
+			return nil, err
+		}
+
+		stored.Versions = append(stored.Versions, storedVersion{
+			HistoryEntry: v.HistoryEntry,
+			Content:      encrypted,
+		})
+	}
+
+	return stored, nil
+}
+
+// contentChanged reports whether two versions of item content differ, ignoring the item id.
+func contentChanged(previous, next map[string]interface{}) (bool, error) {
+	a, err := normalizeContent(previous)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := normalizeContent(next)
+	if err != nil {
+		return false, err
+	}
+
+	delete(a, "id")
+	delete(b, "id")
+
+	return !reflect.DeepEqual(a, b), nil
+}
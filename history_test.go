@@ -0,0 +1,243 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/stretchr/testify/require"
+)
+
+type rollbackRecorder struct {
+	calls []string
+	wrote struct {
+		payload *Payload
+		state   *item_keys.KeysModeState
+	}
+}
+
+func (r *rollbackRecorder) ops() rollbackOps {
+	return rollbackOps{
+		getItemVersion: func(_ context.Context, _, _ string, version int) (chef.DataBagItem, error) {
+			r.calls = append(r.calls, fmt.Sprintf("getItemVersion:%d", version))
+			return map[string]interface{}{
+				"id":  "secret1",
+				"foo": "foo-value-0",
+			}, nil
+		},
+		updateVault: func(_ context.Context, payload *Payload, state *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
+			r.calls = append(r.calls, "updateVault")
+			r.wrote.payload = payload
+			r.wrote.state = state
+			return &item_keys.VaultItemKeysResult{
+				URIs: []string{"https://localhost/data/vault1/secret1_keys"},
+			}, nil
+		},
+	}
+}
+
+func TestRollback_UsesCurrentAccess(t *testing.T) {
+	setupStubs(t)
+
+	rec := &rollbackRecorder{}
+
+	_, err := service.rollback(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+	}, 2, rec.ops())
+	require.NoError(t, err)
+	require.Equal(t, []string{"getItemVersion:2", "updateVault"}, rec.calls)
+	require.Equal(t, "foo-value-0", rec.wrote.payload.Content["foo"])
+	require.ElementsMatch(t, []string{"pivotal", "tester"}, rec.wrote.payload.Admins)
+	require.Equal(t, []string{"testhost"}, rec.wrote.payload.Clients)
+	require.Equal(t, "name:testhost*", *rec.wrote.payload.SearchQuery)
+	require.Equal(t, item_keys.KeysModeDefault, rec.wrote.state.Desired)
+}
+
+func TestRollback_ValidatesSchema(t *testing.T) {
+	setupStubs(t)
+
+	rec := &rollbackRecorder{}
+
+	_, err := service.rollback(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Schema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"bar"},
+		},
+	}, 2, rec.ops())
+	require.ErrorIs(t, err, ErrSchemaValidation)
+	require.Equal(t, []string{"getItemVersion:2"}, rec.calls)
+}
+
+func TestHistory_EncodeDecodeRoundTrip(t *testing.T) {
+	secret, err := item_keys.GenSecret(32)
+	require.NoError(t, err)
+
+	hist := &itemHistory{
+		Limit:   3,
+		Current: HistoryEntry{Version: 3},
+		Versions: []historyVersion{
+			{HistoryEntry: HistoryEntry{Version: 2}, Content: map[string]interface{}{"foo": "two"}},
+			{HistoryEntry: HistoryEntry{Version: 1}, Content: map[string]interface{}{"foo": "one"}},
+		},
+	}
+
+	stored, err := encodeHistory("secret1_history", hist, secret)
+	require.NoError(t, err)
+	require.Equal(t, "secret1_history", stored.Id)
+
+	decoded, err := decodeHistory(stored, secret)
+	require.NoError(t, err)
+	require.Equal(t, hist, decoded)
+
+	other, err := item_keys.GenSecret(32)
+	require.NoError(t, err)
+	_, err = decodeHistory(stored, other)
+	require.Error(t, err)
+}
+
+func TestPrepareHistory_DisabledWithoutHistory(t *testing.T) {
+	setupStubs(t)
+
+	hist, err := service.prepareHistory(context.Background(), &Payload{VaultName: "vault1", VaultItemName: "secret1"})
+	require.NoError(t, err)
+	require.Nil(t, hist)
+}
+
+func TestPrepareHistory_RetainsChangedContent(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+	stubEncryptedItem(t, map[string]interface{}{"foo": "foo-value-1"})
+
+	service.HistoryLimit = 1
+
+	hist, err := service.prepareHistory(context.Background(), &Payload{
+		VaultName:     "vault2",
+		VaultItemName: "secret2",
+		Content:       map[string]interface{}{"foo": "foo-value-2"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, hist.Current.Version)
+	require.Equal(t, userid, hist.Current.Actor)
+	require.Len(t, hist.Versions, 1)
+	require.Equal(t, 1, hist.Versions[0].Version)
+	require.Equal(t, map[string]interface{}{"foo": "foo-value-1"}, hist.Versions[0].Content)
+}
+
+func TestPrepareHistory_UnchangedContentKeepsVersion(t *testing.T) {
+	setup(t)
+	t.Cleanup(teardown)
+	stubEncryptedItem(t, map[string]interface{}{"foo": "foo-value-1"})
+
+	service.HistoryLimit = 5
+
+	hist, err := service.prepareHistory(context.Background(), &Payload{
+		VaultName:     "vault2",
+		VaultItemName: "secret2",
+		Content:       map[string]interface{}{"id": "secret2", "foo": "foo-value-1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, hist.Current.Version)
+	require.Empty(t, hist.Versions)
+}
+
+func TestService_History(t *testing.T) {
+	setupStubs(t)
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mux.HandleFunc("/data/vault1/secret1_history", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(storedHistory{
+			Id:      "secret1_history",
+			Limit:   5,
+			Current: HistoryEntry{Version: 3, CreatedAt: created, Actor: "tester"},
+			Versions: []storedVersion{
+				{HistoryEntry: HistoryEntry{Version: 1}},
+				{HistoryEntry: HistoryEntry{Version: 2, CreatedAt: created, Actor: "pivotal"}},
+			},
+		})
+	})
+
+	resp, err := service.History("vault1", "secret1")
+	require.NoError(t, err)
+	require.Equal(t, 5, resp.Limit)
+	require.Equal(t, []HistoryEntry{
+		{Version: 3, CreatedAt: created, Actor: "tester", Current: true},
+		{Version: 2, CreatedAt: created, Actor: "pivotal"},
+		{Version: 1},
+	}, resp.Versions)
+}
+
+// stubEncryptedItem serves vault2/secret2 encrypted with a shared secret readable by the test client.
+func stubEncryptedItem(t *testing.T, content map[string]interface{}) []byte {
+	t.Helper()
+
+	secret, err := item_keys.GenSecret(32)
+	require.NoError(t, err)
+
+	pub := publicKeyPEM(t, client)
+	actorKey, err := item_keys.EncryptSharedSecret(pub, secret)
+	require.NoError(t, err)
+
+	encrypted, err := item.Encrypt("secret2", content, secret)
+	require.NoError(t, err)
+
+	mux.HandleFunc("/data/vault2/secret2", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(encrypted)
+	})
+	mux.HandleFunc("/data/vault2/secret2_keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":           "secret2_keys",
+			"admins":       []string{userid},
+			"clients":      []string{},
+			"search_query": []string{},
+			"mode":         "default",
+			userid:         actorKey,
+		})
+	})
+	mux.HandleFunc("/", http.NotFound)
+
+	return secret
+}
+
+func TestUpdate_SurvivesFailedHistoryWrite(t *testing.T) {
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+	svc.HistoryLimit = 5
+
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "one"},
+		Admins:        []string{userid},
+	})
+	require.NoError(t, err)
+	_, err = svc.Update(&Payload{VaultName: "vault1", VaultItemName: "secret1", Content: map[string]interface{}{"password": "two"}})
+	require.NoError(t, err)
+
+	// the keys and item are replaced, but the history stays under the outgoing secret.
+	backend.failNext("UpdateDataBagItem secret1_history", cheferr.New(http.StatusBadGateway, http.MethodPut, "data/vault1/secret1_history"))
+	_, err = svc.Update(&Payload{VaultName: "vault1", VaultItemName: "secret1", Content: map[string]interface{}{"password": "three"}})
+	require.Error(t, err)
+
+	_, err = svc.Update(&Payload{VaultName: "vault1", VaultItemName: "secret1", Content: map[string]interface{}{"password": "four"}})
+	require.NoError(t, err)
+
+	hist, err := svc.History("vault1", "secret1")
+	require.NoError(t, err)
+	// the versions the dropped history held are lost, and numbering continues from the stored current.
+	require.Len(t, hist.Versions, 2)
+	require.Equal(t, 3, hist.Versions[0].Version)
+
+	got, err := svc.GetItemVersion("vault1", "secret1", 2)
+	require.NoError(t, err)
+	require.Equal(t, "three", got.(map[string]interface{})["password"])
+}
@@ -0,0 +1,255 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+)
+
+// ImportAction describes what Import did with a manifest item.
+type ImportAction string
+
+const (
+	// ImportCreated indicates the item did not exist and was created.
+	ImportCreated ImportAction = "created"
+
+	// ImportUpdated indicates the item existed and its content or access was updated.
+	ImportUpdated ImportAction = "updated"
+
+	// ImportUnchanged indicates the item already matched the manifest.
+	ImportUnchanged ImportAction = "unchanged"
+
+	// ImportFailed indicates the item could not be imported; see ImportItemResult.Error.
+	ImportFailed ImportAction = "failed"
+)
+
+// ImportItemResult describes the outcome of importing a single manifest item.
+type ImportItemResult struct {
+	VaultName     string       `json:"vault"`
+	VaultItemName string       `json:"item"`
+	Action        ImportAction `json:"action"`
+	URI           string       `json:"uri,omitempty"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// ImportResponse represents the structure of the response from an Import operation.
+type ImportResponse struct {
+	Items     []ImportItemResult `json:"items"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Failed    int                `json:"failed"`
+}
+
+// importOps defines the callable operations required to execute an Import request.
+type importOps struct {
+	loadKeys func(context.Context, *Payload) (*item_keys.VaultItemKeys, error)
+	getItem  func(context.Context, string, string) (chef.DataBagItem, error)
+	create   func(context.Context, *Payload) (*CreateResponse, error)
+	replace  func(context.Context, *Payload) (*UpdateResponse, error)
+}
+
+// Import loads a manifest file or directory (see LoadManifest) and applies it with ImportManifest.
+func (s *Service) Import(path string) (*ImportResponse, error) {
+	m, err := LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.ImportManifest(m)
+}
+
+// ImportManifest creates the manifest items that do not exist and updates those whose content, admins,
+// clients, search query, or keys mode differ. Existing content is replaced by the manifest content,
+// while admins and clients not named in the manifest keep their access.
+//
+// Every item is attempted; failures are recorded in the per-item results, and ErrImportFailed is
+// returned alongside the response when any item failed.
+func (s *Service) ImportManifest(m *Manifest) (resp *ImportResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.import")
+	defer sp.end(&err)
+
+	if m == nil {
+		return nil, ErrNilPayload
+	}
+
+	ops := importOps{
+		loadKeys: s.loadKeysCurrentState,
+		getItem:  s.GetItemContext,
+		create:   s.CreateContext,
+		replace:  s.replaceItem,
+	}
+	return s.importManifest(ctx, m, ops)
+}
+
+// importManifest is the worker called by the public API with the operational methods to complete the import request.
+func (s *Service) importManifest(ctx context.Context, m *Manifest, ops importOps) (*ImportResponse, error) {
+	result := &ImportResponse{Items: make([]ImportItemResult, 0)}
+
+	for _, v := range m.Vaults {
+		for _, mi := range v.Items {
+			res := ImportItemResult{VaultName: v.Name, VaultItemName: mi.Name}
+
+			action, uri, err := s.importItem(ctx, v, mi, ops)
+			if err != nil {
+				res.Action = ImportFailed
+				res.Error = err.Error()
+			} else {
+				res.Action = action
+				res.URI = uri
+			}
+
+			switch res.Action {
+			case ImportCreated:
+				result.Created++
+			case ImportUpdated:
+				result.Updated++
+			case ImportUnchanged:
+				result.Unchanged++
+			case ImportFailed:
+				result.Failed++
+			}
+			result.Items = append(result.Items, res)
+		}
+	}
+
+	if result.Failed != 0 {
+		return result, fmt.Errorf("%w: %d of %d items", ErrImportFailed, result.Failed, len(result.Items))
+	}
+	return result, nil
+}
+
+// importItem creates or updates a single manifest item, returning what was done.
+func (s *Service) importItem(ctx context.Context, v ManifestVault, mi ManifestItem, ops importOps) (ImportAction, string, error) {
+	payload, err := importPayload(v, mi)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := payload.validatePayload(); err != nil {
+		return "", "", err
+	}
+
+	keyState, err := ops.loadKeys(ctx, payload)
+	if err != nil {
+		if !cheferr.IsNotFound(err) {
+			return "", "", err
+		}
+
+		created, err := ops.create(ctx, payload)
+		if err != nil {
+			return "", "", err
+		}
+		return ImportCreated, created.Data.URI, nil
+	}
+
+	current, err := ops.getItem(ctx, payload.VaultName, payload.VaultItemName)
+	if err != nil {
+		return "", "", err
+	}
+
+	currMap, err := item.DataBagItemMap(current)
+	if err != nil {
+		return "", "", err
+	}
+
+	normalized, err := normalizeContent(currMap)
+	if err != nil {
+		return "", "", err
+	}
+
+	uri := fmt.Sprintf("%s/%s", s.vaultURL(payload.VaultName), payload.VaultItemName)
+	if len(item.Diff(normalized, payload.Content)) == 0 && !accessDiffers(payload, keyState) {
+		return ImportUnchanged, uri, nil
+	}
+
+	if _, err := ops.replace(ctx, payload); err != nil {
+		return "", "", err
+	}
+	return ImportUpdated, uri, nil
+}
+
+// replaceItem updates a vault item with the payload content as-is rather than merging it into the current content.
+func (s *Service) replaceItem(ctx context.Context, payload *Payload) (resp *UpdateResponse, err error) {
+	defer s.observe(OpReplace, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpReplace, payload.traceAttributes()...)
+	defer sp.end(&err)
+
+	ops := updateOps{
+		resolveUpdateContent: func(_ context.Context, p *Payload) (map[string]interface{}, error) {
+			return p.Content, nil
+		},
+		updateVault: s.updateVault,
+	}
+	return withItemLock(ctx, s, payload, func(ctx context.Context) (*UpdateResponse, error) {
+		return withAudit(ctx, s, OpReplace, payload.VaultName, payload.VaultItemName, func() (*UpdateResponse, error) {
+			return s.update(ctx, payload, ops)
+		})
+	})
+}
+
+// importPayload builds the Payload for a manifest item, applying the vault-level defaults.
+func importPayload(v ManifestVault, mi ManifestItem) (*Payload, error) {
+	content, err := mi.content()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &Payload{
+		VaultName:     v.Name,
+		VaultItemName: mi.Name,
+		Content:       content,
+		Admins:        item_keys.MergeClients(v.Admins, mi.Admins),
+		Clients:       item_keys.MergeClients(v.Clients, mi.Clients),
+		SearchQuery:   v.SearchQuery,
+	}
+
+	if mi.SearchQuery != nil {
+		payload.SearchQuery = mi.SearchQuery
+	}
+
+	mode := v.KeysMode
+	if mi.KeysMode != "" {
+		mode = mi.KeysMode
+	}
+	if mode != "" {
+		payload.KeysMode = &mode
+	}
+
+	return payload, nil
+}
+
+// accessDiffers reports whether the payload grants access or sets a search query or keys mode
+// that the current keys item does not already reflect.
+func accessDiffers(payload *Payload, keyState *item_keys.VaultItemKeys) bool {
+	if len(item_keys.DiffLists(payload.Admins, keyState.Admins)) != 0 {
+		return true
+	}
+
+	if len(item_keys.DiffLists(payload.Clients, keyState.Clients)) != 0 {
+		return true
+	}
+
+	if payload.SearchQuery != nil {
+		current := item_keys.NormalizeSearchQuery(keyState.SearchQuery)
+		if current == nil || *current != *payload.SearchQuery {
+			return true
+		}
+	}
+
+	if payload.KeysMode != nil {
+		current := keyState.Mode
+		if current == "" {
+			current = item_keys.KeysModeDefault
+		}
+		if current != *payload.KeysMode {
+			return true
+		}
+	}
+
+	return false
+}
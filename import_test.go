@@ -0,0 +1,122 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/stretchr/testify/require"
+)
+
+type importRecorder struct {
+	keys     map[string]*item_keys.VaultItemKeys
+	content  map[string]chef.DataBagItem
+	created  []*Payload
+	replaced []*Payload
+}
+
+func (r *importRecorder) ops() importOps {
+	return importOps{
+		loadKeys: func(_ context.Context, p *Payload) (*item_keys.VaultItemKeys, error) {
+			ks, ok := r.keys[p.VaultItemName]
+			if !ok {
+				return nil, &chef.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+			}
+			return ks, nil
+		},
+		getItem: func(_ context.Context, _, name string) (chef.DataBagItem, error) {
+			return r.content[name], nil
+		},
+		create: func(_ context.Context, p *Payload) (*CreateResponse, error) {
+			if p.VaultItemName == "broken" {
+				return nil, errors.New("boom")
+			}
+			r.created = append(r.created, p)
+			return &CreateResponse{Data: &CreateDataResponse{URI: "created/" + p.VaultItemName}}, nil
+		},
+		replace: func(_ context.Context, p *Payload) (*UpdateResponse, error) {
+			r.replaced = append(r.replaced, p)
+			return &UpdateResponse{}, nil
+		},
+	}
+}
+
+func TestImportManifest_Actions(t *testing.T) {
+	setupStubs(t)
+
+	query := "name:web*"
+	rec := &importRecorder{
+		keys: map[string]*item_keys.VaultItemKeys{
+			"same":    {Admins: []string{"pivotal"}, Mode: item_keys.KeysModeDefault},
+			"content": {Admins: []string{"pivotal"}},
+			"access":  {Admins: []string{"pivotal"}, SearchQuery: []interface{}{}},
+		},
+		content: map[string]chef.DataBagItem{
+			"same":    map[string]interface{}{"id": "same", "port": float64(1)},
+			"content": map[string]interface{}{"id": "content", "pass": "old", "stale": true},
+			"access":  map[string]interface{}{"id": "access"},
+		},
+	}
+
+	m := &Manifest{Vaults: []ManifestVault{{
+		Name:   "vault1",
+		Admins: []string{"pivotal"},
+		Items: []ManifestItem{
+			{Name: "new", Content: map[string]interface{}{"k": "v"}},
+			{Name: "same", Content: map[string]interface{}{"port": 1}},
+			{Name: "content", Content: map[string]interface{}{"pass": "new"}},
+			{Name: "access", SearchQuery: &query},
+			{Name: "broken"},
+		},
+	}}}
+
+	resp, err := service.importManifest(context.Background(), m, rec.ops())
+	require.ErrorIs(t, err, ErrImportFailed)
+	require.Equal(t, 1, resp.Created)
+	require.Equal(t, 2, resp.Updated)
+	require.Equal(t, 1, resp.Unchanged)
+	require.Equal(t, 1, resp.Failed)
+
+	actions := make(map[string]ImportAction)
+	for _, res := range resp.Items {
+		actions[res.VaultItemName] = res.Action
+	}
+	require.Equal(t, map[string]ImportAction{
+		"new":     ImportCreated,
+		"same":    ImportUnchanged,
+		"content": ImportUpdated,
+		"access":  ImportUpdated,
+		"broken":  ImportFailed,
+	}, actions)
+	require.Equal(t, "boom", resp.Items[4].Error)
+
+	require.Len(t, rec.created, 1)
+	require.Equal(t, []string{"pivotal"}, rec.created[0].Admins)
+
+	require.Len(t, rec.replaced, 2)
+	require.Equal(t, map[string]interface{}{"id": "content", "pass": "new"}, rec.replaced[0].Content)
+	require.Equal(t, &query, rec.replaced[1].SearchQuery)
+}
+
+func TestImportManifest_Nil(t *testing.T) {
+	_, err := service.ImportManifest(nil)
+	require.ErrorIs(t, err, ErrNilPayload)
+}
+
+func TestAccessDiffers(t *testing.T) {
+	sparse := item_keys.KeysModeSparse
+	ks := &item_keys.VaultItemKeys{Admins: []string{"a", "b"}, Clients: []string{"c"}, SearchQuery: "role:web"}
+
+	require.False(t, accessDiffers(&Payload{Admins: []string{"a"}}, ks))
+	require.True(t, accessDiffers(&Payload{Admins: []string{"z"}}, ks))
+	require.True(t, accessDiffers(&Payload{Clients: []string{"d"}}, ks))
+	require.True(t, accessDiffers(&Payload{KeysMode: &sparse}, ks))
+
+	q := "role:web"
+	require.False(t, accessDiffers(&Payload{SearchQuery: &q}, ks))
+	q2 := "role:db"
+	require.True(t, accessDiffers(&Payload{SearchQuery: &q2}, ks))
+}
@@ -0,0 +1,46 @@
+// Package fsutil holds the file helpers shared by the packages that write secrets and state to disk.
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile atomically replaces path with data. The data is written to a temp file beside path with
+// mode and, when uid or gid is not -1, that owner, synced, and renamed into place, so that readers
+// see either the previous file or the complete new one.
+func WriteFile(path string, data []byte, mode os.FileMode, uid, gid int) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if err = f.Chmod(mode); err != nil {
+		return err
+	}
+	if uid != -1 || gid != -1 {
+		if err = f.Chown(uid, gid); err != nil {
+			return err
+		}
+	}
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+
+	require.NoError(t, WriteFile(path, []byte("one"), 0o600, -1, -1))
+	require.NoError(t, WriteFile(path, []byte("two"), 0o640, -1, -1))
+
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "two", string(got))
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temp files are renamed into place")
+}
+
+func TestWriteFile_MissingDir(t *testing.T) {
+	err := WriteFile(filepath.Join(t.TempDir(), "missing", "secret"), []byte("one"), 0o600, -1, -1)
+	require.Error(t, err)
+}
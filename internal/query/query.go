@@ -0,0 +1,196 @@
+// Package query evaluates the subset of the Chef search query syntax used by vault search queries
+// against JSON documents such as nodes, clients and data bag items.
+//
+// A query is *:*, or field:value terms joined by AND and OR, AND binding tighter and adjacent terms
+// being joined by OR, as the server's default operator does. A term may be negated with NOT or a
+// leading -, and values may contain the * and ? wildcards and backslash escapes. Parentheses, ranges
+// and quoted phrases are not supported.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalid is returned for queries that are malformed or use unsupported syntax.
+var ErrInvalid = errors.New("query: invalid search query")
+
+// Query is a parsed search query: a disjunction of conjunctions of terms.
+type Query [][]term
+
+// term is a single field:value comparison.
+type term struct {
+	field  string
+	value  *regexp.Regexp
+	negate bool
+}
+
+// Parse parses a search query.
+func Parse(q string) (Query, error) {
+	var (
+		query  Query
+		group  []term
+		op     = "AND"
+		negate bool
+	)
+
+	for _, tok := range strings.Fields(q) {
+		switch tok {
+		case "AND", "OR":
+			if len(group) == 0 || op != "" || negate {
+				return nil, fmt.Errorf("%w: unexpected %s in %q", ErrInvalid, tok, q)
+			}
+			op = tok
+			continue
+		case "NOT":
+			if negate {
+				return nil, fmt.Errorf("%w: unexpected NOT in %q", ErrInvalid, q)
+			}
+			negate = true
+			continue
+		}
+
+		if op == "" {
+			op = "OR"
+		}
+		if op == "OR" && len(group) > 0 {
+			query = append(query, group)
+			group = nil
+		}
+		op = ""
+
+		if rest, ok := strings.CutPrefix(tok, "-"); ok {
+			tok, negate = rest, !negate
+		}
+
+		t, err := parseTerm(tok)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v in %q", ErrInvalid, err, q)
+		}
+		t.negate = negate
+		negate = false
+		group = append(group, t)
+	}
+
+	if len(group) == 0 || op != "" || negate {
+		return nil, fmt.Errorf("%w: incomplete query %q", ErrInvalid, q)
+	}
+	return append(query, group), nil
+}
+
+// parseTerm parses a field:value term.
+func parseTerm(tok string) (term, error) {
+	if strings.ContainsAny(tok, "()[]{}\"") {
+		return term{}, fmt.Errorf("unsupported term %q", tok)
+	}
+
+	field, value, ok := strings.Cut(tok, ":")
+	if !ok || field == "" || value == "" {
+		return term{}, fmt.Errorf("term %q is not field:value", tok)
+	}
+	if field == "*" && value != "*" {
+		return term{}, fmt.Errorf("unsupported term %q", tok)
+	}
+
+	// the server accepts backslash escapes, such as web\-1, which match the literal character.
+	value = strings.ReplaceAll(value, `\`, "")
+
+	pattern := regexp.QuoteMeta(value)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return term{}, err
+	}
+	return term{field: field, value: re}, nil
+}
+
+// Match reports whether doc satisfies the query.
+func (q Query) Match(doc map[string]interface{}) bool {
+	for _, group := range q {
+		all := true
+		for _, t := range group {
+			if t.match(doc) == t.negate {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether any value of the term's field in doc matches the term's value.
+func (t term) match(doc map[string]interface{}) bool {
+	if t.field == "*" {
+		// *:* is the only query over every field the server accepts, and matches everything.
+		return true
+	}
+
+	for _, v := range Values(doc, t.field) {
+		if t.value.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns the values a document has for a search field. role and recipe come from a node's
+// run list and expanded roles and recipes; other fields are read from the document itself and then
+// from its attributes, highest precedence first.
+func Values(doc map[string]interface{}, field string) []string {
+	switch field {
+	case "role", "roles", "recipe", "recipes":
+		kind := strings.TrimSuffix(field, "s")
+		var out []string
+		for _, entry := range stringList(doc["run_list"]) {
+			if name, ok := strings.CutPrefix(entry, kind+"["); ok {
+				out = append(out, strings.TrimSuffix(name, "]"))
+			}
+		}
+		if automatic, ok := doc["automatic"].(map[string]interface{}); ok {
+			out = append(out, stringList(automatic[kind+"s"])...)
+		}
+		return out
+	}
+
+	if v, ok := doc[field]; ok {
+		return stringList(v)
+	}
+	for _, precedence := range []string{"automatic", "override", "normal", "default"} {
+		attrs, ok := doc[precedence].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if v, ok := attrs[field]; ok {
+			return stringList(v)
+		}
+	}
+	return nil
+}
+
+// stringList returns a scalar as a one element list and the scalars of an array as a list.
+func stringList(v interface{}) []string {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, e := range val {
+			if _, ok := e.(map[string]interface{}); !ok {
+				out = append(out, fmt.Sprint(e))
+			}
+		}
+		return out
+	case []string:
+		return val
+	case map[string]interface{}:
+		return nil
+	}
+	return []string{fmt.Sprint(v)}
+}
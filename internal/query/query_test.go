@@ -0,0 +1,53 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	nodes := map[string]map[string]interface{}{
+		"web1": {"name": "web1", "chef_environment": "prod", "run_list": []interface{}{"role[web]"}, "normal": map[string]interface{}{"tags": []interface{}{"blue"}}},
+		"db1":  {"name": "db1", "chef_environment": "prod", "run_list": []interface{}{"recipe[postgres::server]"}},
+		"web2": {"name": "web2", "chef_environment": "dev", "run_list": []interface{}{"role[web]"}},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"*:*", []string{"db1", "web1", "web2"}},
+		{"name:web*", []string{"web1", "web2"}},
+		{"role:web AND chef_environment:prod", []string{"web1"}},
+		{"name:db1 OR name:web2", []string{"db1", "web2"}},
+		{"name:db1 name:web2", []string{"db1", "web2"}},
+		{"recipe:postgres\\:\\:server", []string{"db1"}},
+		{"recipe:postgres*", []string{"db1"}},
+		{"tags:blue", []string{"web1"}},
+		{"name:web?", []string{"web1", "web2"}},
+		{"role:web AND NOT chef_environment:dev", []string{"web1"}},
+		{"chef_environment:prod -name:db*", []string{"db1", "web1", "web2"}},
+		{"chef_environment:prod AND -name:db*", []string{"web1"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := Parse(tc.query)
+			require.NoError(t, err)
+
+			var got []string
+			for _, name := range []string{"db1", "web1", "web2"} {
+				if q.Match(nodes[name]) {
+					got = append(got, name)
+				}
+			}
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	for _, bad := range []string{"", "AND name:x", "name:x OR", "name", "(name:x)", "NOT", "name:x AND NOT", "NOT NOT name:x"} {
+		_, err := Parse(bad)
+		require.ErrorIs(t, err, ErrInvalid, bad)
+	}
+}
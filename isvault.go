@@ -1,11 +1,16 @@
 package vault
 
+import "context"
+
 // IsVault determines whether the data bag item is a vault.
 //
 // References:
 //   - Chef API Docs: https://docs.chef.io/api_chef_server/#get-24
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_isvault.rb
-func (s *Service) IsVault(vaultName, vaultItem string) (bool, error) {
+func (s *Service) IsVault(vaultName, vaultItem string) (ok bool, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.is_vault", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
 	pl := &Payload{
 		VaultName:     vaultName,
 		VaultItemName: vaultItem,
@@ -15,7 +20,7 @@ func (s *Service) IsVault(vaultName, vaultItem string) (bool, error) {
 		return false, err
 	}
 
-	itemType, err := s.ItemType(pl.VaultName, pl.VaultItemName)
+	itemType, err := s.itemType(ctx, pl)
 	if err != nil {
 		return false, err
 	}
@@ -21,16 +21,25 @@ func Decrypt(data chef.DataBagItem, key []byte) (chef.DataBagItem, error) {
 			continue
 		}
 
-		raw, err := json.Marshal(val)
+		d, err := DecryptValue(val, key)
 		if err != nil {
 			return nil, err
 		}
-
-		var d interface{}
-		if err := chefcrypto.Decrypt(key, raw, &d); err != nil {
-			return nil, err
-		}
 		out[dbi] = d
 	}
 	return out, nil
 }
+
+// DecryptValue decrypts a single encrypted data bag value.
+func DecryptValue(val interface{}, key []byte) (interface{}, error) {
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	var d interface{}
+	if err := chefcrypto.Decrypt(key, raw, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,93 @@
+package item
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// ChangeOp classifies a single difference between two versions of item content.
+type ChangeOp string
+
+const (
+	// ChangeAdded indicates a value present only in the newer content.
+	ChangeAdded ChangeOp = "added"
+
+	// ChangeRemoved indicates a value present only in the older content.
+	ChangeRemoved ChangeOp = "removed"
+
+	// ChangeChanged indicates a value present in both with different contents.
+	ChangeChanged ChangeOp = "changed"
+)
+
+// Change describes a difference at a JSON Pointer path between two versions of item content.
+type Change struct {
+	Path string
+	Op   ChangeOp
+	Old  interface{}
+	New  interface{}
+}
+
+// Diff returns the differences between two versions of decoded item content, ordered by path.
+// Objects are compared key by key and arrays element by element; the item id is ignored.
+func Diff(from, to map[string]interface{}) []Change {
+	a := DeepCopy(from)
+	b := DeepCopy(to)
+	delete(a, "id")
+	delete(b, "id")
+
+	var changes []Change
+	diffValue("", a, b, &changes)
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// diffValue appends the differences between a and b, located at path, to out.
+func diffValue(path string, a, b interface{}, out *[]Change) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for k, v := range av {
+			child := path + "/" + EscapeToken(k)
+			if nv, exists := bv[k]; exists {
+				diffValue(child, v, nv, out)
+			} else {
+				*out = append(*out, Change{Path: child, Op: ChangeRemoved, Old: v})
+			}
+		}
+		for k, v := range bv {
+			if _, exists := av[k]; !exists {
+				*out = append(*out, Change{Path: path + "/" + EscapeToken(k), Op: ChangeAdded, New: v})
+			}
+		}
+		return
+
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			child := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(bv):
+				*out = append(*out, Change{Path: child, Op: ChangeRemoved, Old: av[i]})
+			case i >= len(av):
+				*out = append(*out, Change{Path: child, Op: ChangeAdded, New: bv[i]})
+			default:
+				diffValue(child, av[i], bv[i], out)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*out = append(*out, Change{Path: path, Op: ChangeChanged, Old: a, New: b})
+	}
+}
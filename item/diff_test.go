@@ -0,0 +1,53 @@
+package item
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff_Changes(t *testing.T) {
+	from := map[string]interface{}{
+		"id":   "secret1",
+		"user": "admin",
+		"pass": "old",
+		"db": map[string]interface{}{
+			"hosts": []interface{}{"a", "b"},
+			"port":  float64(5432),
+		},
+		"a/b": "x",
+	}
+	to := map[string]interface{}{
+		"id":   "other",
+		"user": "admin",
+		"pass": "new",
+		"db": map[string]interface{}{
+			"hosts": []interface{}{"a", "c", "d"},
+			"port":  float64(5432),
+		},
+		"token": "t",
+	}
+
+	require.Equal(t, []Change{
+		{Path: "/a~1b", Op: ChangeRemoved, Old: "x"},
+		{Path: "/db/hosts/1", Op: ChangeChanged, Old: "b", New: "c"},
+		{Path: "/db/hosts/2", Op: ChangeAdded, New: "d"},
+		{Path: "/pass", Op: ChangeChanged, Old: "old", New: "new"},
+		{Path: "/token", Op: ChangeAdded, New: "t"},
+	}, Diff(from, to))
+}
+
+func TestDiff_TypeChangeReportedAtParent(t *testing.T) {
+	from := map[string]interface{}{"db": map[string]interface{}{"port": float64(1)}}
+	to := map[string]interface{}{"db": "disabled"}
+
+	changes := Diff(from, to)
+	require.Len(t, changes, 1)
+	require.Equal(t, "/db", changes[0].Path)
+	require.Equal(t, ChangeChanged, changes[0].Op)
+}
+
+func TestDiff_Equal(t *testing.T) {
+	content := map[string]interface{}{"foo": []interface{}{"bar"}}
+	require.Empty(t, Diff(content, DeepCopy(content)))
+}
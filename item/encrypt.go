@@ -17,12 +17,7 @@ func Encrypt(vaultItemName string, content map[string]interface{}, secret []byte
 			continue
 		}
 
-		plaintext, err := json.Marshal(v)
-		if err != nil {
-			return nil, err
-		}
-
-		encrypted, err := chefcrypto.Encrypt(secret, plaintext, 3)
+		encrypted, err := EncryptValue(v, secret)
 		if err != nil {
 			return nil, err
 		}
@@ -31,3 +26,13 @@ func Encrypt(vaultItemName string, content map[string]interface{}, secret []byte
 
 	return item, nil
 }
+
+// EncryptValue encrypts a single value in the version 3 encrypted data bag format.
+func EncryptValue(v interface{}, secret []byte) (chefcrypto.EncryptedDataBagItem, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return chefcrypto.Encrypt(secret, plaintext, 3)
+}
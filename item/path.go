@@ -0,0 +1,337 @@
+package item
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPath is returned when a content path cannot be parsed.
+var ErrInvalidPath = errors.New("item: invalid path")
+
+// SegmentKind describes how a path segment selects a value from its parent.
+type SegmentKind int
+
+const (
+	// SegmentKey selects a map key, or an array index when the parent is an array and the key is numeric.
+	SegmentKey SegmentKind = iota
+
+	// SegmentIndex selects an array element by position.
+	SegmentIndex
+
+	// SegmentMatch selects the array elements whose value (or MatchField value) equals MatchValue.
+	SegmentMatch
+)
+
+// Segment represents a single step in a content Path.
+type Segment struct {
+	Kind       SegmentKind
+	Key        string
+	Index      int
+	MatchField string
+	MatchValue string
+}
+
+// Path addresses a value inside vault item content.
+//
+// Paths are written either as a JSON Pointer ("/db/hosts/0") or in dotted form
+// ("db.hosts[0]"). The dotted form also accepts value matches on arrays:
+// "db.hosts[=web1]" selects elements equal to "web1" and "db.hosts[name=web1]"
+// selects object elements whose "name" field equals "web1".
+type Path struct {
+	raw      string
+	Segments []Segment
+}
+
+// String returns the path as it was originally written.
+func (p Path) String() string {
+	return p.raw
+}
+
+// ParsePath parses a JSON Pointer or dotted content path.
+func ParsePath(raw string) (Path, error) {
+	if raw == "" {
+		return Path{}, fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+
+	var (
+		segments []Segment
+		err      error
+	)
+	if strings.HasPrefix(raw, "/") {
+		segments = parsePointer(raw)
+	} else {
+		segments, err = parseDotted(raw)
+		if err != nil {
+			return Path{}, err
+		}
+	}
+
+	if len(segments) == 0 {
+		return Path{}, fmt.Errorf("%w: %q addresses the whole item", ErrInvalidPath, raw)
+	}
+
+	return Path{raw: raw, Segments: segments}, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into key segments.
+func parsePointer(raw string) []Segment {
+	tokens := strings.Split(raw[1:], "/")
+	segments := make([]Segment, 0, len(tokens))
+	for _, tok := range tokens {
+		tok = strings.ReplaceAll(tok, "~1", "/")
+		tok = strings.ReplaceAll(tok, "~0", "~")
+		segments = append(segments, Segment{Kind: SegmentKey, Key: tok})
+	}
+	return segments
+}
+
+// EscapeToken escapes a key for use as a JSON Pointer token, the inverse of the unescaping ParsePath
+// applies to each token of a pointer.
+func EscapeToken(k string) string {
+	k = strings.ReplaceAll(k, "~", "~0")
+	return strings.ReplaceAll(k, "/", "~1")
+}
+
+// parseDotted splits a dotted path with optional bracket selectors into segments.
+func parseDotted(raw string) ([]Segment, error) {
+	var segments []Segment
+	for _, part := range strings.Split(raw, ".") {
+		key, rest, hasSelector := strings.Cut(part, "[")
+		if key == "" && !hasSelector {
+			return nil, fmt.Errorf("%w: %q has an empty segment", ErrInvalidPath, raw)
+		}
+		if key != "" {
+			segments = append(segments, Segment{Kind: SegmentKey, Key: key})
+		}
+		if !hasSelector {
+			continue
+		}
+
+		for _, sel := range strings.Split("["+rest, "[")[1:] {
+			sel, ok := strings.CutSuffix(sel, "]")
+			if !ok {
+				return nil, fmt.Errorf("%w: %q has an unterminated selector", ErrInvalidPath, raw)
+			}
+			seg, err := parseSelector(sel)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: %v", ErrInvalidPath, raw, err)
+			}
+			segments = append(segments, seg)
+		}
+	}
+	return segments, nil
+}
+
+// parseSelector parses the contents of a bracket selector.
+func parseSelector(sel string) (Segment, error) {
+	if field, value, ok := strings.Cut(sel, "="); ok {
+		return Segment{Kind: SegmentMatch, MatchField: field, MatchValue: value}, nil
+	}
+
+	idx, err := strconv.Atoi(sel)
+	if err != nil || idx < 0 {
+		return Segment{}, fmt.Errorf("selector [%s] is not an index or match", sel)
+	}
+	return Segment{Kind: SegmentIndex, Index: idx}, nil
+}
+
+// Lookup returns the value addressed by p. When a match segment selects several
+// elements, the first one is used.
+func Lookup(content map[string]interface{}, p Path) (interface{}, bool) {
+	var cur interface{} = content
+	for _, seg := range p.Segments {
+		next, ok := lookupSegment(cur, seg)
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+	return cur, true
+}
+
+// lookupSegment resolves a single segment against its parent value.
+func lookupSegment(parent interface{}, seg Segment) (interface{}, bool) {
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if seg.Kind != SegmentKey {
+			return nil, false
+		}
+		v, ok := p[seg.Key]
+		return v, ok
+	case []interface{}:
+		matches := matchIndexes(p, seg)
+		if len(matches) == 0 {
+			return nil, false
+		}
+		return p[matches[0]], true
+	default:
+		return nil, false
+	}
+}
+
+// matchIndexes returns the positions in arr selected by seg.
+func matchIndexes(arr []interface{}, seg Segment) []int {
+	switch seg.Kind {
+	case SegmentKey:
+		idx, err := strconv.Atoi(seg.Key)
+		if err != nil || idx < 0 || idx >= len(arr) {
+			return nil
+		}
+		return []int{idx}
+	case SegmentIndex:
+		if seg.Index >= len(arr) {
+			return nil
+		}
+		return []int{seg.Index}
+	case SegmentMatch:
+		var out []int
+		for i, el := range arr {
+			if seg.MatchField != "" {
+				m, ok := el.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				el = m[seg.MatchField]
+			}
+			if scalarString(el) == seg.MatchValue {
+				out = append(out, i)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// scalarString renders a decoded JSON scalar for comparison with a match value.
+func scalarString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// RemovePaths returns a copy of content with the values addressed by paths removed.
+//
+// Paths are applied in order, each against the result of the previous removal, so array
+// indexes in later paths refer to the already shortened array. Parents left empty by a
+// removal are kept. The returned lists report which paths removed a value and which
+// did not resolve.
+func RemovePaths(content map[string]interface{}, paths []Path) (out map[string]interface{}, removed, missing []string) {
+	out = DeepCopy(content)
+	if out == nil {
+		out = make(map[string]interface{})
+	}
+
+	var root interface{} = out
+	for _, p := range paths {
+		if removeFrom(&root, p.Segments) {
+			removed = append(removed, p.String())
+		} else {
+			missing = append(missing, p.String())
+		}
+	}
+	return out, removed, missing
+}
+
+// removeFrom removes the value addressed by segments from *node, replacing *node when an array shrinks.
+func removeFrom(node *interface{}, segments []Segment) bool {
+	seg := segments[0]
+	last := len(segments) == 1
+
+	switch n := (*node).(type) {
+	case map[string]interface{}:
+		if seg.Kind != SegmentKey {
+			return false
+		}
+		child, ok := n[seg.Key]
+		if !ok {
+			return false
+		}
+		if last {
+			delete(n, seg.Key)
+			return true
+		}
+		if !removeFrom(&child, segments[1:]) {
+			return false
+		}
+		n[seg.Key] = child
+		return true
+
+	case []interface{}:
+		idxs := matchIndexes(n, seg)
+		if len(idxs) == 0 {
+			return false
+		}
+		if last {
+			*node = dropIndexes(n, idxs)
+			return true
+		}
+		changed := false
+		for _, i := range idxs {
+			child := n[i]
+			if removeFrom(&child, segments[1:]) {
+				n[i] = child
+				changed = true
+			}
+		}
+		return changed
+
+	default:
+		return false
+	}
+}
+
+// dropIndexes returns arr without the elements at the given ascending positions.
+func dropIndexes(arr []interface{}, idxs []int) []interface{} {
+	drop := make(map[int]struct{}, len(idxs))
+	for _, i := range idxs {
+		drop[i] = struct{}{}
+	}
+
+	out := make([]interface{}, 0, len(arr)-len(drop))
+	for i, v := range arr {
+		if _, ok := drop[i]; !ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// DeepCopy returns a deep copy of decoded JSON content.
+func DeepCopy(content map[string]interface{}) map[string]interface{} {
+	if content == nil {
+		return nil
+	}
+	return deepCopyValue(content).(map[string]interface{})
+}
+
+// deepCopyValue copies maps and slices produced by encoding/json.
+func deepCopyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = deepCopyValue(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = deepCopyValue(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
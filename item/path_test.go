@@ -0,0 +1,112 @@
+package item
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath_Forms(t *testing.T) {
+	p, err := ParsePath("/db/hosts/0")
+	require.NoError(t, err)
+	require.Len(t, p.Segments, 3)
+	require.Equal(t, "0", p.Segments[2].Key)
+
+	p, err = ParsePath("/a~1b/c~0d")
+	require.NoError(t, err)
+	require.Equal(t, []Segment{{Kind: SegmentKey, Key: "a/b"}, {Kind: SegmentKey, Key: "c~d"}}, p.Segments)
+
+	p, err = ParsePath("db.hosts[1][name=web1]")
+	require.NoError(t, err)
+	require.Equal(t, []Segment{
+		{Kind: SegmentKey, Key: "db"},
+		{Kind: SegmentKey, Key: "hosts"},
+		{Kind: SegmentIndex, Index: 1},
+		{Kind: SegmentMatch, MatchField: "name", MatchValue: "web1"},
+	}, p.Segments)
+	require.Equal(t, "db.hosts[1][name=web1]", p.String())
+}
+
+func TestParsePath_Invalid(t *testing.T) {
+	for _, raw := range []string{"", "a..b", "a[1", "a[x]", "a[-1]"} {
+		_, err := ParsePath(raw)
+		require.ErrorIs(t, err, ErrInvalidPath, raw)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	content := pathFixture(t)
+
+	v, ok := Lookup(content, mustPath(t, "db.hosts[name=web2].port"))
+	require.True(t, ok)
+	require.Equal(t, float64(5433), v)
+
+	v, ok = Lookup(content, mustPath(t, "/tags/1"))
+	require.True(t, ok)
+	require.Equal(t, "blue", v)
+
+	_, ok = Lookup(content, mustPath(t, "db.missing"))
+	require.False(t, ok)
+}
+
+func TestRemovePaths(t *testing.T) {
+	content := pathFixture(t)
+
+	out, removed, missing := RemovePaths(content, []Path{
+		mustPath(t, "db.password"),
+		mustPath(t, "tags[=red]"),
+		mustPath(t, "/db/hosts/0"),
+		mustPath(t, "db.hosts[name=web2].port"),
+		mustPath(t, "nope.nested"),
+	})
+
+	require.Equal(t, []string{"db.password", "tags[=red]", "/db/hosts/0", "db.hosts[name=web2].port"}, removed)
+	require.Equal(t, []string{"nope.nested"}, missing)
+	require.Equal(t, map[string]interface{}{
+		"db": map[string]interface{}{
+			"hosts": []interface{}{
+				map[string]interface{}{"name": "web2"},
+			},
+		},
+		"tags":  []interface{}{"blue"},
+		"empty": map[string]interface{}{"only": "value"},
+	}, out)
+
+	// the source content is left untouched
+	_, ok := Lookup(content, mustPath(t, "db.password"))
+	require.True(t, ok)
+}
+
+func TestRemovePaths_KeepsEmptyParent(t *testing.T) {
+	out, removed, _ := RemovePaths(pathFixture(t), []Path{mustPath(t, "empty.only")})
+	require.Equal(t, []string{"empty.only"}, removed)
+	require.Equal(t, map[string]interface{}{}, out["empty"])
+}
+
+func mustPath(t *testing.T, raw string) Path {
+	t.Helper()
+
+	p, err := ParsePath(raw)
+	require.NoError(t, err)
+	return p
+}
+
+func pathFixture(t *testing.T) map[string]interface{} {
+	t.Helper()
+
+	var content map[string]interface{}
+	raw := `{
+		"db": {
+			"password": "hunter2",
+			"hosts": [
+				{"name": "web1", "port": 5432},
+				{"name": "web2", "port": 5433}
+			]
+		},
+		"tags": ["red", "blue", "red"],
+		"empty": {"only": "value"}
+	}`
+	require.NoError(t, json.Unmarshal([]byte(raw), &content))
+	return content
+}
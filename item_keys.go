@@ -1,17 +1,20 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/go-chef/chef"
 	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
 	"github.com/justintsteele/go-chef-vault/item_keys"
 )
 
 // loadKeysCurrentState retrieves the data from the default keys data bag item prior to actions being taken on the vault.
-func (s *Service) loadKeysCurrentState(payload *Payload) (*item_keys.VaultItemKeys, error) {
-	raw, err := s.Client.DataBags.GetItem(
+func (s *Service) loadKeysCurrentState(ctx context.Context, payload *Payload) (*item_keys.VaultItemKeys, error) {
+	raw, err := s.backend().GetDataBagItem(
+		ctx,
 		payload.VaultName,
 		payload.VaultItemName+"_keys",
 	)
@@ -28,34 +31,35 @@ func (s *Service) loadKeysCurrentState(payload *Payload) (*item_keys.VaultItemKe
 	if err := json.Unmarshal(b, &vik); err != nil {
 		return nil, err
 	}
+	s.logger().Debug("keys state loaded", "vault", payload.VaultName, "item", payload.VaultItemName,
+		"mode", vik.Mode, "admins", len(vik.Admins), "clients", len(vik.Clients))
 
 	return &vik, nil
 }
 
 // buildKeys collects actor public keys and builds the encrypted vault keys item
-func (s *Service) buildKeys(payload *Payload, secret []byte) (map[string]any, error) {
+func (s *Service) buildKeys(ctx context.Context, payload *Payload, secret []byte) (keys map[string]any, err error) {
 	admins := make(map[string]chef.AccessKey)
 	clients := make(map[string]chef.AccessKey)
 
 	// Admins are required
-	s.collectAdmins(payload.Admins, admins)
+	s.collectAdmins(ctx, payload.Admins, admins)
 
 	if len(admins) == 0 {
 		return nil, fmt.Errorf("none of the specified admins have public keys")
 	}
 
 	// Explicit clients
-	s.collectClients(payload.Clients, clients)
+	s.collectClients(ctx, payload.Clients, clients)
 
 	// Clients from search
 	var searchedClients []string
 	if payload.SearchQuery != nil {
-		var err error
-		searchedClients, err = s.getClientsFromSearch(payload)
+		searchedClients, err = s.getClientsFromSearch(ctx, payload)
 		if err != nil {
 			return nil, err
 		}
-		s.collectClients(searchedClients, clients)
+		s.collectClients(ctx, searchedClients, clients)
 	}
 
 	finalClients := item_keys.MapKeys(clients)
@@ -76,17 +80,36 @@ func (s *Service) buildKeys(payload *Payload, secret []byte) (map[string]any, er
 		actors[k] = v
 	}
 
-	if err := vik.Encrypt(actors, secret, vik.Keys); err != nil {
+	if err := s.encryptSecret(ctx, payload.effectiveKeysMode(), len(actors), func() error {
+		return vik.Encrypt(actors, secret, vik.Keys)
+	}); err != nil {
 		return nil, err
 	}
+	s.metrics().ObserveActorsEncrypted(len(actors))
+	s.logger().Info("shared secret encrypted", "vault", payload.VaultName, "item", payload.VaultItemName,
+		"admins", len(admins), "clients", len(clients))
 
 	return vik.BuildKeysItem(finalClients), nil
 }
 
+// encryptSecret runs encrypt, which encrypts a shared secret for n actors stored in mode, in a span.
+func (s *Service) encryptSecret(ctx context.Context, mode item_keys.KeysMode, n int, encrypt func() error) (err error) {
+	_, sp := s.startSpan(ctx, "vault.encrypt_secret", Attribute{"keys_mode", string(mode)}, Attribute{"actors", n})
+	defer sp.end(&err)
+	return encrypt()
+}
+
+// encryptItem encrypts the payload's content with secret in a span.
+func (s *Service) encryptItem(ctx context.Context, payload *Payload, secret []byte) (encrypted chef.DataBagItem, err error) {
+	_, sp := s.startSpan(ctx, "vault.encrypt_item", Attribute{"keys", len(payload.Content)})
+	defer sp.end(&err)
+	return item.Encrypt(payload.VaultItemName, payload.Content, secret)
+}
+
 // createKeysDataBag prepares the item_keys.VaultItemKeysResult to be written out as data bag items.
-func (s *Service) createKeysDataBag(payload *Payload, keysModeState *item_keys.KeysModeState, secret []byte) (*item_keys.VaultItemKeysResult, error) {
+func (s *Service) createKeysDataBag(ctx context.Context, payload *Payload, keysModeState *item_keys.KeysModeState, secret []byte) (*item_keys.VaultItemKeysResult, error) {
 	mode := payload.effectiveKeysMode()
-	keys, err := s.buildKeys(payload, secret)
+	keys, err := s.buildKeys(ctx, payload, secret)
 	result := &item_keys.VaultItemKeysResult{}
 	if err != nil {
 		return nil, err
@@ -94,12 +117,14 @@ func (s *Service) createKeysDataBag(payload *Payload, keysModeState *item_keys.K
 	keys["mode"] = &mode
 
 	if keysModeState.Current != keysModeState.Desired {
-		if err := s.cleanupCurrentKeys(payload, keysModeState, keys); err != nil {
+		s.logger().Info("migrating keys mode", "vault", payload.VaultName, "item", payload.VaultItemName,
+			"from", keysModeState.Current, "to", keysModeState.Desired)
+		if err := s.cleanupCurrentKeys(ctx, payload, keysModeState, keys); err != nil {
 			return nil, err
 		}
 	}
 
-	if err := s.writeKeys(payload, mode, keys, result); err != nil {
+	if err := s.writeKeys(ctx, payload, mode, keys, result); err != nil {
 		return nil, err
 	}
 
@@ -107,34 +132,29 @@ func (s *Service) createKeysDataBag(payload *Payload, keysModeState *item_keys.K
 }
 
 // writeKeys creates the default and sparse keys data bag items as specified in the item_keys.VaultItemKeysResult.
-func (s *Service) writeKeys(payload *Payload, mode item_keys.KeysMode, keys map[string]any, result *item_keys.VaultItemKeysResult) error {
+func (s *Service) writeKeys(ctx context.Context, payload *Payload, mode item_keys.KeysMode, keys map[string]any, result *item_keys.VaultItemKeysResult) error {
 	switch mode {
 	case item_keys.KeysModeDefault:
-		return s.writeDefaultKeys(payload, &keys, result)
+		return s.writeDefaultKeys(ctx, payload, &keys, result)
 	case item_keys.KeysModeSparse:
-		return s.writeSparseKeys(payload, keys, result)
+		return s.writeSparseKeys(ctx, payload, keys, result)
 	default:
 		return fmt.Errorf("unsupported key format: %s", mode)
 	}
 }
 
 // writeDefaultKeys constructs and writes the default keys data bag item.
-func (s *Service) writeDefaultKeys(payload *Payload, keys *map[string]any, out *item_keys.VaultItemKeysResult) error {
-	if err := s.Client.DataBags.CreateItem(payload.VaultName, &keys); err != nil {
-		if cheferr.IsConflict(err) {
-			if err := s.Client.DataBags.UpdateItem(payload.VaultName, payload.VaultItemName+"_keys", &keys); err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
+func (s *Service) writeDefaultKeys(ctx context.Context, payload *Payload, keys *map[string]any, out *item_keys.VaultItemKeysResult) error {
+	if err := putDataBagItem(ctx, s.backend(), payload.VaultName, payload.VaultItemName+"_keys", &keys); err != nil {
+		return err
 	}
+	s.logger().Debug("keys item written", "vault", payload.VaultName, "id", payload.VaultItemName+"_keys")
 	out.URIs = append(out.URIs, fmt.Sprintf("%s/%s", s.vaultURL(payload.VaultName), payload.VaultItemName+"_keys"))
 	return nil
 }
 
 // writeSparseKeys constructs and writes the sparse keys data bag items.
-func (s *Service) writeSparseKeys(payload *Payload, keys map[string]any, out *item_keys.VaultItemKeysResult) error {
+func (s *Service) writeSparseKeys(ctx context.Context, payload *Payload, keys map[string]any, out *item_keys.VaultItemKeysResult) error {
 	baseKeys := map[string]any{
 		"id":           keys["id"],
 		"admins":       keys["admins"],
@@ -143,17 +163,15 @@ func (s *Service) writeSparseKeys(payload *Payload, keys map[string]any, out *it
 		"search_query": keys["search_query"],
 	}
 
-	if err := s.Client.DataBags.CreateItem(payload.VaultName, &baseKeys); err != nil {
-		if cheferr.IsConflict(err) {
-			if err := s.Client.DataBags.UpdateItem(payload.VaultName, baseKeys["id"].(string), &baseKeys); err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
+	if err := putDataBagItem(ctx, s.backend(), payload.VaultName, baseKeys["id"].(string), &baseKeys); err != nil {
+		return err
 	}
+	s.logger().Debug("keys item written", "vault", payload.VaultName, "id", baseKeys["id"])
 	out.URIs = append(out.URIs, fmt.Sprintf("%s/%s", s.vaultURL(payload.VaultName), baseKeys["id"].(string)))
 
+	written := 0
+	defer func() { s.metrics().ObserveSparseItemsWritten(written) }()
+
 	for k, val := range keys {
 		switch k {
 		case "id", "admins", "clients", "search_query", "mode":
@@ -164,26 +182,23 @@ func (s *Service) writeSparseKeys(payload *Payload, keys map[string]any, out *it
 			"id": sparseId,
 		}
 		sparseItem[k] = val
-		if err := s.Client.DataBags.CreateItem(payload.VaultName, &sparseItem); err != nil {
-			if cheferr.IsConflict(err) {
-				if err := s.Client.DataBags.UpdateItem(payload.VaultName, sparseId, &sparseItem); err != nil {
-					return err
-				}
-			} else {
-				return err
-			}
+		if err := putDataBagItem(ctx, s.backend(), payload.VaultName, sparseId, &sparseItem); err != nil {
+			return err
 		}
+		written++
+		s.logger().Debug("sparse keys item written", "vault", payload.VaultName, "id", sparseId)
 		out.URIs = append(out.URIs, fmt.Sprintf("%s/%s", s.vaultURL(payload.VaultName), sparseId))
 	}
 	return nil
 }
 
 // collectAdmins collects the public keys for the given admins.
-func (s *Service) collectAdmins(names []string, admins map[string]chef.AccessKey) {
+func (s *Service) collectAdmins(ctx context.Context, names []string, admins map[string]chef.AccessKey) {
 	for _, name := range names {
-		key, err := s.Client.Users.GetKey(name, "default")
+		key, err := s.backend().UserKey(ctx, name)
 		if err != nil {
 			// misses here should be non-fatal so that we continue to get the keys for the actors that exist.
+			s.logger().Info("skipping admin without a public key", "admin", name, "error", err)
 			continue
 		}
 		admins[name] = key
@@ -191,11 +206,12 @@ func (s *Service) collectAdmins(names []string, admins map[string]chef.AccessKey
 }
 
 // collectClients collects the public keys for the given clients.
-func (s *Service) collectClients(names []string, clients map[string]chef.AccessKey) {
+func (s *Service) collectClients(ctx context.Context, names []string, clients map[string]chef.AccessKey) {
 	for _, name := range names {
-		key, err := s.clientPublicKey(name)
+		key, err := s.clientPublicKey(ctx, name)
 		if err != nil {
 			// misses here should be non-fatal so that we continue to get the keys for the actors that exist.
+			s.logger().Info("skipping client without a public key", "client", name, "error", err)
 			continue
 		}
 		clients[name] = key
@@ -203,12 +219,12 @@ func (s *Service) collectClients(names []string, clients map[string]chef.AccessK
 }
 
 // clientPublicKey retrieves the public key for a specified actor.
-func (s *Service) clientPublicKey(actor string) (chef.AccessKey, error) {
-	return s.Client.Clients.GetKey(actor, "default")
+func (s *Service) clientPublicKey(ctx context.Context, actor string) (chef.AccessKey, error) {
+	return s.backend().ClientKey(ctx, actor)
 }
 
 // cleanupCurrentKeys migrates keys between default and sparse keys modes.
-func (s *Service) cleanupCurrentKeys(payload *Payload, keysModeState *item_keys.KeysModeState, keys map[string]any) error {
+func (s *Service) cleanupCurrentKeys(ctx context.Context, payload *Payload, keysModeState *item_keys.KeysModeState, keys map[string]any) error {
 	switch keysModeState.Desired {
 	case item_keys.KeysModeDefault:
 		// If Desired is "default", we need to clean up the sparse keys
@@ -218,27 +234,30 @@ func (s *Service) cleanupCurrentKeys(payload *Payload, keysModeState *item_keys.
 				continue
 			}
 			sparseId := fmt.Sprintf("%s_key_%s", payload.VaultItemName, key)
-			if err := s.Client.DataBags.DeleteItem(payload.VaultName, sparseId); err != nil {
+			if err := s.backend().DeleteDataBagItem(ctx, payload.VaultName, sparseId); err != nil {
 				return err
 			}
+			s.logger().Debug("sparse keys item deleted", "vault", payload.VaultName, "id", sparseId)
 		}
 	case item_keys.KeysModeSparse:
 		// If Desired is "sparse", we need to clean up the base keys
-		if err := s.Client.DataBags.DeleteItem(payload.VaultName, payload.VaultItemName+"_keys"); err != nil {
+		if err := s.backend().DeleteDataBagItem(ctx, payload.VaultName, payload.VaultItemName+"_keys"); err != nil {
 			return err
 		}
+		s.logger().Debug("keys item deleted", "vault", payload.VaultName, "id", payload.VaultItemName+"_keys")
 	}
 	return nil
 }
 
 // cleanUnknownClients removes non-existent clients and prunes their keys from keyState.
-func (s *Service) cleanUnknownClients(payload *Payload, keyState *item_keys.VaultItemKeys, clients []string) (kept, removed []string, err error) {
-	kept, removed, err = resolveClients(clients, s.clientExists)
+func (s *Service) cleanUnknownClients(ctx context.Context, payload *Payload, keyState *item_keys.VaultItemKeys, clients []string) (kept, removed []string, err error) {
+	kept, removed, err = resolveClients(ctx, clients, s.clientExists)
 	if err != nil {
 		return nil, nil, err
 	}
 	if len(removed) != 0 {
-		if err := s.pruneKeys(removed, keyState, payload); err != nil {
+		s.logger().Info("removing unknown clients", "vault", payload.VaultName, "item", payload.VaultItemName, "clients", removed)
+		if err := s.pruneKeys(ctx, removed, keyState, payload); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -246,11 +265,11 @@ func (s *Service) cleanUnknownClients(payload *Payload, keyState *item_keys.Vaul
 }
 
 // pruneKeys removes the keys for the requested actors.
-func (s *Service) pruneKeys(actors []string, keyState *item_keys.VaultItemKeys, payload *Payload) error {
+func (s *Service) pruneKeys(ctx context.Context, actors []string, keyState *item_keys.VaultItemKeys, payload *Payload) error {
 	for _, actor := range actors {
 		keyState.PruneActor(actor)
 		if keyState.Mode == item_keys.KeysModeSparse {
-			if err := s.deleteSparseKeys(payload.VaultName, payload.VaultItemName, actor, &DeleteResponse{}); err != nil {
+			if err := s.deleteSparseKeys(ctx, payload.VaultName, payload.VaultItemName, actor, &DeleteResponse{}); err != nil {
 				return err
 			}
 		}
@@ -259,9 +278,9 @@ func (s *Service) pruneKeys(actors []string, keyState *item_keys.VaultItemKeys,
 }
 
 // deleteDefaultKeys removes the base keys and any actor keys stored in default mode.
-func (s *Service) deleteDefaultKeys(name string, item string, out *DeleteResponse) error {
+func (s *Service) deleteDefaultKeys(ctx context.Context, name string, item string, out *DeleteResponse) error {
 	itemKeysUri := fmt.Sprintf("%s/%s", s.vaultURL(name), item+"_keys")
-	if err := s.Client.DataBags.DeleteItem(name, item+"_keys"); err != nil {
+	if err := s.backend().DeleteDataBagItem(ctx, name, item+"_keys"); err != nil {
 		return err
 	}
 	out.KeysURIs = append(out.KeysURIs, itemKeysUri)
@@ -269,7 +288,7 @@ func (s *Service) deleteDefaultKeys(name string, item string, out *DeleteRespons
 }
 
 // deleteSparseKeys removes all actor keys and the base sparse keys item.
-func (s *Service) deleteSparseKeys(name string, item string, actor interface{}, out *DeleteResponse) error {
+func (s *Service) deleteSparseKeys(ctx context.Context, name string, item string, actor interface{}, out *DeleteResponse) error {
 	baseKeyId := fmt.Sprintf("%s_keys", item)
 	baseUri := fmt.Sprintf("%s/%s", s.vaultURL(name), baseKeyId)
 	out.KeysURIs = append(out.KeysURIs, baseUri)
@@ -285,10 +304,12 @@ func (s *Service) deleteSparseKeys(name string, item string, actor interface{},
 	for _, actor := range actors {
 		sparseId := fmt.Sprintf("%s_key_%s", item, actor)
 		adminKeyUri := fmt.Sprintf("%s/%s", s.vaultURL(name), sparseId)
-		if err := s.Client.DataBags.DeleteItem(name, sparseId); err != nil {
+		if err := s.backend().DeleteDataBagItem(ctx, name, sparseId); err != nil {
 			if !cheferr.IsNotFound(err) {
 				return err
 			}
+		} else {
+			s.logger().Debug("sparse keys item deleted", "vault", name, "id", sparseId)
 		}
 		out.KeysURIs = append(out.KeysURIs, adminKeyUri)
 	}
@@ -296,25 +317,16 @@ func (s *Service) deleteSparseKeys(name string, item string, actor interface{},
 }
 
 // clientExists performs a client lookup to validate the requested client still exists in the Chef Server.
-func (s *Service) clientExists(name string) (bool, error) {
-	_, err := s.Client.Clients.Get(name)
-	if err == nil {
-		return true, nil
-	}
-
-	if cheferr.IsNotFound(err) {
-		return false, nil
-	}
-
-	return false, err
+func (s *Service) clientExists(ctx context.Context, name string) (bool, error) {
+	return s.backend().ClientExists(ctx, name)
 }
 
 // resolveClients partitions clients into those that still exist on the Chef server and those that do not.
-func resolveClients(clients []string, exists func(string) (bool, error)) (kept, removed []string, err error) {
+func resolveClients(ctx context.Context, clients []string, exists func(context.Context, string) (bool, error)) (kept, removed []string, err error) {
 	kept = clients[:0]
 
 	for _, c := range clients {
-		ok, err := exists(c)
+		ok, err := exists(ctx, c)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"slices"
@@ -21,7 +22,7 @@ func TestBuildKeys_NoAdminsFails(t *testing.T) {
 		Admins:        []string{},
 		Clients:       []string{},
 	}
-	_, err := service.buildKeys(payload, secret)
+	_, err := service.buildKeys(context.Background(), payload, secret)
 	if err == nil {
 		t.Fatal("expected error when no admins resolve")
 	}
@@ -33,7 +34,7 @@ func TestResolveSearchQuery_PreservesExisting(t *testing.T) {
 	// send a payload with a nil query at a stubbed vault with a query to ensure the query is preserved
 	payload, _ := stubPayload([]string{"tester"}, []string{"testhost", "testhost2", "testhost3", "testhost4"}, nil)
 
-	keyState, err := service.loadKeysCurrentState(payload)
+	keyState, err := service.loadKeysCurrentState(context.Background(), payload)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -52,7 +53,7 @@ func TestResolveSearchQuery_OverwriteWithNew(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	keyState, err := service.loadKeysCurrentState(payload)
+	keyState, err := service.loadKeysCurrentState(context.Background(), payload)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -84,7 +85,7 @@ func TestCleanUnknownClients(t *testing.T) {
 			"fakehost":  "fakehost-private-key-b64\n",
 		},
 	}
-	kept, removed, err := service.cleanUnknownClients(payload, keyState, keyState.Clients)
+	kept, removed, err := service.cleanUnknownClients(context.Background(), payload, keyState, keyState.Clients)
 	if err != nil {
 		t.Fatal(err)
 	}
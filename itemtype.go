@@ -1,5 +1,7 @@
 package vault
 
+import "context"
+
 // DataBagItemType represents the classification of a Chef data bag item as determined by Chef-Vault semantics.
 type DataBagItemType string
 
@@ -21,7 +23,10 @@ const (
 // References:
 //   - Chef API Docs: https://docs.chef.io/api_chef_server/#get-24
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_itemtype.rb
-func (s *Service) ItemType(vaultName, vaultItem string) (DataBagItemType, error) {
+func (s *Service) ItemType(vaultName, vaultItem string) (itemType DataBagItemType, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.item_type", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
 	pl := &Payload{
 		VaultName:     vaultName,
 		VaultItemName: vaultItem,
@@ -31,7 +36,12 @@ func (s *Service) ItemType(vaultName, vaultItem string) (DataBagItemType, error)
 		return "", err
 	}
 
-	isVault, err := s.bagIsVault(pl.VaultName)
+	return s.itemType(ctx, pl)
+}
+
+// itemType classifies the item a validated payload addresses under ctx.
+func (s *Service) itemType(ctx context.Context, pl *Payload) (DataBagItemType, error) {
+	isVault, err := s.bagIsVault(ctx, pl.VaultName)
 	if err != nil {
 		return "", err
 	}
@@ -40,7 +50,7 @@ func (s *Service) ItemType(vaultName, vaultItem string) (DataBagItemType, error)
 		return DataBagItemTypeVault, nil
 	}
 
-	encrypted, err := s.bagItemIsEncrypted(pl.VaultName, pl.VaultItemName)
+	encrypted, err := s.bagItemIsEncrypted(ctx, pl.VaultName, pl.VaultItemName)
 	if err != nil {
 		return "", err
 	}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"strings"
 
 	"github.com/go-chef/chef"
@@ -12,7 +13,15 @@ import (
 //   - Chef API Docs: https://docs.chef.io/api_chef_server/#get-24
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_list.rb
 func (s *Service) List() (*chef.DataBagListResult, error) {
-	dbl, err := s.Client.DataBags.List()
+	return s.ListContext(context.Background())
+}
+
+// ListContext is List with requests made under ctx.
+func (s *Service) ListContext(ctx context.Context) (res *chef.DataBagListResult, err error) {
+	ctx, sp := s.startSpan(ctx, "vault.list")
+	defer sp.end(&err)
+
+	dbl, err := s.backend().ListDataBags(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -20,7 +29,7 @@ func (s *Service) List() (*chef.DataBagListResult, error) {
 	list := chef.DataBagListResult{}
 
 	for bag, url := range *dbl {
-		isVault, err := s.bagIsVault(bag)
+		isVault, err := s.bagIsVault(ctx, bag)
 		if err != nil {
 			return nil, err
 		}
@@ -39,11 +48,19 @@ func (s *Service) List() (*chef.DataBagListResult, error) {
 // References:
 //   - Chef API Docs: https://docs.chef.io/api_chef_server/#get-25
 func (s *Service) ListItems(vaultName string) (*chef.DataBagListResult, error) {
+	return s.ListItemsContext(context.Background(), vaultName)
+}
+
+// ListItemsContext is ListItems with requests made under ctx.
+func (s *Service) ListItemsContext(ctx context.Context, vaultName string) (res *chef.DataBagListResult, err error) {
+	ctx, sp := s.startSpan(ctx, "vault.list_items", Attribute{"vault", vaultName})
+	defer sp.end(&err)
+
 	if vaultName == "" {
 		return nil, ErrMissingVaultName
 	}
 
-	dbl, err := s.Client.DataBags.ListItems(vaultName)
+	dbl, err := s.backend().ListDataBagItems(ctx, vaultName)
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +72,8 @@ func (s *Service) ListItems(vaultName string) (*chef.DataBagListResult, error) {
 			continue
 		} else if strings.Contains(item, "_key_") {
 			continue
+		} else if isCompanionItem(item, *dbl) {
+			continue
 		} else {
 			items[item] = url
 		}
@@ -62,3 +81,21 @@ func (s *Service) ListItems(vaultName string) (*chef.DataBagListResult, error) {
 
 	return &items, nil
 }
+
+// isCompanionItem reports whether id is the schema, history or lock companion of an item in items.
+// The vault-wide schema, which has no base item, is always a companion.
+func isCompanionItem(id string, items chef.DataBagListResult) bool {
+	if id == schemaItemSuffix {
+		return true
+	}
+	for _, suffix := range []string{schemaItemSuffix, historyItemSuffix, lockItemSuffix} {
+		base, ok := strings.CutSuffix(id, suffix)
+		if !ok || base == "" {
+			continue
+		}
+		if _, exists := items[base]; exists {
+			return true
+		}
+	}
+	return false
+}
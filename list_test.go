@@ -47,14 +47,24 @@ func TestService_ListItems(t *testing.T) {
 						"secret1":"http://testhost/data/vault1/secret1", 
 						"secret1_keys":"http://testhost/data/vault1/secret1_keys",
 						"secret2":"http://testhost/data/vault2/secret2",
-						"secret2_keys":"http://testhost/data/vault2/secret2_keys"
+						"secret2_keys":"http://testhost/data/vault2/secret2_keys",
+						"secret1_schema":"http://testhost/data/vault1/secret1_schema",
+						"secret1_history":"http://testhost/data/vault1/secret1_history",
+						"secret2_lock":"http://testhost/data/vault1/secret2_lock",
+						"_schema":"http://testhost/data/vault1/_schema",
+						"build_history":"http://testhost/data/vault1/build_history",
+						"build_history_keys":"http://testhost/data/vault1/build_history_keys"
 						}`)
 	})
 	vaults, err := service.ListItems("vault1")
 	if err != nil {
 		t.Errorf("Vaults.ListItems returned error: %v", err)
 	}
-	want := &chef.DataBagListResult{"secret1": "http://testhost/data/vault1/secret1", "secret2": "http://testhost/data/vault2/secret2"}
+	want := &chef.DataBagListResult{
+		"secret1":       "http://testhost/data/vault1/secret1",
+		"secret2":       "http://testhost/data/vault2/secret2",
+		"build_history": "http://testhost/data/vault1/build_history",
+	}
 	if !reflect.DeepEqual(vaults, want) {
 		t.Errorf("Vaults.ListItems returned %+v, want %+v", vaults, want)
 	}
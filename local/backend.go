@@ -0,0 +1,417 @@
+package local
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chef/chef"
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/internal/fsutil"
+)
+
+// namePattern matches the data bag, item, client and user names the Chef server accepts.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// Backend is a vault.Backend that stores data bags in a chef-repo directory and reads actor public
+// keys and nodes from it.
+type Backend struct {
+	repo     string
+	name     string
+	key      *rsa.PrivateKey
+	endpoint *url.URL
+
+	// mu serializes access to the repository so that concurrent calls observe whole files.
+	mu sync.Mutex
+}
+
+var _ vault.Backend = (*Backend)(nil)
+
+// NewBackend returns a Backend serving the repository described by opts.
+func NewBackend(opts *Options) (*Backend, error) {
+	if opts == nil || opts.RepoPath == "" {
+		return nil, errors.New("local: RepoPath is required")
+	}
+
+	key, err := chef.PrivateKeyFromString([]byte(opts.Key))
+	if err != nil {
+		return nil, fmt.Errorf("local: parsing key of %s: %w", opts.NodeName, err)
+	}
+
+	endpoint, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{repo: opts.RepoPath, name: opts.NodeName, key: key, endpoint: endpoint}, nil
+}
+
+// ListDataBags implements vault.Backend.
+func (b *Backend) ListDataBags(ctx context.Context) (*chef.DataBagListResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bags, err := listDir(b.dataBagsDir(), true)
+	if err != nil {
+		return nil, err
+	}
+	return urlMap("data", bags), nil
+}
+
+// CreateDataBag implements vault.Backend.
+func (b *Backend) CreateDataBag(ctx context.Context, name string) error {
+	path := "data"
+	if err := checkRequest(ctx, http.MethodPost, path, name); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dir := filepath.Join(b.dataBagsDir(), name)
+	if isDir(dir) {
+		return cheferr.New(http.StatusConflict, http.MethodPost, path)
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// DeleteDataBag implements vault.Backend.
+func (b *Backend) DeleteDataBag(ctx context.Context, name string) error {
+	path := "data/" + name
+	if err := checkRequest(ctx, http.MethodDelete, path, name); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dir := filepath.Join(b.dataBagsDir(), name)
+	if !isDir(dir) {
+		return cheferr.New(http.StatusNotFound, http.MethodDelete, path)
+	}
+	return os.RemoveAll(dir)
+}
+
+// ListDataBagItems implements vault.Backend.
+func (b *Backend) ListDataBagItems(ctx context.Context, bag string) (*chef.DataBagListResult, error) {
+	path := "data/" + bag
+	if err := checkRequest(ctx, http.MethodGet, path, bag); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dir := filepath.Join(b.dataBagsDir(), bag)
+	if !isDir(dir) {
+		return nil, cheferr.New(http.StatusNotFound, http.MethodGet, path)
+	}
+	items, err := listDir(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	return urlMap(path, items), nil
+}
+
+// GetDataBagItem implements vault.Backend.
+func (b *Backend) GetDataBagItem(ctx context.Context, bag, id string) (chef.DataBagItem, error) {
+	path := "data/" + bag + "/" + id
+	if err := checkRequest(ctx, http.MethodGet, path, bag, id); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	item, err := readJSON(b.itemPath(bag, id))
+	if err != nil || item == nil {
+		return nil, notFound(err, http.MethodGet, path)
+	}
+	return item, nil
+}
+
+// CreateDataBagItem implements vault.Backend.
+func (b *Backend) CreateDataBagItem(ctx context.Context, bag string, item chef.DataBagItem) error {
+	path := "data/" + bag
+	if err := checkRequest(ctx, http.MethodPost, path, bag); err != nil {
+		return err
+	}
+
+	content, id, err := decodeItem(item, http.MethodPost, path)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !isDir(filepath.Join(b.dataBagsDir(), bag)) {
+		return cheferr.New(http.StatusNotFound, http.MethodPost, path)
+	}
+	itemPath := b.itemPath(bag, id)
+	if _, err := os.Stat(itemPath); err == nil {
+		return cheferr.New(http.StatusConflict, http.MethodPost, path)
+	}
+	return writeJSON(itemPath, content)
+}
+
+// UpdateDataBagItem implements vault.Backend.
+func (b *Backend) UpdateDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error {
+	path := "data/" + bag + "/" + id
+	if err := checkRequest(ctx, http.MethodPut, path, bag, id); err != nil {
+		return err
+	}
+
+	content, bodyID, err := decodeItem(item, http.MethodPut, path)
+	if err != nil {
+		return err
+	}
+	if bodyID != id {
+		return cheferr.New(http.StatusBadRequest, http.MethodPut, path)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	itemPath := b.itemPath(bag, id)
+	current, err := readJSON(itemPath)
+	if err != nil || current == nil {
+		return notFound(err, http.MethodPut, path)
+	}
+	return writeJSON(itemPath, content)
+}
+
+// DeleteDataBagItem implements vault.Backend.
+func (b *Backend) DeleteDataBagItem(ctx context.Context, bag, id string) error {
+	path := "data/" + bag + "/" + id
+	if err := checkRequest(ctx, http.MethodDelete, path, bag, id); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := os.Remove(b.itemPath(bag, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return cheferr.New(http.StatusNotFound, http.MethodDelete, path)
+	}
+	return err
+}
+
+// UserKey implements vault.Backend.
+func (b *Backend) UserKey(ctx context.Context, name string) (chef.AccessKey, error) {
+	return b.accessKey(ctx, "users", name)
+}
+
+// ClientKey implements vault.Backend.
+func (b *Backend) ClientKey(ctx context.Context, name string) (chef.AccessKey, error) {
+	return b.accessKey(ctx, "clients", name)
+}
+
+// ClientExists implements vault.Backend.
+func (b *Backend) ClientExists(ctx context.Context, name string) (bool, error) {
+	_, err := b.accessKey(ctx, "clients", name)
+	if cheferr.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ActorName implements vault.Backend.
+func (b *Backend) ActorName() string {
+	return b.name
+}
+
+// PrivateKey implements vault.Backend.
+func (b *Backend) PrivateKey() *rsa.PrivateKey {
+	return b.key
+}
+
+// BaseURL implements vault.Backend.
+func (b *Backend) BaseURL() *url.URL {
+	return b.endpoint
+}
+
+// accessKey returns the default key of the kind/NAME actor.
+func (b *Backend) accessKey(ctx context.Context, kind, name string) (chef.AccessKey, error) {
+	path := kind + "/" + name + "/keys/default"
+	if err := checkRequest(ctx, http.MethodGet, path, name); err != nil {
+		return chef.AccessKey{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key, err := b.publicKey(kind, name)
+	if err != nil || key == "" {
+		return chef.AccessKey{}, notFound(err, http.MethodGet, path)
+	}
+	return chef.AccessKey{Name: "default", PublicKey: key, ExpirationDate: "infinity"}, nil
+}
+
+// publicKey reads an actor's public key from kind/NAME.json or kind/NAME.pem. An actor that does not
+// exist, or has no public key, is reported with a nil error and an empty key.
+func (b *Backend) publicKey(kind, name string) (string, error) {
+	base := filepath.Join(b.repo, kind, name)
+
+	actor, err := readJSON(base + ".json")
+	if err != nil {
+		return "", err
+	}
+	if actor != nil {
+		key, _ := actor["public_key"].(string)
+		return key, nil
+	}
+
+	pem, err := os.ReadFile(base + ".pem")
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	return string(pem), err
+}
+
+// dataBagsDir is the directory holding a directory per data bag.
+func (b *Backend) dataBagsDir() string {
+	return filepath.Join(b.repo, "data_bags")
+}
+
+// itemPath is the file holding a data bag item.
+func (b *Backend) itemPath(bag, id string) string {
+	return filepath.Join(b.dataBagsDir(), bag, id+".json")
+}
+
+// checkRequest returns the context's error once it is done, and a 400 response for names the Chef
+// server would refuse, so that no name can address a file outside the repository.
+func checkRequest(ctx context.Context, method, path string, names ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !validName(name) {
+			return cheferr.New(http.StatusBadRequest, method, path)
+		}
+	}
+	return nil
+}
+
+// notFound returns err, or a 404 response for path when err is nil.
+func notFound(err error, method, path string) error {
+	if err != nil {
+		return err
+	}
+	return cheferr.New(http.StatusNotFound, method, path)
+}
+
+// decodeItem converts a data bag item to the JSON object stored for it and returns it with its id.
+func decodeItem(item chef.DataBagItem, method, path string) (map[string]interface{}, string, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal(data, &content); err != nil || content == nil {
+		return nil, "", cheferr.New(http.StatusBadRequest, method, path)
+	}
+
+	// the server accepts items wrapped in a raw_data envelope as well as bare items.
+	if raw, ok := content["raw_data"].(map[string]interface{}); ok {
+		content = raw
+	}
+
+	id, _ := content["id"].(string)
+	if !validName(id) {
+		return nil, "", cheferr.New(http.StatusBadRequest, method, path)
+	}
+	return content, id, nil
+}
+
+// readJSON reads a JSON object from path. A file that does not exist reads as nil.
+func readJSON(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("local: %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// writeJSON atomically replaces path with the indented JSON encoding of v.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(path, append(data, '\n'), 0o644, -1, -1)
+}
+
+// listDir lists the subdirectories of dir, or the .json files in it without their extension.
+// A missing directory lists as empty.
+func listDir(dir string, dirs bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case strings.HasPrefix(name, "."):
+		case dirs && e.IsDir():
+			names = append(names, name)
+		case !dirs && !e.IsDir() && strings.HasSuffix(name, ".json"):
+			names = append(names, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// urlMap returns the name to URL map the Chef server uses for listings.
+func urlMap(prefix string, names []string) *chef.DataBagListResult {
+	out := make(chef.DataBagListResult, len(names))
+	for _, n := range names {
+		out[n] = baseURL + prefix + "/" + n
+	}
+	return &out
+}
+
+// validName reports whether name is a valid Chef object name. Names made only of dots are refused so
+// that names cannot escape the repository.
+func validName(name string) bool {
+	return namePattern.MatchString(name) && strings.Trim(name, ".") != ""
+}
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,39 @@
+// Package local runs the vault API against a chef-repo on disk, as chef-vault's solo mode does for
+// chef-solo and chef-zero.
+//
+// A Backend serves the Chef server API a vault.Service uses from the repository layout:
+//
+//	data_bags/<bag>/<item>.json    vault items, <item>_keys and <item>_key_<actor> items
+//	clients/<name>.json or .pem    client public keys ("public_key" in the JSON form)
+//	users/<name>.json or .pem      user (admin) public keys
+//	nodes/<name>.json              nodes matched by vault search queries
+//
+// Because the Service itself is unchanged, Create, Update, Get, Remove, Refresh and the key rotation
+// operations behave exactly as they do against a server and write the same JSON a server would store.
+package local
+
+import vault "github.com/justintsteele/go-chef-vault"
+
+// baseURL is the organization URL vault item URIs are resolved against. It never leaves the process.
+const baseURL = "http://chef-local/"
+
+// Options describes the local repository and the actor that reads and writes it.
+type Options struct {
+	// RepoPath is the chef-repo directory holding data_bags, clients, users and nodes.
+	RepoPath string
+
+	// NodeName is the client or user name the vault items are decrypted for.
+	NodeName string
+
+	// Key is the PEM encoded private key of NodeName.
+	Key string
+}
+
+// NewService returns a vault.Service operating on the repository.
+func NewService(opts *Options) (*vault.Service, error) {
+	b, err := NewBackend(opts)
+	if err != nil {
+		return nil, err
+	}
+	return vault.NewServiceWithBackend(b), nil
+}
@@ -0,0 +1,243 @@
+package local
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRepo creates a chef-repo with the admin tester, the clients node1 and node2 and their nodes,
+// and returns its path with the PEM encoded private key of tester.
+func newTestRepo(t *testing.T) (string, string) {
+	t.Helper()
+
+	repo := t.TempDir()
+	for _, dir := range []string{"users", "clients", "nodes"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(repo, dir), 0o755))
+	}
+
+	private, public := generateKeyPair(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "users", "tester.pem"), []byte(public), 0o644))
+
+	for node, env := range map[string]string{"node1": "prod", "node2": "dev"} {
+		_, pub := generateKeyPair(t)
+		writeTestJSON(t, filepath.Join(repo, "clients", node+".json"), map[string]interface{}{
+			"name":       node,
+			"public_key": pub,
+		})
+		writeTestJSON(t, filepath.Join(repo, "nodes", node+".json"), map[string]interface{}{
+			"name":             node,
+			"chef_environment": env,
+			"run_list":         []string{"role[web]", "recipe[base::default]"},
+		})
+	}
+
+	return repo, private
+}
+
+func generateKeyPair(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	private := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	public := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	return string(private), string(public)
+}
+
+func writeTestJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+func readTestJSON(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
+
+func newTestService(t *testing.T) (*vault.Service, string) {
+	t.Helper()
+
+	repo, key := newTestRepo(t)
+	service, err := NewService(&Options{RepoPath: repo, NodeName: "tester", Key: key})
+	require.NoError(t, err)
+	return service, repo
+}
+
+func TestService_RoundTrip(t *testing.T) {
+	service, repo := newTestService(t)
+	query := "chef_environment:prod"
+
+	_, err := service.Create(&vault.Payload{
+		VaultName:     "secrets",
+		VaultItemName: "db",
+		Content:       map[string]interface{}{"user": "app", "password": "hunter2"},
+		SearchQuery:   &query,
+		Admins:        []string{"tester"},
+	})
+	require.NoError(t, err)
+
+	bag := filepath.Join(repo, "data_bags", "secrets")
+	stored := readTestJSON(t, filepath.Join(bag, "db.json"))
+	require.Equal(t, "db", stored["id"])
+	require.Contains(t, stored["password"], "encrypted_data")
+
+	keys := readTestJSON(t, filepath.Join(bag, "db_keys.json"))
+	require.Contains(t, keys, "tester")
+	require.Contains(t, keys, "node1")
+	require.NotContains(t, keys, "node2")
+	require.Equal(t, query, keys["search_query"])
+
+	got, err := service.GetItem("secrets", "db")
+	require.NoError(t, err)
+	content, err := item.DataBagItemMap(got)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", content["password"])
+
+	_, err = service.Update(&vault.Payload{
+		VaultName:     "secrets",
+		VaultItemName: "db",
+		Content:       map[string]interface{}{"password": "correct-horse"},
+	})
+	require.NoError(t, err)
+
+	_, err = service.Remove(&vault.Payload{
+		VaultName:     "secrets",
+		VaultItemName: "db",
+		RemovePaths:   []string{"user"},
+	})
+	require.NoError(t, err)
+
+	before := readTestJSON(t, filepath.Join(bag, "db_keys.json"))
+	_, err = service.RotateKeys(&vault.Payload{VaultName: "secrets", VaultItemName: "db"})
+	require.NoError(t, err)
+	after := readTestJSON(t, filepath.Join(bag, "db_keys.json"))
+	require.NotEqual(t, before["tester"], after["tester"])
+
+	got, err = service.GetItem("secrets", "db")
+	require.NoError(t, err)
+	content, err = item.DataBagItemMap(got)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"id": "db", "password": "correct-horse"}, content)
+
+	items, err := service.ListItems("secrets")
+	require.NoError(t, err)
+	require.Contains(t, *items, "db")
+}
+
+func TestService_SparseKeys(t *testing.T) {
+	service, repo := newTestService(t)
+	mode := item_keys.KeysModeSparse
+
+	_, err := service.Create(&vault.Payload{
+		VaultName:     "secrets",
+		VaultItemName: "api",
+		Content:       map[string]interface{}{"token": "abc"},
+		Admins:        []string{"tester"},
+		Clients:       []string{"node2"},
+		KeysMode:      &mode,
+	})
+	require.NoError(t, err)
+
+	bag := filepath.Join(repo, "data_bags", "secrets")
+	require.FileExists(t, filepath.Join(bag, "api_key_tester.json"))
+	require.FileExists(t, filepath.Join(bag, "api_key_node2.json"))
+
+	got, err := service.GetItem("secrets", "api")
+	require.NoError(t, err)
+	content, err := item.DataBagItemMap(got)
+	require.NoError(t, err)
+	require.Equal(t, "abc", content["token"])
+}
+
+func TestService_Delete(t *testing.T) {
+	service, repo := newTestService(t)
+
+	_, err := service.Create(&vault.Payload{
+		VaultName:     "secrets",
+		VaultItemName: "db",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{"tester"},
+	})
+	require.NoError(t, err)
+
+	_, err = service.DeleteItem("secrets", "db")
+	require.NoError(t, err)
+	require.NoFileExists(t, filepath.Join(repo, "data_bags", "secrets", "db.json"))
+	require.NoFileExists(t, filepath.Join(repo, "data_bags", "secrets", "db_keys.json"))
+
+	_, err = service.GetItem("secrets", "db")
+	require.Error(t, err)
+}
+
+func TestBackend_Errors(t *testing.T) {
+	repo, key := newTestRepo(t)
+	b, err := NewBackend(&Options{RepoPath: repo, NodeName: "tester", Key: key})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = b.ListDataBagItems(ctx, "none")
+	require.True(t, cheferr.IsNotFound(err), err)
+
+	_, err = b.ClientKey(ctx, "nobody")
+	require.True(t, cheferr.IsNotFound(err), err)
+
+	exists, err := b.ClientExists(ctx, "nobody")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	_, err = b.GetDataBagItem(ctx, "..", "passwd")
+	requireStatus(t, err, http.StatusBadRequest)
+
+	require.NoError(t, b.CreateDataBag(ctx, "bag"))
+	require.True(t, cheferr.IsConflict(b.CreateDataBag(ctx, "bag")))
+
+	requireStatus(t, b.CreateDataBagItem(ctx, "bag", map[string]interface{}{"value": 1}), http.StatusBadRequest)
+	require.NoError(t, b.CreateDataBagItem(ctx, "bag", map[string]interface{}{"id": "item"}))
+	require.True(t, cheferr.IsConflict(b.CreateDataBagItem(ctx, "bag", map[string]interface{}{"id": "item"})))
+	require.True(t, cheferr.IsNotFound(b.UpdateDataBagItem(ctx, "bag", "other", map[string]interface{}{"id": "other"})))
+
+	_, err = b.PartialSearch(ctx, "role", "*:*", 0, nil)
+	requireStatus(t, err, http.StatusBadRequest)
+	_, err = b.PartialSearch(ctx, "node", "(name:x)", 0, nil)
+	requireStatus(t, err, http.StatusBadRequest)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = b.ListDataBags(cancelled)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func requireStatus(t *testing.T, err error, status int) {
+	t.Helper()
+
+	cerr, ok := cheferr.AsChefError(err)
+	require.True(t, ok, err)
+	require.Equal(t, status, cerr.StatusCode())
+}
@@ -0,0 +1,69 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/internal/query"
+)
+
+// searchRows is the number of rows in a page of search results, as the Chef server returns by default.
+const searchRows = 1000
+
+// PartialSearch implements vault.Backend for the node index, with the query syntax the query package
+// understands. Rows hold the name of each matching node, the only field the Service selects.
+func (b *Backend) PartialSearch(ctx context.Context, index, q string, start int, _ map[string]interface{}) (*vault.SearchResult, error) {
+	path := "search/" + index
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if index != "node" {
+		return nil, fmt.Errorf("local mode only searches the node index, not %q: %w", index,
+			cheferr.New(http.StatusBadRequest, http.MethodPost, path))
+	}
+
+	parsed, err := query.Parse(q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", err, cheferr.New(http.StatusBadRequest, http.MethodPost, path))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dir := filepath.Join(b.repo, "nodes")
+	names, err := listDir(dir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, name := range names {
+		node, err := readJSON(filepath.Join(dir, name+".json"))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := node["name"]; !ok {
+			node["name"] = name
+		}
+		if parsed.Match(node) {
+			matches = append(matches, fmt.Sprint(node["name"]))
+		}
+	}
+	sort.Strings(matches)
+
+	out := &vault.SearchResult{Total: len(matches)}
+	for i := start; i < len(matches) && i < start+searchRows; i++ {
+		row, err := json.Marshal(map[string]interface{}{"name": matches[i]})
+		if err != nil {
+			return nil, err
+		}
+		out.Rows = append(out.Rows, row)
+	}
+	return out, nil
+}
@@ -0,0 +1,359 @@
+package vault
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+)
+
+// lockItemSuffix is appended to an item name to form the id of its advisory lock companion item.
+const lockItemSuffix = "_lock"
+
+// minLockRenewInterval bounds how often keepAlive renews a lock with a very short TTL.
+const minLockRenewInterval = time.Millisecond
+
+// LockInfo describes an advisory lock held on a vault item.
+type LockInfo struct {
+	VaultName     string    `json:"vault"`
+	VaultItemName string    `json:"item"`
+	Owner         string    `json:"owner"`
+	Host          string    `json:"host"`
+	AcquiredAt    time.Time `json:"acquired_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the lock has passed its expiry and may be taken over.
+func (l LockInfo) Expired() bool {
+	return time.Now().After(l.ExpiresAt)
+}
+
+// LockedError is returned when a vault item is locked by another holder.
+type LockedError struct {
+	LockInfo
+}
+
+// Error implements the error interface.
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("vault: %s/%s is locked by %s@%s until %s",
+		e.VaultName, e.VaultItemName, e.Owner, e.Host, e.ExpiresAt.Format(time.RFC3339))
+}
+
+// Is reports whether the target is ErrLocked so callers can use errors.Is.
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Lock is an advisory lock on a vault item acquired by this Service.
+//
+// Locks are stored as plaintext <item>_lock data bag items. Acquisition relies on the Chef server
+// rejecting the creation of an existing item, and an expired lock is taken over by its next caller,
+// which deletes it and creates its own.
+// Locks only exclude other callers that also take them; they do not prevent writes by other tools.
+type Lock struct {
+	LockInfo
+
+	service *Service
+	token   string
+	mu      sync.Mutex
+	done    chan struct{}
+	lost    error
+}
+
+// storedLock is the persisted shape of a lock item.
+type storedLock struct {
+	Id         string    `json:"id"`
+	Owner      string    `json:"owner"`
+	Host       string    `json:"host"`
+	Token      string    `json:"token"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Lock acquires an advisory lock on a vault item for the given duration. ErrLocked is returned
+// when another holder owns an unexpired lock.
+func (s *Service) Lock(vaultName, vaultItem string, ttl time.Duration) (lock *Lock, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.lock", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		return nil, fmt.Errorf("vault: lock ttl must be positive")
+	}
+
+	return s.acquireLock(ctx, pl.VaultName, pl.VaultItemName, ttl)
+}
+
+// Locks lists the advisory locks present in every vault, including expired ones.
+func (s *Service) Locks() (locks []LockInfo, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.locks")
+	defer sp.end(&err)
+
+	vaults, err := s.ListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	locks = make([]LockInfo, 0)
+	for vaultName := range *vaults {
+		rawItems, err := s.backend().ListDataBagItems(ctx, vaultName)
+		if err != nil {
+			return nil, err
+		}
+
+		for id := range *rawItems {
+			if !strings.HasSuffix(id, lockItemSuffix) {
+				continue
+			}
+
+			stored, err := s.loadLock(ctx, vaultName, strings.TrimSuffix(id, lockItemSuffix))
+			if err != nil {
+				return nil, err
+			}
+			if stored != nil {
+				locks = append(locks, stored.info(vaultName))
+			}
+		}
+	}
+
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].VaultName != locks[j].VaultName {
+			return locks[i].VaultName < locks[j].VaultName
+		}
+		return locks[i].VaultItemName < locks[j].VaultItemName
+	})
+
+	return locks, nil
+}
+
+// Renew extends the lock expiry by ttl. It fails with ErrLocked if the lock was taken over.
+func (l *Lock) Renew(ttl time.Duration) error {
+	ctx := context.Background()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stored, err := l.service.loadLock(ctx, l.VaultName, l.VaultItemName)
+	if err != nil {
+		return err
+	}
+	if stored == nil || stored.Token != l.token {
+		return l.lostTo(stored)
+	}
+
+	stored.ExpiresAt = time.Now().UTC().Add(ttl)
+	if err := l.service.backend().UpdateDataBagItem(ctx, l.VaultName, stored.Id, stored); err != nil {
+		return err
+	}
+
+	l.ExpiresAt = stored.ExpiresAt
+	return nil
+}
+
+// Release removes the lock if it is still held by this Lock.
+func (l *Lock) Release() error {
+	ctx := context.Background()
+	l.stopKeepAlive()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stored, err := l.service.loadLock(ctx, l.VaultName, l.VaultItemName)
+	if err != nil {
+		return err
+	}
+	if stored == nil || stored.Token != l.token {
+		return l.lostTo(stored)
+	}
+
+	if err := l.service.backend().DeleteDataBagItem(ctx, l.VaultName, stored.Id); err != nil && !cheferr.IsNotFound(err) {
+		return err
+	}
+	return l.lost
+}
+
+// keepAlive renews the lock every third of ttl, or every minLockRenewInterval, until Release is called. A failed renewal cancels the
+// operation the lock protects with the renewal error as the cause, and is reported by Release.
+func (l *Lock) keepAlive(ttl time.Duration, cancel context.CancelCauseFunc) {
+	done := make(chan struct{})
+	l.done = done
+	go func() {
+		ticker := time.NewTicker(max(ttl/3, minLockRenewInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := l.Renew(ttl); err != nil {
+					l.mu.Lock()
+					l.lost = err
+					l.mu.Unlock()
+					cancel(err)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// stopKeepAlive stops background renewal, if running.
+func (l *Lock) stopKeepAlive() {
+	if l.done != nil {
+		close(l.done)
+		l.done = nil
+	}
+}
+
+// lostTo returns the error reported when the lock is no longer held by this Lock.
+func (l *Lock) lostTo(stored *storedLock) error {
+	if stored == nil {
+		return fmt.Errorf("vault: lock on %s/%s was released by another holder", l.VaultName, l.VaultItemName)
+	}
+	return &LockedError{LockInfo: stored.info(l.VaultName)}
+}
+
+// acquireLock creates the lock item, taking over an existing lock only when it has expired.
+func (s *Service) acquireLock(ctx context.Context, vaultName, vaultItem string, ttl time.Duration) (*Lock, error) {
+	token, err := item_keys.GenSecret(16)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+	stored := &storedLock{
+		Id:         vaultItem + lockItemSuffix,
+		Owner:      s.backend().ActorName(),
+		Host:       host,
+		Token:      hex.EncodeToString(token),
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	if err := s.backend().CreateDataBagItem(ctx, vaultName, stored); err != nil {
+		if !cheferr.IsConflict(err) {
+			return nil, err
+		}
+
+		if err := s.takeOverLock(ctx, vaultName, vaultItem, stored); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Lock{
+		LockInfo: stored.info(vaultName),
+		service:  s,
+		token:    stored.Token,
+	}, nil
+}
+
+// takeOverLock deletes an expired lock and creates stored in its place. Of concurrent callers taking
+// over the same lock, the server lets only one create succeed; the others get ErrLocked.
+func (s *Service) takeOverLock(ctx context.Context, vaultName, vaultItem string, stored *storedLock) error {
+	current, err := s.loadLock(ctx, vaultName, vaultItem)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		info := current.info(vaultName)
+		if !info.Expired() {
+			return &LockedError{LockInfo: info}
+		}
+
+		if err := s.backend().DeleteDataBagItem(ctx, vaultName, stored.Id); err != nil && !cheferr.IsNotFound(err) {
+			return err
+		}
+	}
+
+	err = s.backend().CreateDataBagItem(ctx, vaultName, stored)
+	if !cheferr.IsConflict(err) {
+		return err
+	}
+
+	winner, err := s.loadLock(ctx, vaultName, vaultItem)
+	if err != nil {
+		return err
+	}
+	if winner == nil {
+		return fmt.Errorf("vault: lock on %s/%s was released during takeover", vaultName, vaultItem)
+	}
+	return &LockedError{LockInfo: winner.info(vaultName)}
+}
+
+// loadLock fetches the lock item of a vault item, returning nil when it does not exist.
+func (s *Service) loadLock(ctx context.Context, vaultName, vaultItem string) (*storedLock, error) {
+	raw, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem+lockItemSuffix)
+	if err != nil {
+		if cheferr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored storedLock
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// info returns the public description of a stored lock.
+func (l *storedLock) info(vaultName string) LockInfo {
+	return LockInfo{
+		VaultName:     vaultName,
+		VaultItemName: strings.TrimSuffix(l.Id, lockItemSuffix),
+		Owner:         l.Owner,
+		Host:          l.Host,
+		AcquiredAt:    l.AcquiredAt,
+		ExpiresAt:     l.ExpiresAt,
+	}
+}
+
+// withItemLock runs op while holding the item lock when Service.LockTTL is set, renewing the lock
+// in the background and releasing it afterward. op runs under a context derived from ctx that is
+// cancelled if the lock cannot be renewed, so that it stops writing once another caller may hold it.
+func withItemLock[T any](ctx context.Context, s *Service, payload *Payload, op func(ctx context.Context) (T, error)) (T, error) {
+	if s.LockTTL <= 0 {
+		return op(ctx)
+	}
+
+	lock, err := s.acquireLock(ctx, payload.VaultName, payload.VaultItemName, s.LockTTL)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	lock.keepAlive(s.LockTTL, cancel)
+
+	res, err := op(lockCtx)
+	if rerr := lock.Release(); rerr != nil && err == nil {
+		err = rerr
+	}
+	if cause := context.Cause(lockCtx); cause != nil && ctx.Err() == nil && err != nil {
+		err = fmt.Errorf("vault: lock on %s/%s lost during the operation: %w", payload.VaultName, payload.VaultItemName, cause)
+	}
+	return res, err
+}
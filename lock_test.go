@@ -0,0 +1,124 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock_AcquireAndRelease(t *testing.T) {
+	store := newMemoryBackend(t)
+	svc := NewServiceWithBackend(store)
+	store.seed("vault1", "secret1", map[string]interface{}{"id": "secret1"})
+
+	lock, err := svc.Lock("vault1", "secret1", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, userid, lock.Owner)
+	require.True(t, store.has("vault1", "secret1_lock"))
+
+	_, err = svc.Lock("vault1", "secret1", time.Minute)
+	require.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, lock.Renew(2*time.Minute))
+	require.NoError(t, lock.Release())
+	require.False(t, store.has("vault1", "secret1_lock"))
+}
+
+func TestLock_TakesOverExpired(t *testing.T) {
+	store := newMemoryBackend(t)
+	svc := NewServiceWithBackend(store)
+	store.seed("vault1", "secret1_lock", storedLock{
+		Id:        "secret1_lock",
+		Owner:     "someone-else",
+		Token:     "old",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+
+	lock, err := svc.Lock("vault1", "secret1", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, userid, lock.Owner)
+
+	// the previous holder has lost the lock and cannot release it
+	stale := &Lock{LockInfo: LockInfo{VaultName: "vault1", VaultItemName: "secret1"}, service: svc, token: "old"}
+	require.ErrorIs(t, stale.Release(), ErrLocked)
+
+	require.NoError(t, lock.Release())
+}
+
+func TestService_Locks(t *testing.T) {
+	store := newMemoryBackend(t)
+	svc := NewServiceWithBackend(store)
+	store.seed("vault1", "secret1", map[string]interface{}{"id": "secret1"})
+	store.seed("vault1", "secret1_keys", map[string]interface{}{"id": "secret1_keys"})
+
+	lock, err := svc.Lock("vault1", "secret1", time.Minute)
+	require.NoError(t, err)
+
+	locks, err := svc.Locks()
+	require.NoError(t, err)
+	require.Len(t, locks, 1)
+	require.Equal(t, "secret1", locks[0].VaultItemName)
+	require.False(t, locks[0].Expired())
+
+	items, err := svc.ListItems("vault1")
+	require.NoError(t, err)
+	require.NotContains(t, *items, "secret1_lock")
+
+	require.NoError(t, lock.Release())
+}
+
+func TestWithItemLock_HeldDuringOperation(t *testing.T) {
+	store := newMemoryBackend(t)
+	require.NoError(t, store.CreateDataBag(context.Background(), "vault1"))
+	svc := NewServiceWithBackend(store)
+	svc.LockTTL = time.Minute
+
+	got, err := withItemLock(context.Background(), svc, &Payload{VaultName: "vault1", VaultItemName: "secret1"}, func(context.Context) (bool, error) {
+		return store.has("vault1", "secret1_lock"), nil
+	})
+	require.NoError(t, err)
+	require.True(t, got)
+	require.False(t, store.has("vault1", "secret1_lock"))
+}
+
+func TestWithItemLock_CancelsOnLostLock(t *testing.T) {
+	store := newMemoryBackend(t)
+	require.NoError(t, store.CreateDataBag(context.Background(), "vault1"))
+	svc := NewServiceWithBackend(store)
+	svc.LockTTL = 30 * time.Millisecond
+
+	_, err := withItemLock(context.Background(), svc, &Payload{VaultName: "vault1", VaultItemName: "secret1"}, func(ctx context.Context) (bool, error) {
+		store.seed("vault1", "secret1_lock", storedLock{
+			Id:        "secret1_lock",
+			Owner:     "someone-else",
+			Token:     "other",
+			ExpiresAt: time.Now().Add(time.Minute),
+		})
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return true, nil
+		}
+	})
+	require.ErrorIs(t, err, ErrLocked)
+	require.ErrorContains(t, err, "lost during the operation")
+	require.True(t, store.has("vault1", "secret1_lock"))
+}
+
+func TestWithItemLock_TinyTTL(t *testing.T) {
+	store := newMemoryBackend(t)
+	require.NoError(t, store.CreateDataBag(context.Background(), "vault1"))
+	svc := NewServiceWithBackend(store)
+	svc.LockTTL = 2
+
+	got, err := withItemLock(context.Background(), svc, &Payload{VaultName: "vault1", VaultItemName: "secret1"}, func(context.Context) (bool, error) {
+		time.Sleep(5 * time.Millisecond)
+		return true, nil
+	})
+	require.NoError(t, err)
+	require.True(t, got)
+	require.False(t, store.has("vault1", "secret1_lock"))
+}
@@ -0,0 +1,62 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Logger(t *testing.T) {
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+	var buf bytes.Buffer
+	svc.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	query := "name:*"
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid, "nobody"},
+		SearchQuery:   &query,
+	})
+	require.NoError(t, err)
+
+	sparse := item_keys.KeysModeSparse
+	_, err = svc.Update(&Payload{VaultName: "vault1", VaultItemName: "secret1", KeysMode: &sparse})
+	require.NoError(t, err)
+
+	_, err = svc.RotateKeys(&Payload{VaultName: "vault1", VaultItemName: "secret1"})
+	require.NoError(t, err)
+
+	var messages []string
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var rec map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &rec))
+		messages = append(messages, rec["msg"].(string))
+	}
+	for _, msg := range []string{
+		"keys state loaded",
+		"search page fetched",
+		"skipping admin without a public key",
+		"migrating keys mode",
+		"keys item deleted",
+		"sparse keys item written",
+		"shared secret encrypted",
+		"item re-encrypted",
+		"operation completed",
+	} {
+		require.Contains(t, messages, msg)
+	}
+
+	// neither content nor the encrypted shared secrets are logged.
+	keys, err := backend.GetDataBagItem(context.Background(), "vault1", "secret1_key_"+userid)
+	require.NoError(t, err)
+	require.NotContains(t, buf.String(), "hunter2")
+	require.NotContains(t, buf.String(), keys.(map[string]interface{})[userid].(string))
+}
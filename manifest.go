@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes the vaults and items to be created or updated by Import.
+type Manifest struct {
+	Vaults []ManifestVault `json:"vaults" yaml:"vaults"`
+}
+
+// ManifestVault describes a vault and its items. Admins and clients apply to every item in the vault
+// in addition to those listed on the item; the search query and keys mode are item defaults.
+type ManifestVault struct {
+	Name        string             `json:"name" yaml:"name"`
+	Admins      []string           `json:"admins,omitempty" yaml:"admins,omitempty"`
+	Clients     []string           `json:"clients,omitempty" yaml:"clients,omitempty"`
+	SearchQuery *string            `json:"search_query,omitempty" yaml:"search_query,omitempty"`
+	KeysMode    item_keys.KeysMode `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Items       []ManifestItem     `json:"items" yaml:"items"`
+}
+
+// ManifestItem describes a single vault item.
+//
+// Content is assembled from ContentFile, a JSON or YAML document, then the inline Content, then
+// ContentFiles, which maps top-level keys to files whose raw contents become string values.
+// Relative file paths are resolved against the directory of the manifest they were loaded from.
+type ManifestItem struct {
+	Name         string                 `json:"name" yaml:"name"`
+	Content      map[string]interface{} `json:"content,omitempty" yaml:"content,omitempty"`
+	ContentFile  string                 `json:"content_file,omitempty" yaml:"content_file,omitempty"`
+	ContentFiles map[string]string      `json:"content_files,omitempty" yaml:"content_files,omitempty"`
+	Admins       []string               `json:"admins,omitempty" yaml:"admins,omitempty"`
+	Clients      []string               `json:"clients,omitempty" yaml:"clients,omitempty"`
+	SearchQuery  *string                `json:"search_query,omitempty" yaml:"search_query,omitempty"`
+	KeysMode     item_keys.KeysMode     `json:"mode,omitempty" yaml:"mode,omitempty"`
+
+	baseDir string
+}
+
+// LoadManifest reads a JSON or YAML manifest, or every .json, .yaml, and .yml manifest in a
+// directory, in name order. Vaults named in more than one file have their items combined.
+func LoadManifest(path string) (*Manifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return loadManifestFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && isManifestFile(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	out := &Manifest{}
+	for _, name := range names {
+		m, err := loadManifestFile(filepath.Join(path, name))
+		if err != nil {
+			return nil, err
+		}
+		out.merge(m)
+	}
+	return out, nil
+}
+
+// merge appends the vaults of other, combining the items of vaults that are already present.
+func (m *Manifest) merge(other *Manifest) {
+	for _, v := range other.Vaults {
+		merged := false
+		for i := range m.Vaults {
+			if m.Vaults[i].Name == v.Name {
+				m.Vaults[i].Items = append(m.Vaults[i].Items, v.Items...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			m.Vaults = append(m.Vaults, v)
+		}
+	}
+}
+
+// loadManifestFile decodes a single manifest and records its directory on each item.
+func loadManifestFile(path string) (*Manifest, error) {
+	var m Manifest
+	if err := decodeDocument(path, &m); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	for i := range m.Vaults {
+		for j := range m.Vaults[i].Items {
+			m.Vaults[i].Items[j].baseDir = dir
+		}
+	}
+	return &m, nil
+}
+
+// content assembles the item content from its files and inline content.
+func (mi *ManifestItem) content() (map[string]interface{}, error) {
+	content := make(map[string]interface{})
+
+	if mi.ContentFile != "" {
+		if err := decodeDocument(mi.resolve(mi.ContentFile), &content); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, v := range mi.Content {
+		content[k] = v
+	}
+
+	for k, file := range mi.ContentFiles {
+		b, err := os.ReadFile(mi.resolve(file))
+		if err != nil {
+			return nil, err
+		}
+		content[k] = string(b)
+	}
+
+	content["id"] = mi.Name
+	return normalizeContent(content)
+}
+
+// resolve returns path relative to the manifest the item was loaded from.
+func (mi *ManifestItem) resolve(path string) string {
+	if filepath.IsAbs(path) || mi.baseDir == "" {
+		return path
+	}
+	return filepath.Join(mi.baseDir, path)
+}
+
+// decodeDocument decodes a JSON file, or a YAML file for any other extension, into v.
+func decodeDocument(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(v)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err = dec.Decode(v); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("vault: decoding %s: %w", path, err)
+	}
+	return nil
+}
+
+// isManifestFile reports whether a file name has a manifest extension.
+func isManifestFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
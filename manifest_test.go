@@ -0,0 +1,73 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, body string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+}
+
+func TestLoadManifest_YAMLWithContentFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "files", "db.json"), `{"user": "admin", "port": 5432}`)
+	writeFile(t, filepath.Join(dir, "files", "tls.pem"), "-----BEGIN CERTIFICATE-----\n")
+	writeFile(t, filepath.Join(dir, "manifest.yaml"), `
+vaults:
+  - name: vault1
+    admins: [pivotal]
+    mode: sparse
+    items:
+      - name: db
+        content_file: files/db.json
+        content:
+          user: root
+      - name: tls
+        content_files:
+          cert: files/tls.pem
+        clients: [node1]
+`)
+
+	m, err := LoadManifest(filepath.Join(dir, "manifest.yaml"))
+	require.NoError(t, err)
+	require.Len(t, m.Vaults, 1)
+	require.Equal(t, "sparse", string(m.Vaults[0].KeysMode))
+
+	db, err := m.Vaults[0].Items[0].content()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"id": "db", "user": "root", "port": float64(5432)}, db)
+
+	tls, err := m.Vaults[0].Items[1].content()
+	require.NoError(t, err)
+	require.Equal(t, "-----BEGIN CERTIFICATE-----\n", tls["cert"])
+}
+
+func TestLoadManifest_Directory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{"vaults": [{"name": "vault1", "items": [{"name": "one", "content": {"k": "v"}}]}]}`)
+	writeFile(t, filepath.Join(dir, "b.yml"), "vaults:\n  - name: vault1\n    items:\n      - name: two\n  - name: vault2\n    items:\n      - name: three\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "ignored")
+	writeFile(t, filepath.Join(dir, "empty.yaml"), "")
+
+	m, err := LoadManifest(dir)
+	require.NoError(t, err)
+	require.Len(t, m.Vaults, 2)
+	require.Equal(t, "vault1", m.Vaults[0].Name)
+	require.Len(t, m.Vaults[0].Items, 2)
+	require.Equal(t, "two", m.Vaults[0].Items[1].Name)
+	require.Equal(t, "vault2", m.Vaults[1].Name)
+}
+
+func TestLoadManifest_UnknownField(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "m.yaml"), "vaults:\n  - name: vault1\n    itmes: []\n")
+
+	_, err := LoadManifest(filepath.Join(dir, "m.yaml"))
+	require.ErrorContains(t, err, "itmes")
+}
@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+)
+
+// Operation names reported to Metrics.
+const (
+	OpCreate     = "create"
+	OpUpdate     = "update"
+	OpGet        = "get"
+	OpRotate     = "rotate"
+	OpRefresh    = "refresh"
+	OpRemove     = "remove"
+	OpDelete     = "delete"
+	OpDeleteItem = "delete_item"
+	OpReplace    = "replace"
+	OpRollback   = "rollback"
+)
+
+// Metrics receives measurements from a Service whose Metrics is set. Error classes are those returned
+// by cheferr.Classify, and are empty for calls that succeeded. Implementations must be safe for
+// concurrent use; the prom package provides one exporting Prometheus metrics.
+type Metrics interface {
+	// ObserveOperation records a completed Create, Update, GetItem, RotateKeys, Refresh, Remove, Delete,
+	// DeleteItem or Rollback call, or an item replaced by Edit, Import or Restore, named by one of the
+	// Op constants.
+	ObserveOperation(op string, d time.Duration, errClass string)
+
+	// ObserveChefRequest records a Chef API request, named after the Backend method that made it.
+	ObserveChefRequest(call string, d time.Duration, errClass string)
+
+	// ObserveRetry records a Chef API request about to be repeated under Service.Retry after failing
+	// with errClass.
+	ObserveRetry(call string, errClass string)
+
+	// ObserveSearchPage records a page of client search results fetched from index.
+	ObserveSearchPage(index string, rows int)
+
+	// ObserveActorsEncrypted records the number of actors a shared secret was encrypted for.
+	ObserveActorsEncrypted(n int)
+
+	// ObserveSparseItemsWritten records the number of per-actor sparse keys items written.
+	ObserveSparseItemsWritten(n int)
+}
+
+// noopMetrics is the Metrics of a Service whose Metrics is nil.
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveOperation(string, time.Duration, string)   {}
+func (noopMetrics) ObserveChefRequest(string, time.Duration, string) {}
+func (noopMetrics) ObserveRetry(string, string)                      {}
+func (noopMetrics) ObserveSearchPage(string, int)                    {}
+func (noopMetrics) ObserveActorsEncrypted(int)                       {}
+func (noopMetrics) ObserveSparseItemsWritten(int)                    {}
+
+// metrics returns the Metrics the Service reports to.
+func (s *Service) metrics() Metrics {
+	if s.Metrics == nil {
+		return noopMetrics{}
+	}
+	return s.Metrics
+}
+
+// observe records and logs an operation started at start that failed with *err. It is deferred by the public
+// operations, so that it sees the error they return.
+func (s *Service) observe(op string, start time.Time, err *error) {
+	d := time.Since(start)
+	s.metrics().ObserveOperation(op, d, cheferr.Classify(*err))
+	if *err != nil {
+		s.logger().Info("operation failed", "operation", op, "duration", d, "error", *err)
+		return
+	}
+	s.logger().Debug("operation completed", "operation", op, "duration", d)
+}
+
+// metricsBackend is the Backend of a Service with Metrics. It records each request it makes.
+type metricsBackend struct {
+	Backend
+	metrics Metrics
+}
+
+// observe records a request started at start.
+func (b *metricsBackend) observe(call string, start time.Time, err error) {
+	b.metrics.ObserveChefRequest(call, time.Since(start), cheferr.Classify(err))
+}
+
+// ListDataBags implements Backend.
+func (b *metricsBackend) ListDataBags(ctx context.Context) (*chef.DataBagListResult, error) {
+	start := time.Now()
+	res, err := b.Backend.ListDataBags(ctx)
+	b.observe("ListDataBags", start, err)
+	return res, err
+}
+
+// CreateDataBag implements Backend.
+func (b *metricsBackend) CreateDataBag(ctx context.Context, name string) error {
+	start := time.Now()
+	err := b.Backend.CreateDataBag(ctx, name)
+	b.observe("CreateDataBag", start, err)
+	return err
+}
+
+// DeleteDataBag implements Backend.
+func (b *metricsBackend) DeleteDataBag(ctx context.Context, name string) error {
+	start := time.Now()
+	err := b.Backend.DeleteDataBag(ctx, name)
+	b.observe("DeleteDataBag", start, err)
+	return err
+}
+
+// ListDataBagItems implements Backend.
+func (b *metricsBackend) ListDataBagItems(ctx context.Context, bag string) (*chef.DataBagListResult, error) {
+	start := time.Now()
+	res, err := b.Backend.ListDataBagItems(ctx, bag)
+	b.observe("ListDataBagItems", start, err)
+	return res, err
+}
+
+// GetDataBagItem implements Backend.
+func (b *metricsBackend) GetDataBagItem(ctx context.Context, bag, id string) (chef.DataBagItem, error) {
+	start := time.Now()
+	res, err := b.Backend.GetDataBagItem(ctx, bag, id)
+	b.observe("GetDataBagItem", start, err)
+	return res, err
+}
+
+// CreateDataBagItem implements Backend.
+func (b *metricsBackend) CreateDataBagItem(ctx context.Context, bag string, item chef.DataBagItem) error {
+	start := time.Now()
+	err := b.Backend.CreateDataBagItem(ctx, bag, item)
+	b.observe("CreateDataBagItem", start, err)
+	return err
+}
+
+// UpdateDataBagItem implements Backend.
+func (b *metricsBackend) UpdateDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error {
+	start := time.Now()
+	err := b.Backend.UpdateDataBagItem(ctx, bag, id, item)
+	b.observe("UpdateDataBagItem", start, err)
+	return err
+}
+
+// DeleteDataBagItem implements Backend.
+func (b *metricsBackend) DeleteDataBagItem(ctx context.Context, bag, id string) error {
+	start := time.Now()
+	err := b.Backend.DeleteDataBagItem(ctx, bag, id)
+	b.observe("DeleteDataBagItem", start, err)
+	return err
+}
+
+// UserKey implements Backend.
+func (b *metricsBackend) UserKey(ctx context.Context, name string) (chef.AccessKey, error) {
+	start := time.Now()
+	res, err := b.Backend.UserKey(ctx, name)
+	b.observe("UserKey", start, err)
+	return res, err
+}
+
+// ClientKey implements Backend.
+func (b *metricsBackend) ClientKey(ctx context.Context, name string) (chef.AccessKey, error) {
+	start := time.Now()
+	res, err := b.Backend.ClientKey(ctx, name)
+	b.observe("ClientKey", start, err)
+	return res, err
+}
+
+// ClientExists implements Backend.
+func (b *metricsBackend) ClientExists(ctx context.Context, name string) (bool, error) {
+	start := time.Now()
+	res, err := b.Backend.ClientExists(ctx, name)
+	b.observe("ClientExists", start, err)
+	return res, err
+}
+
+// PartialSearch implements Backend.
+func (b *metricsBackend) PartialSearch(ctx context.Context, index, query string, start int, fields map[string]interface{}) (*SearchResult, error) {
+	began := time.Now()
+	res, err := b.Backend.PartialSearch(ctx, index, query, start, fields)
+	b.observe("PartialSearch", began, err)
+	return res, err
+}
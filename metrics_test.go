@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMetrics records the measurements it receives.
+type recordingMetrics struct {
+	mu          sync.Mutex
+	operations  []string
+	requests    map[string]int
+	retries     []string
+	pages       int
+	actors      []int
+	sparseItems []int
+}
+
+func (m *recordingMetrics) ObserveOperation(op string, _ time.Duration, errClass string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.operations = append(m.operations, op+":"+errClass)
+}
+
+func (m *recordingMetrics) ObserveChefRequest(call string, _ time.Duration, errClass string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[string]int)
+	}
+	m.requests[call+":"+errClass]++
+}
+
+func (m *recordingMetrics) ObserveRetry(call string, errClass string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = append(m.retries, call+":"+errClass)
+}
+
+func (m *recordingMetrics) ObserveSearchPage(string, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pages++
+}
+
+func (m *recordingMetrics) ObserveActorsEncrypted(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actors = append(m.actors, n)
+}
+
+func (m *recordingMetrics) ObserveSparseItemsWritten(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sparseItems = append(m.sparseItems, n)
+}
+
+func TestService_Metrics(t *testing.T) {
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+	rec := &recordingMetrics{}
+	svc.Metrics = rec
+
+	query := "name:*"
+	mode := item_keys.KeysModeSparse
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+		SearchQuery:   &query,
+		KeysMode:      &mode,
+	})
+	require.NoError(t, err)
+
+	_, err = svc.GetItem("vault1", "secret1")
+	require.NoError(t, err)
+
+	notFound := rec.requests["GetDataBagItem:"+cheferr.ClassNotFound]
+	_, err = svc.GetItem("vault1", "missing")
+	require.True(t, cheferr.IsNotFound(err))
+
+	require.Equal(t, []string{
+		OpCreate + ":",
+		OpGet + ":",
+		OpGet + ":" + cheferr.ClassNotFound,
+	}, rec.operations)
+	require.Equal(t, 1, rec.pages)
+	require.Equal(t, []int{2}, rec.actors)
+	require.Equal(t, []int{2}, rec.sparseItems)
+	require.Equal(t, 1, rec.requests["PartialSearch:"])
+	require.Equal(t, notFound+1, rec.requests["GetDataBagItem:"+cheferr.ClassNotFound])
+}
+
+func TestService_MetricsUnset(t *testing.T) {
+	svc := NewServiceWithBackend(newMemoryBackend(t))
+
+	require.IsType(t, noopMetrics{}, svc.metrics())
+	require.IsType(t, &memoryBackend{}, svc.backend())
+}
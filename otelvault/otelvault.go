@@ -0,0 +1,75 @@
+// Package otelvault records the spans of a vault.Service with OpenTelemetry.
+//
+//	svc.Tracer = otelvault.New(otel.GetTracerProvider())
+//	res, err := svc.RotateKeysContext(ctx, payload)
+//
+// Attributes are prefixed with "vault.", so that the vault attribute becomes "vault.vault".
+package otelvault
+
+import (
+	"context"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans.
+const ScopeName = "github.com/justintsteele/go-chef-vault"
+
+// Tracer is a vault.Tracer that records spans with an OpenTelemetry tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ vault.Tracer = (*Tracer)(nil)
+
+// New returns a Tracer recording spans with provider.
+func New(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(ScopeName)}
+}
+
+// Start implements vault.Tracer.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...vault.Attribute) (context.Context, vault.Span) {
+	ctx, sp := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attrs)...))
+	return ctx, &span{span: sp}
+}
+
+// span is a vault.Span wrapping an OpenTelemetry span.
+type span struct {
+	span trace.Span
+}
+
+// SetAttributes implements vault.Span.
+func (s *span) SetAttributes(attrs ...vault.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+// End implements vault.Span.
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+// convert returns attrs as OpenTelemetry attributes. Values of unsupported types are dropped.
+func convert(attrs []vault.Attribute) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		key := "vault." + a.Key
+		switch v := a.Value.(type) {
+		case string:
+			out = append(out, attribute.String(key, v))
+		case int:
+			out = append(out, attribute.Int(key, v))
+		case bool:
+			out = append(out, attribute.Bool(key, v))
+		case []string:
+			out = append(out, attribute.StringSlice(key, v))
+		}
+	}
+	return out
+}
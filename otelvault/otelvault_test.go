@@ -0,0 +1,42 @@
+package otelvault
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := tracer.Start(context.Background(), "vault.rotate",
+		vault.Attribute{Key: "vault", Value: "passwords"}, vault.Attribute{Key: "ignored", Value: 1.5})
+	_, child := tracer.Start(ctx, "chef.GetDataBagItem")
+	child.SetAttributes(vault.Attribute{Key: "result", Value: "not_found"})
+	child.End(errors.New("GET data/passwords/root: 404"))
+	parent.SetAttributes(vault.Attribute{Key: "actors", Value: 3})
+	parent.End(nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "chef.GetDataBagItem", spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Contains(t, spans[0].Attributes(), attribute.String("vault.result", "not_found"))
+
+	require.Equal(t, "vault.rotate", spans[1].Name())
+	require.Equal(t, codes.Unset, spans[1].Status().Code)
+	require.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("vault.vault", "passwords"),
+		attribute.Int("vault.actors", 3),
+	}, spans[1].Attributes())
+}
@@ -0,0 +1,11 @@
+// Package profile resolves Chef connection settings the way knife does and builds a vault.Service from them.
+//
+// Settings come from a profile in ~/.chef/credentials (selected by CHEF_PROFILE, ~/.chef/context, or
+// "default"), or from ~/.chef/config.rb or ~/.chef/knife.rb. Besides node_name, client_key,
+// chef_server_url and ssl_verify_mode, the chef-vault settings knife[:vault_admins] and
+// knife[:vault_mode] are read; in a credentials profile they live in the profile's knife table.
+// CHEF_SERVER_URL overrides the server URL of whichever source is used.
+//
+// When vault_mode is solo, Profile.NewService reads and writes the data_bags of chef_repo_path with
+// the local package instead of connecting to chef_server_url.
+package profile
@@ -0,0 +1,476 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// rbFile holds the settings read from a knife.rb or config.rb file.
+type rbFile struct {
+	settings map[string]interface{}
+	knife    map[string]interface{}
+}
+
+// parseRb evaluates the subset of Ruby used by knife.rb files: setting calls such as
+// node_name "name", local variable assignments, knife[:key] = value, string interpolation,
+// symbols, arrays and %w() lists, File.dirname(__FILE__), File.join, File.expand_path and ENV[...].
+// Statements outside this subset are ignored, and so are malformed statements unless they set one of
+// the settings this package reads.
+func parseRb(path string, data []byte, getenv func(string) string) (*rbFile, error) {
+	out := &rbFile{
+		settings: make(map[string]interface{}),
+		knife:    make(map[string]interface{}),
+	}
+	ev := &rbEvaluator{
+		file:   path,
+		vars:   make(map[string]interface{}),
+		getenv: getenv,
+	}
+
+	for n, line := range strings.Split(string(data), "\n") {
+		p := &rbParser{src: stripRbComment(line)}
+		p.skipSpace()
+		if p.done() {
+			continue
+		}
+
+		if err := ev.statement(p, out); err != nil && rbSettings[ev.target] {
+			return nil, fmt.Errorf("%s:%d: %w", path, n+1, err)
+		}
+	}
+	return out, nil
+}
+
+// rbSettings are the settings, and knife keys, whose malformed values are reported as errors.
+var rbSettings = map[string]bool{
+	"node_name":       true,
+	"client_key":      true,
+	"chef_server_url": true,
+	"chef_repo_path":  true,
+	"ssl_verify_mode": true,
+	"vault_admins":    true,
+	"vault_mode":      true,
+}
+
+// rbEvaluator evaluates knife.rb statements in the context of a single file.
+type rbEvaluator struct {
+	file   string
+	vars   map[string]interface{}
+	getenv func(string) string
+
+	// target is the setting or knife key assigned by the statement being evaluated.
+	target string
+}
+
+// errRbSkip marks a statement outside the supported subset, which is ignored.
+var errRbSkip = fmt.Errorf("unsupported statement")
+
+// statement evaluates one line into out.
+func (ev *rbEvaluator) statement(p *rbParser, out *rbFile) error {
+	name := p.ident()
+	ev.target = name
+	if name == "" {
+		return nil
+	}
+
+	// knife[:key] = value
+	if name == "knife" && p.consume("[") {
+		key, err := ev.expr(p)
+		if err != nil {
+			return ev.skip(err)
+		}
+		if !p.consume("]") || !p.consume("=") {
+			return nil
+		}
+		ev.target, _ = key.(string)
+		v, err := ev.expr(p)
+		if err != nil {
+			return ev.skip(err)
+		}
+		if k, ok := key.(string); ok {
+			out.knife[k] = v
+		}
+		return nil
+	}
+
+	// local = value
+	p.skipSpace()
+	if p.peek() == '=' && !strings.HasPrefix(p.rest(), "==") {
+		ev.target = ""
+		p.consume("=")
+		v, err := ev.expr(p)
+		if err != nil {
+			return ev.skip(err)
+		}
+		ev.vars[name] = v
+		return nil
+	}
+
+	// setting value, or setting(value)
+	paren := p.consume("(")
+	v, err := ev.expr(p)
+	if err != nil {
+		return ev.skip(err)
+	}
+	if paren && !p.consume(")") {
+		return nil
+	}
+	out.settings[name] = v
+	return nil
+}
+
+// skip turns unsupported expressions into ignored statements while keeping real errors.
+func (ev *rbEvaluator) skip(err error) error {
+	if err == errRbSkip {
+		return nil
+	}
+	return err
+}
+
+// expr evaluates a single value expression.
+func (ev *rbEvaluator) expr(p *rbParser) (interface{}, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '"':
+		return ev.doubleQuoted(p)
+	case c == '\'':
+		return p.singleQuoted()
+	case c == ':':
+		p.pos++
+		sym := p.ident()
+		if sym == "" {
+			return nil, errRbSkip
+		}
+		return sym, nil
+	case c == '[':
+		return ev.array(p)
+	case strings.HasPrefix(p.rest(), "%w"):
+		return p.wordList()
+	case c == '-' || unicode.IsDigit(rune(c)):
+		return p.number(), nil
+	}
+
+	name := p.ident()
+	switch name {
+	case "":
+		return nil, errRbSkip
+	case "true", "false", "nil":
+		return name, nil
+	case "__FILE__":
+		return ev.file, nil
+	case "ENV":
+		return ev.env(p)
+	case "File":
+		return ev.fileCall(p)
+	}
+
+	if v, ok := ev.vars[name]; ok {
+		return v, nil
+	}
+	return nil, errRbSkip
+}
+
+// doubleQuoted evaluates a double-quoted string with #{} interpolation.
+func (ev *rbEvaluator) doubleQuoted(p *rbParser) (interface{}, error) {
+	p.pos++
+	var b strings.Builder
+	for !p.done() {
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.src):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == '#' && strings.HasPrefix(p.rest(), "#{"):
+			p.pos += 2
+			v, err := ev.expr(p)
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !p.consume("}") {
+				return nil, fmt.Errorf("unterminated interpolation")
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot interpolate a list")
+			}
+			b.WriteString(s)
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return nil, fmt.Errorf("unterminated string")
+}
+
+// array evaluates a [a, b] literal of strings.
+func (ev *rbEvaluator) array(p *rbParser) (interface{}, error) {
+	p.pos++
+	list := make([]string, 0)
+	for {
+		p.skipSpace()
+		if p.consume("]") {
+			return list, nil
+		}
+		v, err := ev.expr(p)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, errRbSkip
+		}
+		list = append(list, s)
+		p.skipSpace()
+		if !p.consume(",") && p.peek() != ']' {
+			return nil, fmt.Errorf("malformed array")
+		}
+	}
+}
+
+// env evaluates ENV['NAME'] and ENV.fetch('NAME', default).
+func (ev *rbEvaluator) env(p *rbParser) (interface{}, error) {
+	if p.consume("[") {
+		v, err := ev.expr(p)
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume("]") {
+			return nil, fmt.Errorf("malformed ENV lookup")
+		}
+		name, _ := v.(string)
+		return ev.getenv(name), nil
+	}
+
+	if p.consume(".fetch") {
+		args, err := ev.args(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("ENV.fetch requires a name")
+		}
+		if v := ev.getenv(args[0]); v != "" || len(args) == 1 {
+			return v, nil
+		}
+		return args[1], nil
+	}
+	return nil, errRbSkip
+}
+
+// fileCall evaluates File.dirname, File.join and File.expand_path.
+func (ev *rbEvaluator) fileCall(p *rbParser) (interface{}, error) {
+	if !p.consume(".") {
+		return nil, errRbSkip
+	}
+	method := p.ident()
+
+	args, err := ev.args(p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case method == "dirname" && len(args) == 1:
+		return filepath.Dir(args[0]), nil
+	case method == "join" && len(args) > 0:
+		return filepath.Join(args...), nil
+	case method == "expand_path" && len(args) > 0:
+		path := expandHome(args[0])
+		if !filepath.IsAbs(path) && len(args) > 1 {
+			path = filepath.Join(expandHome(args[1]), path)
+		}
+		return filepath.Abs(path)
+	}
+	return nil, errRbSkip
+}
+
+// args evaluates a parenthesized list of string arguments.
+func (ev *rbEvaluator) args(p *rbParser) ([]string, error) {
+	if !p.consume("(") {
+		return nil, fmt.Errorf("expected (")
+	}
+
+	var out []string
+	for {
+		p.skipSpace()
+		if p.consume(")") {
+			return out, nil
+		}
+		v, err := ev.expr(p)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, errRbSkip
+		}
+		out = append(out, s)
+		p.skipSpace()
+		if !p.consume(",") && p.peek() != ')' {
+			return nil, fmt.Errorf("malformed argument list")
+		}
+	}
+}
+
+// rbParser is a cursor over a single line of Ruby.
+type rbParser struct {
+	src string
+	pos int
+}
+
+func (p *rbParser) done() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *rbParser) rest() string {
+	return p.src[p.pos:]
+}
+
+func (p *rbParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *rbParser) skipSpace() {
+	for !p.done() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// consume skips leading space and advances past tok when it is next.
+func (p *rbParser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.rest(), tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// ident reads a Ruby identifier, which may end in ? or !.
+func (p *rbParser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for !p.done() {
+		c := rune(p.src[p.pos])
+		if c == '_' || unicode.IsLetter(c) || (p.pos > start && unicode.IsDigit(c)) {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.pos > start && !p.done() && (p.src[p.pos] == '?' || p.src[p.pos] == '!') {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// singleQuoted reads a single-quoted string, which has no interpolation.
+func (p *rbParser) singleQuoted() (interface{}, error) {
+	p.pos++
+	var b strings.Builder
+	for !p.done() {
+		c := p.src[p.pos]
+		switch {
+		case c == '\'':
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.src) && (p.src[p.pos+1] == '\'' || p.src[p.pos+1] == '\\'):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return nil, fmt.Errorf("unterminated string")
+}
+
+// wordList reads a %w() word list with any of the (), [], {} or <> delimiters.
+func (p *rbParser) wordList() (interface{}, error) {
+	p.pos += 2
+	closers := map[byte]byte{'(': ')', '[': ']', '{': '}', '<': '>'}
+	closer, ok := closers[p.peek()]
+	if !ok {
+		return nil, errRbSkip
+	}
+	p.pos++
+
+	end := strings.IndexByte(p.rest(), closer)
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated word list")
+	}
+	words := strings.Fields(p.src[p.pos : p.pos+end])
+	p.pos += end + 1
+	return words, nil
+}
+
+// number reads a numeric literal as its source text.
+func (p *rbParser) number() string {
+	start := p.pos
+	p.pos++
+	for !p.done() && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.' || p.src[p.pos] == '_') {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// stripRbComment removes a trailing # comment that is not inside a string or an interpolation.
+func stripRbComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// rbList returns the names of a knife list setting, given as an array or as a comma-separated string
+// such as "alice, bob", with surrounding spaces and empty entries dropped.
+func rbList(v interface{}) []string {
+	var names []string
+	switch v := v.(type) {
+	case []string:
+		names = v
+	case string:
+		names = strings.Split(v, ",")
+	}
+
+	var out []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,71 @@
+package profile
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRb(t *testing.T) {
+	env := map[string]string{"CHEF_USER": "tester", "ORG": "acme"}
+	rb, err := parseRb("/home/tester/.chef/knife.rb", []byte(`# knife.rb
+current_dir = File.dirname(__FILE__)
+log_level                :info
+node_name                ENV['CHEF_USER']
+client_key               "#{current_dir}/#{ENV['CHEF_USER']}.pem" # the client key
+chef_server_url          "https://chef.example.com/organizations/#{ENV.fetch('ORG', 'default')}"
+ssl_verify_mode          :verify_none
+cookbook_path            [File.join(current_dir, '..', 'cookbooks')]
+knife[:vault_admins] = %w(alice bob)
+knife[:vault_mode] = 'client'
+knife[:editor] = "vim"
+if ENV['CI'] then puts "ci" end
+`), func(k string) string { return env[k] })
+	require.NoError(t, err)
+
+	require.Equal(t, "tester", rb.settings["node_name"])
+	require.Equal(t, "/home/tester/.chef/tester.pem", rb.settings["client_key"])
+	require.Equal(t, "https://chef.example.com/organizations/acme", rb.settings["chef_server_url"])
+	require.Equal(t, "verify_none", rb.settings["ssl_verify_mode"])
+	require.Equal(t, []string{filepath.Clean("/home/tester/cookbooks")}, rb.settings["cookbook_path"])
+	require.Equal(t, []string{"alice", "bob"}, rb.knife["vault_admins"])
+	require.Equal(t, "client", rb.knife["vault_mode"])
+	require.Equal(t, "vim", rb.knife["editor"])
+}
+
+func TestParseRb_Parens(t *testing.T) {
+	rb, err := parseRb("/etc/chef/config.rb", []byte(`node_name("tester")
+chef_server_url('https://chef.example.com/')
+knife[:vault_admins] = ["alice", 'bob']
+`), func(string) string { return "" })
+	require.NoError(t, err)
+	require.Equal(t, "tester", rb.settings["node_name"])
+	require.Equal(t, "https://chef.example.com/", rb.settings["chef_server_url"])
+	require.Equal(t, []string{"alice", "bob"}, rb.knife["vault_admins"])
+}
+
+func TestParseRb_Errors(t *testing.T) {
+	_, err := parseRb("/etc/chef/knife.rb", []byte("node_name \"tester\nchef_server_url 'x'\n"), func(string) string { return "" })
+	require.ErrorContains(t, err, "knife.rb:1: unterminated string")
+
+	// malformed statements that do not set a setting this package reads are ignored
+	rb, err := parseRb("/etc/chef/knife.rb", []byte("cookbook_path \"unterminated\nnode_name 'tester'\n"), func(string) string { return "" })
+	require.NoError(t, err)
+	require.Equal(t, "tester", rb.settings["node_name"])
+}
+
+func TestRbList(t *testing.T) {
+	rb, err := parseRb("/etc/chef/knife.rb", []byte(`knife[:vault_admins] = "alice, bob,,carol "`), func(string) string { return "" })
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "bob", "carol"}, rbList(rb.knife["vault_admins"]))
+
+	require.Equal(t, []string{"alice", "bob"}, rbList([]string{"alice", " bob", ""}))
+	require.Nil(t, rbList(nil))
+}
+
+func TestStripRbComment(t *testing.T) {
+	require.Equal(t, `node_name "a#b" `, stripRbComment(`node_name "a#b" # comment`))
+	require.Equal(t, `x = "#{y}"`, stripRbComment(`x = "#{y}"`))
+	require.Equal(t, "", stripRbComment("# only a comment"))
+}
@@ -0,0 +1,443 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-chef/chef"
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/local"
+)
+
+const (
+	// EnvProfile selects the credentials profile, overriding the ~/.chef/context file.
+	EnvProfile = "CHEF_PROFILE"
+
+	// EnvServerURL overrides the Chef server URL of the loaded profile.
+	EnvServerURL = "CHEF_SERVER_URL"
+
+	// DefaultProfile is the credentials profile used when none is selected.
+	DefaultProfile = "default"
+
+	// VaultModeSolo is the vault mode that reads and writes vault items in a local chef-repo.
+	VaultModeSolo = "solo"
+)
+
+// ErrProfileNotFound is returned when a selected credentials profile does not exist.
+var ErrProfileNotFound = errors.New("profile: credentials profile not found")
+
+// ErrNoConfig is returned when neither a credentials file nor a config.rb or knife.rb can be found.
+var ErrNoConfig = errors.New("profile: no Chef configuration found")
+
+// MissingSettingError reports a required setting that the loaded configuration does not provide.
+type MissingSettingError struct {
+	Setting string
+	Source  string
+}
+
+// Error implements the error interface.
+func (e *MissingSettingError) Error() string {
+	return fmt.Sprintf("profile: %s is not set in %s", e.Setting, e.Source)
+}
+
+// Profile is a resolved set of Chef connection and chef-vault settings.
+type Profile struct {
+	// Name is the credentials profile name, or empty when loaded from a Ruby config file.
+	Name string
+
+	// Source is the file the profile was loaded from.
+	Source string
+
+	NodeName      string
+	ClientKey     string
+	ClientKeyPath string
+	ChefServerURL string
+
+	// SSLVerifyMode is verify_peer or verify_none.
+	SSLVerifyMode string
+
+	// VaultAdmins are the default admins of new vault items, from knife[:vault_admins].
+	VaultAdmins []string
+
+	// VaultMode is the chef-vault mode, client or solo, from knife[:vault_mode].
+	VaultMode string
+
+	// ChefRepoPath is the chef-repo directory solo mode operates on. A config.rb or knife.rb without
+	// chef_repo_path defaults it to the parent of the directory holding the file.
+	ChefRepoPath string
+}
+
+// Options controls where Load looks for configuration. The zero value searches ~/.chef.
+type Options struct {
+	// ChefDir is the directory holding credentials, config.rb and knife.rb. Defaults to ~/.chef.
+	ChefDir string
+
+	// Profile selects a credentials profile, overriding CHEF_PROFILE and ~/.chef/context.
+	Profile string
+
+	// ConfigFile loads this config.rb or knife.rb instead of searching ChefDir.
+	ConfigFile string
+
+	// Getenv looks up environment variables. Defaults to os.Getenv.
+	Getenv func(string) string
+
+	// NodeName, ClientKeyFile, ChefServerURL, VaultMode and ChefRepoPath override the loaded
+	// settings, as command-line flags do. They take precedence over CHEF_SERVER_URL.
+	NodeName      string
+	ClientKeyFile string
+	ChefServerURL string
+	VaultMode     string
+	ChefRepoPath  string
+}
+
+// Load resolves a Profile. An explicit Options.ConfigFile is used as-is; otherwise a profile from
+// ChefDir/credentials is preferred, falling back to ChefDir/config.rb and then ChefDir/knife.rb.
+// CHEF_SERVER_URL overrides the server URL in every case, and the Options overrides are applied last.
+func Load(opts *Options) (*Profile, error) {
+	o, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := o.load()
+	switch {
+	case errors.Is(err, ErrNoConfig) && o.complete():
+		// The overrides alone are a complete configuration.
+		p = &Profile{Source: "overrides"}
+	case err != nil:
+		return nil, err
+	}
+
+	if err := o.override(p); err != nil {
+		return nil, err
+	}
+
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// NewService loads a profile and returns a vault.Service connected with it.
+func NewService(opts *Options) (*vault.Service, error) {
+	p, err := Load(opts)
+	if err != nil {
+		return nil, err
+	}
+	return p.NewService()
+}
+
+// ChefConfig returns the go-chef client configuration for the profile.
+func (p *Profile) ChefConfig() *chef.Config {
+	return &chef.Config{
+		Name:                  p.NodeName,
+		Key:                   p.ClientKey,
+		BaseURL:               strings.TrimSuffix(p.ChefServerURL, "/") + "/",
+		SkipSSL:               p.SSLVerifyMode == "verify_none",
+		AuthenticationVersion: "1.0",
+	}
+}
+
+// NewService returns a vault.Service connected with the profile. In solo mode the service reads and
+// writes the data_bags of ChefRepoPath instead of a Chef server.
+func (p *Profile) NewService() (*vault.Service, error) {
+	if p.VaultMode == VaultModeSolo {
+		svc, err := local.NewService(&local.Options{
+			RepoPath: p.ChefRepoPath,
+			NodeName: p.NodeName,
+			Key:      p.ClientKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("profile: creating solo service from %s: %w", p.Source, err)
+		}
+		return svc, nil
+	}
+
+	client, err := chef.NewClient(p.ChefConfig())
+	if err != nil {
+		return nil, fmt.Errorf("profile: creating client from %s: %w", p.Source, err)
+	}
+	return vault.NewService(client), nil
+}
+
+// withDefaults returns a copy of opts with defaults applied.
+func (o *Options) withDefaults() (*Options, error) {
+	out := &Options{}
+	if o != nil {
+		*out = *o
+	}
+
+	if out.Getenv == nil {
+		out.Getenv = os.Getenv
+	}
+
+	if out.ChefDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		out.ChefDir = filepath.Join(home, ".chef")
+	}
+	return out, nil
+}
+
+// load reads the profile from the first configuration source that exists.
+func (o *Options) load() (*Profile, error) {
+	if o.ConfigFile != "" {
+		return loadRbProfile(o.ConfigFile, o.Getenv)
+	}
+
+	name, explicit, err := o.profileName()
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := filepath.Join(o.ChefDir, "credentials")
+	p, err := loadCredentialsProfile(credentials, name, o.ChefDir)
+	switch {
+	case err == nil:
+		return p, nil
+	case errors.Is(err, os.ErrNotExist), errors.Is(err, ErrProfileNotFound) && !explicit:
+	default:
+		return nil, err
+	}
+
+	if explicit {
+		return nil, fmt.Errorf("%w: %s has no credentials file to select profile %q from", ErrNoConfig, o.ChefDir, name)
+	}
+
+	for _, file := range []string{"config.rb", "knife.rb"} {
+		path := filepath.Join(o.ChefDir, file)
+		if _, err := os.Stat(path); err == nil {
+			return loadRbProfile(path, o.Getenv)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: looked for credentials, config.rb and knife.rb in %s", ErrNoConfig, o.ChefDir)
+}
+
+// override applies CHEF_SERVER_URL and the explicit overrides to p.
+func (o *Options) override(p *Profile) error {
+	if url := o.Getenv(EnvServerURL); url != "" {
+		p.ChefServerURL = url
+	}
+
+	if o.ChefServerURL != "" {
+		p.ChefServerURL = o.ChefServerURL
+	}
+
+	if o.NodeName != "" {
+		p.NodeName = o.NodeName
+	}
+
+	if o.VaultMode != "" {
+		p.VaultMode = o.VaultMode
+	}
+
+	if o.ChefRepoPath != "" {
+		path, err := filepath.Abs(expandHome(o.ChefRepoPath))
+		if err != nil {
+			return err
+		}
+		p.ChefRepoPath = path
+	}
+
+	if o.ClientKeyFile != "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		return p.readClientKey(o.ClientKeyFile, wd)
+	}
+	return nil
+}
+
+// complete reports whether the overrides alone make a usable configuration.
+func (o *Options) complete() bool {
+	if o.NodeName == "" || o.ClientKeyFile == "" {
+		return false
+	}
+	if o.VaultMode == VaultModeSolo {
+		return o.ChefRepoPath != ""
+	}
+	return o.ChefServerURL != ""
+}
+
+// profileName returns the selected profile and whether it was chosen explicitly rather than defaulted.
+func (o *Options) profileName() (string, bool, error) {
+	if o.Profile != "" {
+		return o.Profile, true, nil
+	}
+
+	if name := o.Getenv(EnvProfile); name != "" {
+		return name, true, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(o.ChefDir, "context"))
+	switch {
+	case err == nil:
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name, true, nil
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return "", false, err
+	}
+
+	return DefaultProfile, false, nil
+}
+
+// validate reports the first required setting that is missing.
+func (p *Profile) validate() error {
+	switch {
+	case p.NodeName == "":
+		return &MissingSettingError{Setting: "node_name (client_name)", Source: p.Source}
+	case p.ClientKey == "":
+		return &MissingSettingError{Setting: "client_key", Source: p.Source}
+	case p.VaultMode == VaultModeSolo && p.ChefRepoPath == "":
+		return &MissingSettingError{Setting: "chef_repo_path", Source: p.Source}
+	case p.VaultMode != VaultModeSolo && p.ChefServerURL == "":
+		return &MissingSettingError{Setting: "chef_server_url (or " + EnvServerURL + ")", Source: p.Source}
+	}
+
+	switch p.SSLVerifyMode {
+	case "", "verify_peer", "verify_none":
+	default:
+		return fmt.Errorf("profile: ssl_verify_mode in %s must be verify_peer or verify_none, not %q", p.Source, p.SSLVerifyMode)
+	}
+
+	switch p.VaultMode {
+	case "", "client", VaultModeSolo:
+	default:
+		return fmt.Errorf("profile: knife[:vault_mode] in %s must be client or solo, not %q", p.Source, p.VaultMode)
+	}
+	return nil
+}
+
+// credentialsProfile is a table of the credentials file.
+type credentialsProfile struct {
+	ClientName    string           `toml:"client_name"`
+	NodeName      string           `toml:"node_name"`
+	ClientKey     string           `toml:"client_key"`
+	ChefServerURL string           `toml:"chef_server_url"`
+	SSLVerifyMode string           `toml:"ssl_verify_mode"`
+	ChefRepoPath  string           `toml:"chef_repo_path"`
+	Knife         credentialsKnife `toml:"knife"`
+}
+
+// credentialsKnife is the knife table of a credentials profile.
+type credentialsKnife struct {
+	VaultAdmins []string `toml:"vault_admins"`
+	VaultMode   string   `toml:"vault_mode"`
+}
+
+// loadCredentialsProfile reads a named profile from a TOML credentials file. client_key may hold
+// the key itself or a path relative to chefDir.
+func loadCredentialsProfile(path, name, chefDir string) (*Profile, error) {
+	var profiles map[string]credentialsProfile
+	if _, err := toml.DecodeFile(path, &profiles); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("profile: reading %s: %w", path, err)
+	}
+
+	cp, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q in %s", ErrProfileNotFound, name, path)
+	}
+
+	p := &Profile{
+		Name:          name,
+		Source:        fmt.Sprintf("%s [%s]", path, name),
+		NodeName:      cp.ClientName,
+		ChefServerURL: cp.ChefServerURL,
+		SSLVerifyMode: strings.TrimPrefix(cp.SSLVerifyMode, ":"),
+		VaultAdmins:   cp.Knife.VaultAdmins,
+		VaultMode:     cp.Knife.VaultMode,
+	}
+	if p.NodeName == "" {
+		p.NodeName = cp.NodeName
+	}
+
+	if cp.ChefRepoPath != "" {
+		p.ChefRepoPath = resolvePath(cp.ChefRepoPath, chefDir)
+	}
+
+	if err := p.readClientKey(cp.ClientKey, chefDir); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// loadRbProfile reads a config.rb or knife.rb file.
+func loadRbProfile(path string, getenv func(string) string) (*Profile, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	rb, err := parseRb(abs, data, getenv)
+	if err != nil {
+		return nil, fmt.Errorf("profile: %w", err)
+	}
+
+	p := &Profile{Source: abs}
+	p.NodeName, _ = rb.settings["node_name"].(string)
+	p.ChefServerURL, _ = rb.settings["chef_server_url"].(string)
+	p.SSLVerifyMode, _ = rb.settings["ssl_verify_mode"].(string)
+	p.VaultMode, _ = rb.knife["vault_mode"].(string)
+	if repo, ok := rb.settings["chef_repo_path"].(string); ok && repo != "" {
+		p.ChefRepoPath = resolvePath(repo, filepath.Dir(abs))
+	} else {
+		// knife defaults the repository to the directory holding the .chef directory.
+		p.ChefRepoPath = filepath.Dir(filepath.Dir(abs))
+	}
+
+	p.VaultAdmins = rbList(rb.knife["vault_admins"])
+
+	if key, ok := rb.settings["client_key"].(string); ok {
+		if err := p.readClientKey(key, filepath.Dir(abs)); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// readClientKey sets the client key from inline PEM, or from a file resolved against dir.
+func (p *Profile) readClientKey(value, dir string) error {
+	if value == "" {
+		return nil
+	}
+
+	if strings.Contains(value, "-----BEGIN") {
+		p.ClientKey = value
+		return nil
+	}
+
+	path := resolvePath(value, dir)
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("profile: reading client_key from %s: %w", p.Source, err)
+	}
+	p.ClientKeyPath = path
+	p.ClientKey = string(key)
+	return nil
+}
+
+// resolvePath expands a leading ~ in path and resolves it against dir when relative.
+func resolvePath(path, dir string) string {
+	path = expandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path
+}
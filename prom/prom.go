@@ -0,0 +1,163 @@
+// Package prom exports the measurements of a vault.Service as Prometheus metrics.
+//
+//	m := prom.New("chef_vault")
+//	prometheus.MustRegister(m)
+//	svc.Metrics = m
+//
+// Operations and Chef API requests are counted and timed by name and by the cheferr class of the
+// error they failed with, "none" for those that succeeded.
+package prom
+
+import (
+	"time"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// errorLabel is the error label of calls that succeeded.
+const errorLabel = "none"
+
+// Metrics is a vault.Metrics that is also a prometheus.Collector. Register it with a
+// prometheus.Registerer to export what it records.
+type Metrics struct {
+	operations      *prometheus.CounterVec
+	operationErrors *prometheus.CounterVec
+	operationTime   *prometheus.HistogramVec
+	requests        *prometheus.CounterVec
+	requestTime     *prometheus.HistogramVec
+	retries         *prometheus.CounterVec
+	searchPages     *prometheus.CounterVec
+	searchRows      *prometheus.CounterVec
+	actors          prometheus.Histogram
+	sparseItems     prometheus.Histogram
+}
+
+var _ vault.Metrics = (*Metrics)(nil)
+
+// New returns Metrics whose metric names start with namespace, which may be empty.
+func New(namespace string) *Metrics {
+	return &Metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operations_total",
+			Help:      "Vault operations, by operation and error class.",
+		}, []string{"operation", "error"}),
+		operationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operation_errors_total",
+			Help:      "Vault operations that failed, by operation and error class.",
+		}, []string{"operation", "error"}),
+		operationTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of vault operations, by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chef_requests_total",
+			Help:      "Chef API requests, by call and error class.",
+		}, []string{"call", "error"}),
+		requestTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "chef_request_duration_seconds",
+			Help:      "Latency of Chef API requests, by call.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"call"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chef_request_retries_total",
+			Help:      "Chef API requests repeated after a transient failure, by call and error class.",
+		}, []string{"call", "error"}),
+		searchPages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "search_pages_total",
+			Help:      "Pages of client search results fetched, by index.",
+		}, []string{"index"}),
+		searchRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "search_rows_total",
+			Help:      "Client search results fetched, by index.",
+		}, []string{"index"}),
+		actors: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "actors_encrypted",
+			Help:      "Actors a shared secret was encrypted for, per operation.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}),
+		sparseItems: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "sparse_items_written",
+			Help:      "Sparse keys items written, per operation.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}),
+	}
+}
+
+// ObserveOperation implements vault.Metrics.
+func (m *Metrics) ObserveOperation(op string, d time.Duration, errClass string) {
+	m.operations.WithLabelValues(op, label(errClass)).Inc()
+	if errClass != "" {
+		m.operationErrors.WithLabelValues(op, errClass).Inc()
+	}
+	m.operationTime.WithLabelValues(op).Observe(d.Seconds())
+}
+
+// ObserveChefRequest implements vault.Metrics.
+func (m *Metrics) ObserveChefRequest(call string, d time.Duration, errClass string) {
+	m.requests.WithLabelValues(call, label(errClass)).Inc()
+	m.requestTime.WithLabelValues(call).Observe(d.Seconds())
+}
+
+// ObserveRetry implements vault.Metrics.
+func (m *Metrics) ObserveRetry(call string, errClass string) {
+	m.retries.WithLabelValues(call, label(errClass)).Inc()
+}
+
+// ObserveSearchPage implements vault.Metrics.
+func (m *Metrics) ObserveSearchPage(index string, rows int) {
+	m.searchPages.WithLabelValues(index).Inc()
+	m.searchRows.WithLabelValues(index).Add(float64(rows))
+}
+
+// ObserveActorsEncrypted implements vault.Metrics.
+func (m *Metrics) ObserveActorsEncrypted(n int) {
+	m.actors.Observe(float64(n))
+}
+
+// ObserveSparseItemsWritten implements vault.Metrics.
+func (m *Metrics) ObserveSparseItemsWritten(n int) {
+	m.sparseItems.Observe(float64(n))
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.operations, m.operationErrors, m.operationTime,
+		m.requests, m.requestTime, m.retries,
+		m.searchPages, m.searchRows,
+		m.actors, m.sparseItems,
+	}
+}
+
+// label returns the error label of errClass.
+func label(errClass string) string {
+	if errClass == "" {
+		return errorLabel
+	}
+	return errClass
+}
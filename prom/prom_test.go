@@ -0,0 +1,59 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("chef_vault")
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(m))
+
+	m.ObserveOperation("get", 10*time.Millisecond, "")
+	m.ObserveOperation("get", 20*time.Millisecond, cheferr.ClassNotFound)
+	m.ObserveChefRequest("GetDataBagItem", time.Millisecond, "")
+	m.ObserveRetry("UpdateDataBagItem", cheferr.ClassServerError)
+	m.ObserveSearchPage("node", 3)
+	m.ObserveActorsEncrypted(4)
+	m.ObserveSparseItemsWritten(2)
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP chef_vault_operations_total Vault operations, by operation and error class.
+# TYPE chef_vault_operations_total counter
+chef_vault_operations_total{error="none",operation="get"} 1
+chef_vault_operations_total{error="not_found",operation="get"} 1
+# HELP chef_vault_operation_errors_total Vault operations that failed, by operation and error class.
+# TYPE chef_vault_operation_errors_total counter
+chef_vault_operation_errors_total{error="not_found",operation="get"} 1
+# HELP chef_vault_chef_requests_total Chef API requests, by call and error class.
+# TYPE chef_vault_chef_requests_total counter
+chef_vault_chef_requests_total{call="GetDataBagItem",error="none"} 1
+# HELP chef_vault_chef_request_retries_total Chef API requests repeated after a transient failure, by call and error class.
+# TYPE chef_vault_chef_request_retries_total counter
+chef_vault_chef_request_retries_total{call="UpdateDataBagItem",error="server_error"} 1
+# HELP chef_vault_search_pages_total Pages of client search results fetched, by index.
+# TYPE chef_vault_search_pages_total counter
+chef_vault_search_pages_total{index="node"} 1
+# HELP chef_vault_search_rows_total Client search results fetched, by index.
+# TYPE chef_vault_search_rows_total counter
+chef_vault_search_rows_total{index="node"} 3
+`),
+		"chef_vault_operations_total",
+		"chef_vault_operation_errors_total",
+		"chef_vault_chef_requests_total",
+		"chef_vault_chef_request_retries_total",
+		"chef_vault_search_pages_total",
+		"chef_vault_search_rows_total",
+	))
+
+	require.Equal(t, 1, testutil.CollectAndCount(m, "chef_vault_actors_encrypted"))
+	require.Equal(t, 1, testutil.CollectAndCount(m, "chef_vault_sparse_items_written"))
+	require.Equal(t, 1, testutil.CollectAndCount(m, "chef_vault_operation_duration_seconds"))
+}
@@ -1,8 +1,10 @@
 package vault
 
 import (
+	"context"
 	"errors"
 	"maps"
+	"time"
 
 	"github.com/go-chef/chef"
 	"github.com/justintsteele/go-chef-vault/item"
@@ -14,10 +16,10 @@ type RefreshResponse = UpdateResponse
 
 // refreshOps defines the callable operations required to execute a Refresh request.
 type refreshOps struct {
-	loadSharedSecret    func(*Payload) ([]byte, error)
+	loadSharedSecret    func(context.Context, *Payload) ([]byte, error)
 	encryptSharedSecret func(pem string, secret []byte) (string, error)
-	getItem             func(string, string) (chef.DataBagItem, error)
-	updateVault         func(*Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
+	getItem             func(context.Context, string, string) (chef.DataBagItem, error)
+	updateVault         func(context.Context, *Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
 }
 
 // Refresh reprocesses the vault search query and ensures all matching nodes have an encrypted secret,
@@ -26,6 +28,15 @@ type refreshOps struct {
 // References:
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_refresh.rb
 func (s *Service) Refresh(payload *Payload) (*RefreshResponse, error) {
+	return s.RefreshContext(context.Background(), payload)
+}
+
+// RefreshContext is Refresh with requests made under ctx.
+func (s *Service) RefreshContext(ctx context.Context, payload *Payload) (resp *RefreshResponse, err error) {
+	defer s.observe(OpRefresh, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpRefresh, payload.traceAttributes()...)
+	defer sp.end(&err)
+
 	if err := payload.validatePayload(); err != nil {
 		return nil, err
 	}
@@ -33,16 +44,25 @@ func (s *Service) Refresh(payload *Payload) (*RefreshResponse, error) {
 	ops := refreshOps{
 		loadSharedSecret:    s.loadSharedSecret,
 		encryptSharedSecret: item_keys.EncryptSharedSecret,
-		getItem:             s.GetItem,
+		getItem:             s.GetItemContext,
 		updateVault:         s.updateVault,
 	}
 
-	return s.refresh(payload, ops)
+	return withItemLock(ctx, s, payload, func(ctx context.Context) (*RefreshResponse, error) {
+		return withAudit(ctx, s, OpRefresh, payload.VaultName, payload.VaultItemName, func() (*RefreshResponse, error) {
+			return s.refresh(ctx, payload, ops)
+		})
+	})
 }
 
 // refresh is the worker called by the public API with the operational methods to complete the refresh request.
-func (s *Service) refresh(payload *Payload, ops refreshOps) (*RefreshResponse, error) {
-	keyState, err := s.loadKeysCurrentState(payload)
+func (s *Service) refresh(ctx context.Context, payload *Payload, ops refreshOps) (*RefreshResponse, error) {
+	revision, err := s.expectedRevision(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	keyState, err := s.loadKeysCurrentState(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +88,7 @@ func (s *Service) refresh(payload *Payload, ops refreshOps) (*RefreshResponse, e
 		Admins:        nextState.Admins,
 	}
 
-	searchedClients, err := s.getClientsFromSearch(refreshPayload)
+	searchedClients, err := s.getClientsFromSearch(ctx, refreshPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -76,9 +96,15 @@ func (s *Service) refresh(payload *Payload, ops refreshOps) (*RefreshResponse, e
 	normalizedClients := item_keys.MergeClients(searchedClients, nextState.Clients)
 
 	addedClients := item_keys.DiffLists(normalizedClients, nextState.Clients)
+	s.logger().Debug("refresh clients resolved", "vault", payload.VaultName, "item", payload.VaultItemName,
+		"searched", len(searchedClients), "added", addedClients)
+
+	if err := s.checkRevision(ctx, payload.VaultName, payload.VaultItemName, revision); err != nil {
+		return nil, err
+	}
 
 	if payload.CleanUnknown {
-		normalizedClients, _, err = s.cleanUnknownClients(payload, nextState, normalizedClients)
+		normalizedClients, _, err = s.cleanUnknownClients(ctx, payload, nextState, normalizedClients)
 		if err != nil {
 			return nil, err
 		}
@@ -88,17 +114,17 @@ func (s *Service) refresh(payload *Payload, ops refreshOps) (*RefreshResponse, e
 	refreshPayload.Clients = normalizedClients
 
 	if payload.SkipReencrypt {
-		return s.refreshSkipReencrypt(refreshPayload, nextState, addedClients, ops)
+		return s.refreshSkipReencrypt(ctx, refreshPayload, nextState, addedClients, ops)
 	}
 
 	nextState.Clients = normalizedClients
-	return s.refreshReencrypt(refreshPayload, nextState, ops)
+	return s.refreshReencrypt(ctx, refreshPayload, nextState, ops)
 }
 
 // refreshReencrypt performs a full refresh by re-encrypting the vault using
 // a newly generated shared secret. All existing data and keys are re-written.
-func (s *Service) refreshReencrypt(payload *Payload, keyState *item_keys.VaultItemKeys, ops refreshOps) (*RefreshResponse, error) {
-	currentItem, err := ops.getItem(payload.VaultName, payload.VaultItemName)
+func (s *Service) refreshReencrypt(ctx context.Context, payload *Payload, keyState *item_keys.VaultItemKeys, ops refreshOps) (*RefreshResponse, error) {
+	currentItem, err := ops.getItem(ctx, payload.VaultName, payload.VaultItemName)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +142,7 @@ func (s *Service) refreshReencrypt(payload *Payload, keyState *item_keys.VaultIt
 		Desired: keyState.Mode,
 	}
 
-	keysResult, err := ops.updateVault(payload, modeState)
+	keysResult, err := ops.updateVault(ctx, payload, modeState)
 	if err != nil {
 		return nil, err
 	}
@@ -132,29 +158,36 @@ func (s *Service) refreshReencrypt(payload *Payload, keyState *item_keys.VaultIt
 // refreshSkipReencrypt performs a refresh without re-encrypting the vault.
 // New actors are granted access by encrypting the existing shared secret,
 // preserving all existing encrypted data and keys.
-func (s *Service) refreshSkipReencrypt(payload *Payload, keyState *item_keys.VaultItemKeys, clients []string, ops refreshOps) (*RefreshResponse, error) {
-	sharedSecret, err := ops.loadSharedSecret(payload)
+func (s *Service) refreshSkipReencrypt(ctx context.Context, payload *Payload, keyState *item_keys.VaultItemKeys, clients []string, ops refreshOps) (*RefreshResponse, error) {
+	sharedSecret, err := ops.loadSharedSecret(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, actor := range clients {
-		pub, err := s.clientPublicKey(actor)
-		if err != nil {
-			return nil, err
-		}
+	if err := s.encryptSecret(ctx, keyState.Mode, len(clients), func() error {
+		for _, actor := range clients {
+			pub, err := s.clientPublicKey(ctx, actor)
+			if err != nil {
+				return err
+			}
 
-		enc, err := ops.encryptSharedSecret(pub.PublicKey, sharedSecret)
-		if err != nil {
-			return nil, err
-		}
+			enc, err := ops.encryptSharedSecret(pub.PublicKey, sharedSecret)
+			if err != nil {
+				return err
+			}
 
-		keyState.Keys[actor] = enc
+			keyState.Keys[actor] = enc
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
+	s.metrics().ObserveActorsEncrypted(len(clients))
+	s.logger().Info("shared secret encrypted for new clients", "vault", payload.VaultName, "item", payload.VaultItemName, "clients", clients)
 
 	keys := keyState.BuildKeysItem(keyState.Clients)
 	result := &item_keys.VaultItemKeysResult{}
-	if err := s.writeKeys(payload, keyState.Mode, keys, result); err != nil {
+	if err := s.writeKeys(ctx, payload, keyState.Mode, keys, result); err != nil {
 		return nil, err
 	}
 	return &RefreshResponse{
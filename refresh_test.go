@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -20,7 +21,7 @@ type refreshRecorder struct {
 
 func (r *refreshRecorder) ops() refreshOps {
 	return refreshOps{
-		loadSharedSecret: func(*Payload) ([]byte, error) {
+		loadSharedSecret: func(context.Context, *Payload) ([]byte, error) {
 			r.calls = append(r.calls, "loadSecret")
 			return []byte("secret"), nil
 		},
@@ -28,7 +29,7 @@ func (r *refreshRecorder) ops() refreshOps {
 			r.calls = append(r.calls, "encryptSharedSecret")
 			return "new encrypted secret", nil
 		},
-		getItem: func(_, _ string) (chef.DataBagItem, error) {
+		getItem: func(_ context.Context, _, _ string) (chef.DataBagItem, error) {
 			r.calls = append(r.calls, "getItem")
 			type data chef.DataBagItem
 			var current data
@@ -45,7 +46,7 @@ func (r *refreshRecorder) ops() refreshOps {
 			}
 			return current, nil
 		},
-		updateVault: func(payload *Payload, state *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
+		updateVault: func(_ context.Context, payload *Payload, state *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
 			r.calls = append(r.calls, "updateVault")
 			r.wrote.refreshPayload = payload
 			r.wrote.modeState = state
@@ -66,7 +67,7 @@ func TestRefresh_CleanUnknownClients(t *testing.T) {
 		"testhost4",
 		"testhost5",
 	}
-	kept, removed, err := resolveClients(clients, service.clientExists)
+	kept, removed, err := resolveClients(context.Background(), clients, service.clientExists)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -86,7 +87,7 @@ func TestRefresh_Reencrypt(t *testing.T) {
 
 	rec := refreshRecorder{}
 
-	_, err := service.refresh(&Payload{
+	_, err := service.refresh(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 	}, rec.ops())
@@ -104,7 +105,7 @@ func TestRefresh_SkipReencrypt(t *testing.T) {
 
 	rec := refreshRecorder{}
 
-	_, err := service.refresh(&Payload{
+	_, err := service.refresh(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 		SkipReencrypt: true,
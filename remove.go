@@ -1,7 +1,10 @@
 package vault
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-chef/chef"
 	"github.com/justintsteele/go-chef-vault/item"
@@ -17,34 +20,62 @@ type RemoveResponse struct {
 
 // RemoveDataResponse represents the response returned after removing data from the vault item.
 type RemoveDataResponse struct {
-	URI string `json:"uri"`
+	URI     string   `json:"uri"`
+	Removed []string `json:"removed,omitempty"`
+	Missing []string `json:"missing,omitempty"`
 }
 
 // removeOps defines the callable operations required to execute an Remove request.
 type removeOps struct {
-	getItem func(string, string) (chef.DataBagItem, error)
-	update  func(*Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
+	getItem func(context.Context, string, string) (chef.DataBagItem, error)
+	update  func(context.Context, *Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
 }
 
 // Remove removes clients, admins, or data keys from an existing vault item.
 //
+// Data may be removed by mirroring its shape in Payload.Content, or by listing JSON Pointer or
+// dotted paths in Payload.RemovePaths. Path removal can address array elements by index or value
+// and keeps parents that become empty. With Payload.StrictPaths set, any path that does not
+// resolve fails the request with ErrPathNotFound before anything is written. As with Update, the
+// request fails with ErrConflict if the item changed since Payload.Revision or since it was read.
+//
 // References:
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_remove.rb
 func (s *Service) Remove(payload *Payload) (*RemoveResponse, error) {
+	return s.RemoveContext(context.Background(), payload)
+}
+
+// RemoveContext is Remove with requests made under ctx.
+func (s *Service) RemoveContext(ctx context.Context, payload *Payload) (resp *RemoveResponse, err error) {
+	defer s.observe(OpRemove, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpRemove, payload.traceAttributes()...)
+	defer sp.end(&err)
+
 	if err := payload.validatePayload(); err != nil {
 		return nil, err
 	}
 
 	ops := removeOps{
-		getItem: s.GetItem,
+		getItem: s.GetItemContext,
 		update:  s.updateVault,
 	}
-	return s.remove(payload, ops)
+	return withItemLock(ctx, s, payload, func(ctx context.Context) (*RemoveResponse, error) {
+		return withAudit(ctx, s, OpRemove, payload.VaultName, payload.VaultItemName, func() (*RemoveResponse, error) {
+			return withConflictRetry(payload, func(p *Payload) (*RemoveResponse, error) {
+				return s.remove(ctx, p, ops)
+			})
+		})
+	})
 }
 
 // remove is the worker called by the public API with the operational methods to complete the Remove request.
-func (s *Service) remove(payload *Payload, ops removeOps) (*RemoveResponse, error) {
-	keyState, err := s.loadKeysCurrentState(payload)
+func (s *Service) remove(ctx context.Context, payload *Payload, ops removeOps) (*RemoveResponse, error) {
+	revision, err := s.expectedRevision(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	keyState, err := s.loadKeysCurrentState(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -55,45 +86,68 @@ func (s *Service) remove(payload *Payload, ops removeOps) (*RemoveResponse, erro
 		KeysMode:      &keyState.Mode,
 	}
 
-	if payload.CleanUnknown {
-		resolvedClients, _, err := s.cleanUnknownClients(payload, keyState, keyState.Clients)
+	// content is resolved before any actor keys are pruned so that a strict path miss fails without side effects.
+	var removedPaths, missingPaths []string
+	if payload.Content != nil || len(payload.RemovePaths) != 0 {
+		current, err := ops.getItem(ctx, payload.VaultName, payload.VaultItemName)
 		if err != nil {
 			return nil, err
 		}
 
-		keyState.Clients = resolvedClients
+		dbi, err := item.DataBagItemMap(current)
+		if err != nil {
+			return nil, err
+		}
+
+		if payload.Content != nil {
+			removeContent, ok := pruneData(dbi, payload.Content)
+			if ok {
+				dbi = removeContent.(map[string]any)
+			} else {
+				dbi = nil
+			}
+		}
+
+		if len(payload.RemovePaths) != 0 {
+			dbi, removedPaths, missingPaths, err = removeContentPaths(dbi, payload.RemovePaths)
+			if err != nil {
+				return nil, err
+			}
+
+			if payload.StrictPaths && len(missingPaths) != 0 {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, strings.Join(missingPaths, ", "))
+			}
+		}
+
+		finalPayload.Content = dbi
 	}
 
-	if err := s.resolveActors(payload, keyState); err != nil {
+	if err := s.checkRevision(ctx, payload.VaultName, payload.VaultItemName, revision); err != nil {
 		return nil, err
 	}
 
-	finalPayload.Admins = keyState.Admins
-	finalPayload.Clients = keyState.Clients
-
-	if payload.Content != nil {
-		current, err := ops.getItem(payload.VaultName, payload.VaultItemName)
+	if payload.CleanUnknown {
+		resolvedClients, _, err := s.cleanUnknownClients(ctx, payload, keyState, keyState.Clients)
 		if err != nil {
 			return nil, err
 		}
 
-		dbi, err := item.DataBagItemMap(current)
-		if err != nil {
-			return nil, err
-		}
+		keyState.Clients = resolvedClients
+	}
 
-		removeContent, ok := pruneData(dbi, payload.Content)
-		if ok {
-			finalPayload.Content = removeContent.(map[string]any)
-		}
+	if err := s.resolveActors(ctx, payload, keyState); err != nil {
+		return nil, err
 	}
 
+	finalPayload.Admins = keyState.Admins
+	finalPayload.Clients = keyState.Clients
+
 	keysModeState := &item_keys.KeysModeState{
 		Current: keyState.Mode,
 		Desired: keyState.Mode,
 	}
 
-	removed, err := ops.update(finalPayload, keysModeState)
+	removed, err := ops.update(ctx, finalPayload, keysModeState)
 	if err != nil {
 		return nil, err
 	}
@@ -108,17 +162,19 @@ func (s *Service) remove(payload *Payload, ops removeOps) (*RemoveResponse, erro
 				s.vaultURL(finalPayload.VaultName),
 				finalPayload.VaultItemName,
 			),
+			Removed: removedPaths,
+			Missing: missingPaths,
 		},
 		KeysURIs: removed.URIs,
 	}, nil
 }
 
 // resolveActors removes actors and their keys.
-func (s *Service) resolveActors(payload *Payload, keyState *item_keys.VaultItemKeys) error {
+func (s *Service) resolveActors(ctx context.Context, payload *Payload, keyState *item_keys.VaultItemKeys) error {
 	toRemove := make([]string, 0)
 
 	if payload.SearchQuery != nil {
-		found, err := s.getClientsFromSearch(payload)
+		found, err := s.getClientsFromSearch(ctx, payload)
 		if err != nil {
 			return err
 		}
@@ -137,12 +193,27 @@ func (s *Service) resolveActors(payload *Payload, keyState *item_keys.VaultItemK
 		return nil
 	}
 
-	if err := s.pruneKeys(toRemove, keyState, payload); err != nil {
+	if err := s.pruneKeys(ctx, toRemove, keyState, payload); err != nil {
 		return err
 	}
 	return nil
 }
 
+// removeContentPaths parses the requested paths and removes them from the current content.
+func removeContentPaths(current map[string]any, rawPaths []string) (map[string]any, []string, []string, error) {
+	paths := make([]item.Path, 0, len(rawPaths))
+	for _, raw := range rawPaths {
+		p, err := item.ParsePath(raw)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		paths = append(paths, p)
+	}
+
+	out, removed, missing := item.RemovePaths(current, paths)
+	return out, removed, missing, nil
+}
+
 // pruneData recursively removes keys from existing data based on the shape of the remove payload.
 func pruneData(existing, remove any) (any, bool) {
 	switch rem := remove.(type) {
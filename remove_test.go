@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -20,14 +21,14 @@ type removeRecorder struct {
 
 func (r *removeRecorder) ops() removeOps {
 	return removeOps{
-		getItem: func(vaultName, vaultItemName string) (chef.DataBagItem, error) {
+		getItem: func(_ context.Context, vaultName, vaultItemName string) (chef.DataBagItem, error) {
 			r.calls = append(r.calls, "getItem")
 			return map[string]interface{}{
 				"foo": "foo-value-1",
 				"bar": "bar-value-1",
 			}, nil
 		},
-		update: func(payload *Payload, mode *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
+		update: func(_ context.Context, payload *Payload, mode *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
 			r.calls = append(r.calls, "update")
 			r.wrote.removePayload = payload
 			return &item_keys.VaultItemKeysResult{
@@ -42,7 +43,7 @@ func TestRemove_Actor(t *testing.T) {
 
 	rec := &removeRecorder{}
 
-	_, err := service.remove(&Payload{
+	_, err := service.remove(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 		Clients:       []string{"fakehost"},
@@ -59,7 +60,7 @@ func TestRemove_Data(t *testing.T) {
 
 	rec := &removeRecorder{}
 
-	_, err := service.remove(&Payload{
+	_, err := service.remove(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 		Content:       map[string]interface{}{"foo": "foo-value-1"},
@@ -113,3 +114,36 @@ func TestRemove_pruneData(t *testing.T) {
 	}
 
 }
+
+func TestRemove_Paths(t *testing.T) {
+	setupStubs(t)
+
+	rec := &removeRecorder{}
+
+	resp, err := service.remove(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		RemovePaths:   []string{"/foo", "baz.qux"},
+	}, rec.ops())
+	require.NoError(t, err)
+	require.Equal(t, []string{"getItem", "update"}, rec.calls)
+	require.Equal(t, map[string]interface{}{"bar": "bar-value-1"}, rec.wrote.removePayload.Content)
+	require.Equal(t, []string{"/foo"}, resp.Data.Removed)
+	require.Equal(t, []string{"baz.qux"}, resp.Data.Missing)
+}
+
+func TestRemove_StrictPathsMissing(t *testing.T) {
+	setupStubs(t)
+
+	rec := &removeRecorder{}
+
+	_, err := service.remove(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Clients:       []string{"testhost"},
+		RemovePaths:   []string{"foo", "baz.qux"},
+		StrictPaths:   true,
+	}, rec.ops())
+	require.ErrorIs(t, err, ErrPathNotFound)
+	require.Equal(t, []string{"getItem"}, rec.calls)
+}
@@ -0,0 +1,291 @@
+// Package render renders Go text/template files with values read from vault items.
+//
+// Templates read secrets with the vault and vaultItem functions:
+//
+//	password = {{ vault "database" "postgres" "password" }}
+//	{{ with vaultItem "database" "postgres" }}host = {{ .host }}{{ end }}
+//
+// Paths are JSON Pointers or dotted paths, as accepted by vault.Payload.RemovePaths. Each item is
+// read once per Renderer, however many templates refer to it.
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"text/template"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/internal/fsutil"
+	"github.com/justintsteele/go-chef-vault/item"
+)
+
+// DefaultMode is the file mode of rendered files when Template.Mode is zero.
+const DefaultMode os.FileMode = 0o600
+
+// ErrMissing is returned when a template refers to a vault item or path that does not exist.
+var ErrMissing = errors.New("render: missing vault value")
+
+// Source reads decrypted vault items. *vault.Service satisfies it.
+type Source interface {
+	GetItem(vaultName, vaultItem string) (chef.DataBagItem, error)
+}
+
+// Template describes a template file and where its output is written.
+type Template struct {
+	// Path is the template file.
+	Path string
+
+	// Dest is the rendered file. It is replaced atomically.
+	Dest string
+
+	// Mode is the permission of the rendered file. Defaults to DefaultMode.
+	Mode os.FileMode
+
+	// Owner and Group name the user and group of the rendered file. Empty leaves them unchanged.
+	Owner string
+	Group string
+}
+
+// Result describes the outcome of rendering one template.
+type Result struct {
+	Dest    string   `json:"dest"`
+	Written bool     `json:"written"`
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Renderer renders templates against a Source, caching the items it reads.
+type Renderer struct {
+	source Source
+
+	// Funcs are added to the template functions, and may override the defaults.
+	Funcs template.FuncMap
+
+	mu    sync.Mutex
+	items map[string]map[string]interface{}
+}
+
+// New returns a Renderer reading vault items from source.
+func New(source Source) *Renderer {
+	return &Renderer{
+		source: source,
+		items:  make(map[string]map[string]interface{}),
+	}
+}
+
+// Render renders t and atomically writes the result to t.Dest. A reference to a missing vault item
+// or path fails with ErrMissing and leaves t.Dest untouched.
+func (r *Renderer) Render(t *Template) (*Result, error) {
+	out, _, err := r.execute(t, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeAtomic(t, out); err != nil {
+		return nil, err
+	}
+	return &Result{Dest: t.Dest, Written: true}, nil
+}
+
+// Check renders t without writing anything and reports every missing vault item or path it refers to.
+func (r *Renderer) Check(t *Template) (*Result, error) {
+	_, missing, err := r.execute(t, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Dest: t.Dest, Missing: missing}, nil
+}
+
+// RenderAll renders every template, or checks them when check is set. It stops at the first error.
+func (r *Renderer) RenderAll(templates []*Template, check bool) ([]*Result, error) {
+	results := make([]*Result, 0, len(templates))
+	for _, t := range templates {
+		render := r.Render
+		if check {
+			render = r.Check
+		}
+
+		res, err := render(t)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// execute renders t into memory. In check mode missing values render as empty and are collected
+// instead of failing the template.
+func (r *Renderer) execute(t *Template, check bool) ([]byte, []string, error) {
+	if t == nil || t.Path == "" {
+		return nil, nil, errors.New("render: template path is required")
+	}
+	if !check && t.Dest == "" {
+		return nil, nil, fmt.Errorf("render: %s has no destination", t.Path)
+	}
+
+	src, err := os.ReadFile(t.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var missing []string
+	lookup := func(vaultName, vaultItem, path string) (interface{}, error) {
+		v, err := r.lookup(vaultName, vaultItem, path)
+		if check && errors.Is(err, ErrMissing) {
+			missing = append(missing, missingRef(vaultName, vaultItem, path))
+			return "", nil
+		}
+		return v, err
+	}
+
+	funcs := template.FuncMap{
+		"vault": func(vaultName, vaultItem, path string) (interface{}, error) {
+			return lookup(vaultName, vaultItem, path)
+		},
+		"vaultItem": func(vaultName, vaultItem string) (interface{}, error) {
+			return lookup(vaultName, vaultItem, "")
+		},
+		"toJSON": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"b64enc": func(v interface{}) string {
+			return base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(v)))
+		},
+	}
+	for name, fn := range r.Funcs {
+		funcs[name] = fn
+	}
+
+	tmpl, err := template.New(filepath.Base(t.Path)).Option("missingkey=error").Funcs(funcs).Parse(string(src))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		// template wraps function errors, so ErrMissing remains visible to errors.Is.
+		return nil, nil, err
+	}
+
+	sort.Strings(missing)
+	return buf.Bytes(), missing, nil
+}
+
+// lookup returns the value at path in a vault item, or the whole item when path is empty.
+func (r *Renderer) lookup(vaultName, vaultItem, path string) (interface{}, error) {
+	content, err := r.item(vaultName, vaultItem)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMissing, missingRef(vaultName, vaultItem, ""))
+	}
+
+	if path == "" {
+		return content, nil
+	}
+
+	p, err := item.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := item.Lookup(content, p)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMissing, missingRef(vaultName, vaultItem, path))
+	}
+	return v, nil
+}
+
+// item returns the cached content of a vault item, reading it on first use. A nil map means the item does not exist.
+func (r *Renderer) item(vaultName, vaultItem string) (map[string]interface{}, error) {
+	key := vaultName + "/" + vaultItem
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if content, ok := r.items[key]; ok {
+		return content, nil
+	}
+
+	raw, err := r.source.GetItem(vaultName, vaultItem)
+	if err != nil {
+		if !cheferr.IsNotFound(err) {
+			return nil, err
+		}
+		r.items[key] = nil
+		return nil, nil
+	}
+
+	content, err := item.DataBagItemMap(raw)
+	if err != nil {
+		return nil, err
+	}
+	r.items[key] = content
+	return content, nil
+}
+
+// missingRef formats a reference to a vault item or path for error messages and Result.Missing.
+func missingRef(vaultName, vaultItem, path string) string {
+	if path == "" {
+		return vaultName + "/" + vaultItem
+	}
+	return vaultName + "/" + vaultItem + ":" + path
+}
+
+// writeAtomic atomically replaces t.Dest with data, applying the template's mode and owner.
+func writeAtomic(t *Template, data []byte) error {
+	mode := t.Mode
+	if mode == 0 {
+		mode = DefaultMode
+	}
+
+	uid, gid, err := lookupOwner(t.Owner, t.Group)
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(t.Dest, data, mode, uid, gid)
+}
+
+// lookupOwner resolves user and group names, or numeric ids, to ids. Empty names resolve to -1.
+func lookupOwner(owner, group string) (int, int, error) {
+	uid, gid := -1, -1
+
+	if owner != "" {
+		u, err := user.Lookup(owner)
+		if err != nil {
+			if u, err = user.LookupId(owner); err != nil {
+				return 0, 0, fmt.Errorf("render: unknown owner %q: %w", owner, err)
+			}
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return 0, 0, fmt.Errorf("render: owner %q has non-numeric uid %q", owner, u.Uid)
+		}
+	}
+
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			if g, err = user.LookupGroupId(group); err != nil {
+				return 0, 0, fmt.Errorf("render: unknown group %q: %w", group, err)
+			}
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return 0, 0, fmt.Errorf("render: group %q has non-numeric gid %q", group, g.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
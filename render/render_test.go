@@ -0,0 +1,173 @@
+package render
+
+import (
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/go-chef/chef"
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Source = (*vault.Service)(nil)
+
+// fakeSource serves vault items from memory and counts reads.
+type fakeSource struct {
+	items map[string]map[string]interface{}
+	reads map[string]int
+}
+
+func (f *fakeSource) GetItem(vaultName, vaultItem string) (chef.DataBagItem, error) {
+	key := vaultName + "/" + vaultItem
+	f.reads[key]++
+	content, ok := f.items[key]
+	if !ok {
+		return nil, &chef.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	}
+	return content, nil
+}
+
+func newSource() *fakeSource {
+	return &fakeSource{
+		items: map[string]map[string]interface{}{
+			"database/postgres": {
+				"id":       "postgres",
+				"password": "s3cr3t",
+				"conn":     map[string]interface{}{"host": "db1", "port": 5432},
+				"hosts":    []interface{}{"a", "b"},
+			},
+		},
+		reads: make(map[string]int),
+	}
+}
+
+// writeTemplate writes a template file and returns a Template rendering it into the same directory.
+func writeTemplate(t *testing.T, body string) *Template {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+	return &Template{Path: path, Dest: filepath.Join(dir, "app.conf")}
+}
+
+func TestRender(t *testing.T) {
+	src := newSource()
+	tmpl := writeTemplate(t, `password={{ vault "database" "postgres" "password" }}
+port={{ vault "database" "postgres" "/conn/port" }}
+first={{ vault "database" "postgres" "hosts[0]" }}
+{{ with vaultItem "database" "postgres" }}host={{ .conn.host }}{{ end }}
+hosts={{ vault "database" "postgres" "hosts" | toJSON }}
+b64={{ vault "database" "postgres" "password" | b64enc }}
+`)
+	tmpl.Mode = 0o640
+
+	res, err := New(src).Render(tmpl)
+	require.NoError(t, err)
+	require.True(t, res.Written)
+	require.Equal(t, 1, src.reads["database/postgres"])
+
+	out, err := os.ReadFile(tmpl.Dest)
+	require.NoError(t, err)
+	require.Equal(t, `password=s3cr3t
+port=5432
+first=a
+host=db1
+hosts=["a","b"]
+b64=czNjcjN0
+`, string(out))
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(tmpl.Dest)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	}
+
+	// no temp files are left beside the destination
+	entries, err := os.ReadDir(filepath.Dir(tmpl.Dest))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
+
+func TestRender_CachesAcrossTemplates(t *testing.T) {
+	src := newSource()
+	r := New(src)
+
+	a := writeTemplate(t, `{{ vault "database" "postgres" "password" }}`)
+	b := writeTemplate(t, `{{ vault "database" "postgres" "conn.host" }}`)
+
+	results, err := r.RenderAll([]*Template{a, b}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, 1, src.reads["database/postgres"])
+}
+
+func TestRender_MissingLeavesDestUntouched(t *testing.T) {
+	tmpl := writeTemplate(t, `{{ vault "database" "postgres" "nope" }}`)
+	require.NoError(t, os.WriteFile(tmpl.Dest, []byte("previous"), 0o600))
+
+	_, err := New(newSource()).Render(tmpl)
+	require.ErrorIs(t, err, ErrMissing)
+	require.ErrorContains(t, err, "database/postgres:nope")
+
+	out, err := os.ReadFile(tmpl.Dest)
+	require.NoError(t, err)
+	require.Equal(t, "previous", string(out))
+}
+
+func TestCheck(t *testing.T) {
+	src := newSource()
+	tmpl := writeTemplate(t, `{{ vault "database" "postgres" "password" }}
+{{ vault "database" "postgres" "conn.user" }}
+{{ vault "database" "mysql" "password" }}
+{{ vault "database" "mysql" "user" }}
+`)
+
+	res, err := New(src).Check(tmpl)
+	require.NoError(t, err)
+	require.False(t, res.Written)
+	require.Equal(t, []string{
+		"database/mysql:password",
+		"database/mysql:user",
+		"database/postgres:conn.user",
+	}, res.Missing)
+	require.Equal(t, 1, src.reads["database/mysql"])
+
+	_, err = os.Stat(tmpl.Dest)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRender_Errors(t *testing.T) {
+	r := New(newSource())
+
+	_, err := r.Render(&Template{})
+	require.ErrorContains(t, err, "template path is required")
+
+	tmpl := writeTemplate(t, `{{ vault "database" }}`)
+	_, err = r.Render(tmpl)
+	require.Error(t, err)
+
+	tmpl = writeTemplate(t, `{{ vault "database" "postgres" "password" }}`)
+	tmpl.Owner = "no-such-user-for-render-tests"
+	_, err = r.Render(tmpl)
+	require.ErrorContains(t, err, "unknown owner")
+}
+
+func TestRender_Owner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file ownership is not supported on windows")
+	}
+
+	u, err := user.Current()
+	require.NoError(t, err)
+
+	tmpl := writeTemplate(t, `{{ vault "database" "postgres" "password" }}`)
+	tmpl.Owner = u.Username
+	tmpl.Group = u.Gid
+
+	_, err = New(newSource()).Render(tmpl)
+	require.NoError(t, err)
+}
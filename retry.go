@@ -0,0 +1,231 @@
+package vault
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+)
+
+// Defaults of a RetryPolicy whose fields are zero.
+const (
+	DefaultRetryAttempts     = 4
+	DefaultRetryInitialDelay = 200 * time.Millisecond
+	DefaultRetryMaxDelay     = 10 * time.Second
+)
+
+// RetryPolicy retries Chef API requests that fail with a transient error, as reported by
+// cheferr.IsTransient. Reads, updates and deletes are retried; creates are retried only after a 429 or
+// 503 response, with which the server declines a request without acting on it, unless they are followed
+// by an update when the item exists, as keys items are.
+//
+// The delay before each retry doubles from InitialDelay up to MaxDelay, with a random jitter of up to
+// half of it. A Retry-After header on a 429 or 503 response replaces the computed delay; a request is
+// not retried if the server asks for a delay longer than MaxDelay.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, including the first. Defaults to
+	// DefaultRetryAttempts; 1 disables retries.
+	MaxAttempts int
+
+	// InitialDelay is the delay before the first retry. Defaults to DefaultRetryInitialDelay.
+	InitialDelay time.Duration
+
+	// MaxDelay caps the delay before a retry. Defaults to DefaultRetryMaxDelay.
+	MaxDelay time.Duration
+
+	// sleep waits between attempts; tests replace it.
+	sleep func(time.Duration)
+}
+
+// attempts returns the number of times a request is sent.
+func (p *RetryPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+// delay returns how long to wait before retrying after the given failed attempt, counted from 1, and
+// whether to retry at all.
+func (p *RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	initial, maxDelay := p.InitialDelay, p.MaxDelay
+	if initial <= 0 {
+		initial = DefaultRetryInitialDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+
+	if d, ok := cheferr.RetryAfter(err); ok {
+		return d, d <= maxDelay
+	}
+
+	d := maxDelay
+	if shift := attempt - 1; shift < 32 && initial<<shift > 0 && initial<<shift < maxDelay {
+		d = initial << shift
+	}
+	return d/2 + rand.N(d/2+1), true
+}
+
+// wait sleeps for d, or until ctx is done, in which case it returns the context's error.
+func (p *RetryPolicy) wait(ctx context.Context, d time.Duration) error {
+	if p.sleep != nil {
+		p.sleep(d)
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryBackend is the Backend of a Service with a RetryPolicy. It repeats requests that fail with a
+// transient error.
+type retryBackend struct {
+	Backend
+	svc    *Service
+	policy *RetryPolicy
+}
+
+// retry sends a request named call until it succeeds, fails with an error that is not transient, runs out
+// of attempts, or ctx is done. Requests that are not idempotent are repeated only after the server declined
+// them.
+func retry[T any](ctx context.Context, b *retryBackend, call string, idempotent bool, send func() (T, error)) (T, error) {
+	res, err := send()
+	for attempt := 1; err != nil && attempt < b.policy.attempts(); attempt++ {
+		if !cheferr.IsTransient(err) || !(idempotent || cheferr.IsUnprocessed(err)) {
+			break
+		}
+		d, ok := b.policy.delay(attempt, err)
+		if !ok {
+			break
+		}
+
+		b.svc.metrics().ObserveRetry(call, cheferr.Classify(err))
+		b.svc.logger().Info("retrying Chef request", "call", call, "attempt", attempt+1, "delay", d, "error", err)
+		if err := b.policy.wait(ctx, d); err != nil {
+			return res, err
+		}
+
+		res, err = send()
+	}
+	return res, err
+}
+
+// retryDelete is retry for a delete, which has succeeded if the object is gone after an attempt failed.
+func retryDelete(ctx context.Context, b *retryBackend, call string, send func() error) error {
+	failed := false
+	_, err := retry(ctx, b, call, true, func() (struct{}, error) {
+		err := send()
+		if failed && cheferr.IsNotFound(err) {
+			return struct{}{}, nil
+		}
+		failed = err != nil
+		return struct{}{}, err
+	})
+	return err
+}
+
+// ListDataBags implements Backend.
+func (b *retryBackend) ListDataBags(ctx context.Context) (*chef.DataBagListResult, error) {
+	return retry(ctx, b, "ListDataBags", true, func() (*chef.DataBagListResult, error) {
+		return b.Backend.ListDataBags(ctx)
+	})
+}
+
+// CreateDataBag implements Backend.
+func (b *retryBackend) CreateDataBag(ctx context.Context, name string) error {
+	_, err := retry(ctx, b, "CreateDataBag", false, func() (struct{}, error) {
+		return struct{}{}, b.Backend.CreateDataBag(ctx, name)
+	})
+	return err
+}
+
+// DeleteDataBag implements Backend.
+func (b *retryBackend) DeleteDataBag(ctx context.Context, name string) error {
+	return retryDelete(ctx, b, "DeleteDataBag", func() error {
+		return b.Backend.DeleteDataBag(ctx, name)
+	})
+}
+
+// ListDataBagItems implements Backend.
+func (b *retryBackend) ListDataBagItems(ctx context.Context, bag string) (*chef.DataBagListResult, error) {
+	return retry(ctx, b, "ListDataBagItems", true, func() (*chef.DataBagListResult, error) {
+		return b.Backend.ListDataBagItems(ctx, bag)
+	})
+}
+
+// GetDataBagItem implements Backend.
+func (b *retryBackend) GetDataBagItem(ctx context.Context, bag, id string) (chef.DataBagItem, error) {
+	return retry(ctx, b, "GetDataBagItem", true, func() (chef.DataBagItem, error) {
+		return b.Backend.GetDataBagItem(ctx, bag, id)
+	})
+}
+
+// CreateDataBagItem implements Backend.
+func (b *retryBackend) CreateDataBagItem(ctx context.Context, bag string, item chef.DataBagItem) error {
+	_, err := retry(ctx, b, "CreateDataBagItem", false, func() (struct{}, error) {
+		return struct{}{}, b.Backend.CreateDataBagItem(ctx, bag, item)
+	})
+	return err
+}
+
+// UpdateDataBagItem implements Backend.
+func (b *retryBackend) UpdateDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error {
+	_, err := retry(ctx, b, "UpdateDataBagItem", true, func() (struct{}, error) {
+		return struct{}{}, b.Backend.UpdateDataBagItem(ctx, bag, id, item)
+	})
+	return err
+}
+
+// DeleteDataBagItem implements Backend.
+func (b *retryBackend) DeleteDataBagItem(ctx context.Context, bag, id string) error {
+	return retryDelete(ctx, b, "DeleteDataBagItem", func() error {
+		return b.Backend.DeleteDataBagItem(ctx, bag, id)
+	})
+}
+
+// putDataBagItem creates a data bag item or, if it exists, updates it. Unlike a lone create, the pair is
+// repeated after any transient failure: a repeated create that conflicts means the item exists, whether or
+// not an earlier attempt created it, and the update then replaces it.
+func (b *retryBackend) putDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) error {
+	_, err := retry(ctx, b, "PutDataBagItem", true, func() (struct{}, error) {
+		return struct{}{}, putDataBagItem(ctx, b.Backend, bag, id, item)
+	})
+	return err
+}
+
+// UserKey implements Backend.
+func (b *retryBackend) UserKey(ctx context.Context, name string) (chef.AccessKey, error) {
+	return retry(ctx, b, "UserKey", true, func() (chef.AccessKey, error) {
+		return b.Backend.UserKey(ctx, name)
+	})
+}
+
+// ClientKey implements Backend.
+func (b *retryBackend) ClientKey(ctx context.Context, name string) (chef.AccessKey, error) {
+	return retry(ctx, b, "ClientKey", true, func() (chef.AccessKey, error) {
+		return b.Backend.ClientKey(ctx, name)
+	})
+}
+
+// ClientExists implements Backend.
+func (b *retryBackend) ClientExists(ctx context.Context, name string) (bool, error) {
+	return retry(ctx, b, "ClientExists", true, func() (bool, error) {
+		return b.Backend.ClientExists(ctx, name)
+	})
+}
+
+// PartialSearch implements Backend.
+func (b *retryBackend) PartialSearch(ctx context.Context, index, query string, start int, fields map[string]interface{}) (*SearchResult, error) {
+	return retry(ctx, b, "PartialSearch", true, func() (*SearchResult, error) {
+		return b.Backend.PartialSearch(ctx, index, query, start, fields)
+	})
+}
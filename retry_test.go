@@ -0,0 +1,156 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item_keys"
+	"github.com/stretchr/testify/require"
+)
+
+// statusError returns a Chef error with status and the given Retry-After header.
+func statusError(status int, retryAfter string) error {
+	err := cheferr.New(status, http.MethodPut, "data/vault1/secret1").(*chef.ErrorResponse)
+	if retryAfter != "" {
+		err.Response.Header = http.Header{"Retry-After": []string{retryAfter}}
+	}
+	return err
+}
+
+// newRetryService returns a Service over a memory backend that records the delays it sleeps for.
+func newRetryService(t *testing.T, policy *RetryPolicy) (*Service, *memoryBackend, *[]time.Duration, *recordingMetrics) {
+	t.Helper()
+
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+	var slept []time.Duration
+	policy.sleep = func(d time.Duration) { slept = append(slept, d) }
+	svc.Retry = policy
+	rec := &recordingMetrics{}
+	svc.Metrics = rec
+	return svc, backend, &slept, rec
+}
+
+func TestRetry_SparseWrite(t *testing.T) {
+	svc, backend, slept, rec := newRetryService(t, &RetryPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second})
+
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+	})
+	require.NoError(t, err)
+
+	// rewriting an existing item in sparse mode updates its keys items.
+	backend.failNext("UpdateDataBagItem",
+		statusError(http.StatusBadGateway, ""),
+		statusError(http.StatusBadGateway, ""),
+	)
+	sparse := item_keys.KeysModeSparse
+	_, err = svc.Update(&Payload{VaultName: "vault1", VaultItemName: "secret1", KeysMode: &sparse})
+	require.NoError(t, err)
+
+	require.Len(t, *slept, 2)
+	require.GreaterOrEqual(t, (*slept)[0], 50*time.Millisecond)
+	require.LessOrEqual(t, (*slept)[0], 100*time.Millisecond)
+	require.GreaterOrEqual(t, (*slept)[1], 100*time.Millisecond)
+	require.LessOrEqual(t, (*slept)[1], 200*time.Millisecond)
+	require.Equal(t, []string{
+		"UpdateDataBagItem:" + cheferr.ClassServerError,
+		"UpdateDataBagItem:" + cheferr.ClassServerError,
+	}, rec.retries)
+
+	got, err := svc.GetItem("vault1", "secret1")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", got.(map[string]interface{})["password"])
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	svc, backend, slept, _ := newRetryService(t, &RetryPolicy{MaxDelay: 5 * time.Second})
+
+	backend.failNext("CreateDataBagItem", statusError(http.StatusTooManyRequests, "3"))
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{3 * time.Second}, *slept)
+
+	// a delay beyond MaxDelay is not waited out.
+	backend.failNext("UpdateDataBagItem", statusError(http.StatusServiceUnavailable, "60"))
+	_, err = svc.Update(&Payload{VaultName: "vault1", VaultItemName: "secret1"})
+	require.Error(t, err)
+	require.Len(t, *slept, 1)
+}
+
+func TestRetry_NotRetried(t *testing.T) {
+	svc, backend, slept, _ := newRetryService(t, &RetryPolicy{MaxAttempts: 3})
+
+	// creates are not repeated after a failure the server may have acted on.
+	backend.failNext("CreateDataBagItem", statusError(http.StatusBadGateway, ""))
+	err := svc.backend().CreateDataBagItem(context.Background(), "vault1", map[string]interface{}{"id": "secret1"})
+	require.Error(t, err)
+	require.Empty(t, *slept)
+
+	// errors that are not transient are returned at once.
+	_, err = svc.GetItem("vault1", "missing")
+	require.True(t, cheferr.IsNotFound(err))
+	require.Empty(t, *slept)
+
+	// attempts run out.
+	backend.failNext("UpdateDataBagItem",
+		statusError(http.StatusGatewayTimeout, ""),
+		statusError(http.StatusGatewayTimeout, ""),
+		statusError(http.StatusGatewayTimeout, ""),
+	)
+	err = svc.backend().UpdateDataBagItem(context.Background(), "vault1", "secret1", map[string]interface{}{"id": "secret1"})
+	require.Equal(t, http.StatusGatewayTimeout, err.(*chef.ErrorResponse).Response.StatusCode)
+	require.Len(t, *slept, 2)
+}
+
+func TestRetry_DeleteLostResponse(t *testing.T) {
+	svc, backend, slept, _ := newRetryService(t, &RetryPolicy{})
+	require.NoError(t, backend.CreateDataBag(context.Background(), "vault1"))
+	require.NoError(t, backend.CreateDataBagItem(context.Background(), "vault1", map[string]interface{}{"id": "secret1_key_node1"}))
+
+	backend.loseNext("DeleteDataBagItem", statusError(http.StatusBadGateway, ""))
+	require.NoError(t, svc.backend().DeleteDataBagItem(context.Background(), "vault1", "secret1_key_node1"))
+	require.Len(t, *slept, 1)
+}
+
+func TestRetry_PutAsUnit(t *testing.T) {
+	svc, backend, slept, rec := newRetryService(t, &RetryPolicy{})
+	require.NoError(t, backend.CreateDataBag(context.Background(), "vault1"))
+
+	// an earlier attempt created the item, but its response was lost.
+	require.NoError(t, backend.CreateDataBagItem(context.Background(), "vault1", map[string]interface{}{"id": "secret1_keys", "admins": []string{}}))
+	backend.failNext("CreateDataBagItem", statusError(http.StatusBadGateway, ""))
+
+	keys := map[string]interface{}{"id": "secret1_keys", "admins": []string{userid}}
+	require.NoError(t, putDataBagItem(context.Background(), svc.backend(), "vault1", "secret1_keys", keys))
+	require.Len(t, *slept, 1)
+	require.Equal(t, []string{"PutDataBagItem:" + cheferr.ClassServerError}, rec.retries)
+
+	stored, err := backend.GetDataBagItem(context.Background(), "vault1", "secret1_keys")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{userid}, stored.(map[string]interface{})["admins"])
+}
+
+func TestRetry_Context(t *testing.T) {
+	svc, backend, _, _ := newRetryService(t, &RetryPolicy{InitialDelay: time.Hour, MaxDelay: time.Hour})
+	svc.Retry.sleep = nil
+
+	backend.failNext("UpdateDataBagItem", statusError(http.StatusBadGateway, ""))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	err := svc.backend().UpdateDataBagItem(ctx, "vault1", "secret1", map[string]interface{}{"id": "secret1"})
+	require.ErrorIs(t, err, context.Canceled)
+}
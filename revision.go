@@ -0,0 +1,169 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-chef/chef"
+)
+
+// revisionReadAttempts bounds how often GetItemWithRevision re-reads an item that changes while it is being read.
+const revisionReadAttempts = 3
+
+// ConflictError is returned when a vault item changed between the revision a write was based on and the write itself.
+type ConflictError struct {
+	VaultName     string
+	VaultItemName string
+	Expected      string
+	Actual        string
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("vault: %s/%s changed since revision %s (now %s)", e.VaultName, e.VaultItemName, e.Expected, e.Actual)
+}
+
+// Is reports whether the target is ErrConflict so callers can use errors.Is.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Revision returns an opaque token identifying the current stored state of a vault item. It changes
+// whenever the encrypted item or its keys item is rewritten, and may be passed in Payload.Revision
+// to make a later write fail with ErrConflict if the item changed in the meantime.
+func (s *Service) Revision(vaultName, vaultItem string) (revision string, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.revision", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return "", err
+	}
+
+	return s.itemRevision(ctx, pl.VaultName, pl.VaultItemName)
+}
+
+// GetItemWithRevision returns the decrypted vault item together with the revision it was read at.
+func (s *Service) GetItemWithRevision(vaultName, vaultItem string) (content chef.DataBagItem, revision string, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.get_item_with_revision", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	return s.getItemWithRevision(ctx, vaultName, vaultItem)
+}
+
+// getItemWithRevision reads a vault item and its revision under ctx.
+func (s *Service) getItemWithRevision(ctx context.Context, vaultName, vaultItem string) (chef.DataBagItem, string, error) {
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, "", err
+	}
+
+	var err error
+	for attempt := 0; attempt < revisionReadAttempts; attempt++ {
+		var before, after string
+		var content chef.DataBagItem
+
+		before, err = s.itemRevision(ctx, pl.VaultName, pl.VaultItemName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		content, err = s.GetItemContext(ctx, pl.VaultName, pl.VaultItemName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		after, err = s.itemRevision(ctx, pl.VaultName, pl.VaultItemName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if before == after {
+			return content, after, nil
+		}
+		err = &ConflictError{VaultName: pl.VaultName, VaultItemName: pl.VaultItemName, Expected: before, Actual: after}
+	}
+
+	return nil, "", err
+}
+
+// expectedRevision returns the revision a write must still observe: the caller supplied revision, or
+// the current revision when the caller did not supply one.
+func (s *Service) expectedRevision(ctx context.Context, payload *Payload) (string, error) {
+	if payload.Revision != "" {
+		return payload.Revision, nil
+	}
+	return s.itemRevision(ctx, payload.VaultName, payload.VaultItemName)
+}
+
+// checkRevision returns a *ConflictError when the stored item no longer matches the expected revision.
+func (s *Service) checkRevision(ctx context.Context, vaultName, vaultItem, expected string) error {
+	actual, err := s.itemRevision(ctx, vaultName, vaultItem)
+	if err != nil {
+		return err
+	}
+
+	if actual != expected {
+		return &ConflictError{
+			VaultName:     vaultName,
+			VaultItemName: vaultItem,
+			Expected:      expected,
+			Actual:        actual,
+		}
+	}
+	return nil
+}
+
+// itemRevision hashes the raw encrypted item and keys item as stored on the Chef server.
+func (s *Service) itemRevision(ctx context.Context, vaultName, vaultItem string) (string, error) {
+	rawItem, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem)
+	if err != nil {
+		return "", err
+	}
+
+	rawKeys, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem+"_keys")
+	if err != nil {
+		return "", err
+	}
+
+	return revisionOf(rawItem, rawKeys)
+}
+
+// revisionOf computes the revision token for a raw encrypted item and keys item.
+func revisionOf(rawItem, rawKeys chef.DataBagItem) (string, error) {
+	h := sha256.New()
+	for _, raw := range []chef.DataBagItem{rawItem, rawKeys} {
+		// encoding/json sorts map keys, so equal items always hash the same.
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return "", err
+		}
+		h.Write(b)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// withConflictRetry runs op and, while it fails with ErrConflict, re-runs it against the latest stored
+// state up to payload.ConflictRetries times. Each retry re-reads and re-merges the current content.
+func withConflictRetry[T any](payload *Payload, op func(*Payload) (T, error)) (T, error) {
+	res, err := op(payload)
+	for attempt := 0; attempt < payload.ConflictRetries && errors.Is(err, ErrConflict); attempt++ {
+		retry := *payload
+		retry.Revision = ""
+		res, err = op(&retry)
+	}
+	return res, err
+}
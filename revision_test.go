@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionOf_StableAndSensitive(t *testing.T) {
+	a, err := revisionOf(map[string]interface{}{"id": "secret1", "foo": "x"}, map[string]interface{}{"id": "secret1_keys"})
+	require.NoError(t, err)
+
+	b, err := revisionOf(map[string]interface{}{"foo": "x", "id": "secret1"}, map[string]interface{}{"id": "secret1_keys"})
+	require.NoError(t, err)
+	require.Equal(t, a, b)
+
+	c, err := revisionOf(map[string]interface{}{"id": "secret1", "foo": "y"}, map[string]interface{}{"id": "secret1_keys"})
+	require.NoError(t, err)
+	require.NotEqual(t, a, c)
+}
+
+func TestService_Revision(t *testing.T) {
+	setupStubs(t)
+
+	rev, err := service.Revision("vault1", "secret1")
+	require.NoError(t, err)
+	require.Len(t, rev, 64)
+
+	require.NoError(t, service.checkRevision(context.Background(), "vault1", "secret1", rev))
+
+	err = service.checkRevision(context.Background(), "vault1", "secret1", "stale")
+	require.ErrorIs(t, err, ErrConflict)
+
+	var cerr *ConflictError
+	require.ErrorAs(t, err, &cerr)
+	require.Equal(t, "stale", cerr.Expected)
+	require.Equal(t, rev, cerr.Actual)
+}
+
+func TestUpdate_StaleRevisionConflicts(t *testing.T) {
+	setupStubs(t)
+
+	rec := &updateRecorder{}
+
+	_, err := service.update(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Revision:      "stale",
+	}, rec.ops())
+	require.ErrorIs(t, err, ErrConflict)
+	require.Equal(t, []string{"resolveUpdateContent"}, rec.calls)
+}
+
+func TestRemove_StaleRevisionConflicts(t *testing.T) {
+	setupStubs(t)
+
+	rec := &removeRecorder{}
+
+	_, err := service.remove(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Clients:       []string{"testhost"},
+		Revision:      "stale",
+	}, rec.ops())
+	require.ErrorIs(t, err, ErrConflict)
+	require.Empty(t, rec.calls)
+}
+
+func TestWithConflictRetry(t *testing.T) {
+	var seen []string
+	op := func(p *Payload) (int, error) {
+		seen = append(seen, p.Revision)
+		if len(seen) < 3 {
+			return 0, &ConflictError{}
+		}
+		return len(seen), nil
+	}
+
+	_, err := withConflictRetry(&Payload{Revision: "r1", ConflictRetries: 1}, op)
+	require.ErrorIs(t, err, ErrConflict)
+	require.Equal(t, []string{"r1", ""}, seen)
+
+	seen = nil
+	got, err := withConflictRetry(&Payload{Revision: "r1", ConflictRetries: 5}, op)
+	require.NoError(t, err)
+	require.Equal(t, 3, got)
+
+	seen = nil
+	_, err = withConflictRetry(&Payload{ConflictRetries: 5}, func(p *Payload) (int, error) {
+		seen = append(seen, p.Revision)
+		return 0, errors.New("boom")
+	})
+	require.EqualError(t, err, "boom")
+	require.Len(t, seen, 1)
+}
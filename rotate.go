@@ -1,7 +1,9 @@
 package vault
 
 import (
+	"context"
 	"maps"
+	"time"
 
 	"github.com/go-chef/chef"
 	"github.com/justintsteele/go-chef-vault/item"
@@ -16,8 +18,8 @@ type RotateResponse struct {
 
 // rotateOps defines the callable operations required to execute a RotateKeys request.
 type rotateOps struct {
-	getItem     func(string, string) (chef.DataBagItem, error)
-	updateVault func(*Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
+	getItem     func(context.Context, string, string) (chef.DataBagItem, error)
+	updateVault func(context.Context, *Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
 }
 
 // RotateKeys rotates the shared secret for a vault item by generating a new secret,
@@ -26,20 +28,38 @@ type rotateOps struct {
 // References:
 //   - Chef-vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_rotate_keys.rb
 func (s *Service) RotateKeys(payload *Payload) (*RotateResponse, error) {
+	return s.RotateKeysContext(context.Background(), payload)
+}
+
+// RotateKeysContext is RotateKeys with requests made under ctx.
+func (s *Service) RotateKeysContext(ctx context.Context, payload *Payload) (resp *RotateResponse, err error) {
+	defer s.observe(OpRotate, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpRotate, payload.traceAttributes()...)
+	defer sp.end(&err)
+
 	if err := payload.validatePayload(); err != nil {
 		return nil, err
 	}
 
 	ops := rotateOps{
-		getItem:     s.GetItem,
+		getItem:     s.GetItemContext,
 		updateVault: s.updateVault,
 	}
-	return s.rotateKeys(payload, ops)
+	return withItemLock(ctx, s, payload, func(ctx context.Context) (*RotateResponse, error) {
+		return withAudit(ctx, s, OpRotate, payload.VaultName, payload.VaultItemName, func() (*RotateResponse, error) {
+			return s.rotateKeys(ctx, payload, ops)
+		})
+	})
 }
 
 // rotateKeys is the worker called by the public API with the operational methods to complete a RotateKeys request.
-func (s *Service) rotateKeys(payload *Payload, ops rotateOps) (*RotateResponse, error) {
-	keyState, err := s.loadKeysCurrentState(payload)
+func (s *Service) rotateKeys(ctx context.Context, payload *Payload, ops rotateOps) (*RotateResponse, error) {
+	revision, err := s.expectedRevision(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	keyState, err := s.loadKeysCurrentState(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +73,7 @@ func (s *Service) rotateKeys(payload *Payload, ops rotateOps) (*RotateResponse,
 		Keys:        maps.Clone(keyState.Keys),
 	}
 
-	currentItem, err := ops.getItem(payload.VaultName, payload.VaultItemName)
+	currentItem, err := ops.getItem(ctx, payload.VaultName, payload.VaultItemName)
 	if err != nil {
 		return nil, err
 	}
@@ -79,15 +99,21 @@ func (s *Service) rotateKeys(payload *Payload, ops rotateOps) (*RotateResponse,
 		KeysMode:      &keyState.Mode,
 	}
 
-	searchedClients, err := s.getClientsFromSearch(rotatePayload)
+	searchedClients, err := s.getClientsFromSearch(ctx, rotatePayload)
 	if err != nil {
 		return nil, err
 	}
 
 	normalizedClients := item_keys.MergeClients(searchedClients, nextState.Clients)
+	s.logger().Debug("rotate clients resolved", "vault", payload.VaultName, "item", payload.VaultItemName,
+		"searched", len(searchedClients), "clients", len(normalizedClients))
+
+	if err := s.checkRevision(ctx, payload.VaultName, payload.VaultItemName, revision); err != nil {
+		return nil, err
+	}
 
 	if payload.CleanUnknown {
-		normalizedClients, _, err = s.cleanUnknownClients(payload, nextState, normalizedClients)
+		normalizedClients, _, err = s.cleanUnknownClients(ctx, payload, nextState, normalizedClients)
 		if err != nil {
 			return nil, err
 		}
@@ -95,7 +121,7 @@ func (s *Service) rotateKeys(payload *Payload, ops rotateOps) (*RotateResponse,
 
 	rotatePayload.Clients = normalizedClients
 
-	keysResult, err := ops.updateVault(rotatePayload, modeState)
+	keysResult, err := ops.updateVault(ctx, rotatePayload, modeState)
 	if err != nil {
 		return nil, err
 	}
@@ -114,14 +140,21 @@ func (s *Service) rotateKeys(payload *Payload, ops rotateOps) (*RotateResponse,
 // References:
 //   - Chef-vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_rotate_all_keys.rb
 func (s *Service) RotateAllKeys() ([]RotateResponse, error) {
-	vaults, err := s.List()
+	return s.RotateAllKeysContext(context.Background())
+}
+
+// RotateAllKeysContext is RotateAllKeys with requests made under ctx.
+func (s *Service) RotateAllKeysContext(ctx context.Context) (res []RotateResponse, err error) {
+	ctx, sp := s.startSpan(ctx, "vault.rotate_all")
+	defer sp.end(&err)
+
+	vaults, err := s.ListContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var res []RotateResponse
 	for vault := range *vaults {
-		vaultItems, err := s.ListItems(vault)
+		vaultItems, err := s.ListItemsContext(ctx, vault)
 		if err != nil {
 			return nil, err
 		}
@@ -131,13 +164,14 @@ func (s *Service) RotateAllKeys() ([]RotateResponse, error) {
 				VaultItemName: vaultItem,
 			}
 
-			result, err := s.RotateKeys(rotatePayload)
+			result, err := s.RotateKeysContext(ctx, rotatePayload)
 			if err != nil {
 				return nil, err
 			}
 			res = append(res, *result)
 		}
 	}
+	sp.set(Attribute{"items", len(res)})
 
 	return res, nil
 }
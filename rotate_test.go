@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -19,7 +20,7 @@ type rotateRecorder struct {
 
 func (r *rotateRecorder) ops() rotateOps {
 	return rotateOps{
-		getItem: func(_, _ string) (chef.DataBagItem, error) {
+		getItem: func(_ context.Context, _, _ string) (chef.DataBagItem, error) {
 			r.calls = append(r.calls, "getItem")
 			type data chef.DataBagItem
 			var current data
@@ -36,7 +37,7 @@ func (r *rotateRecorder) ops() rotateOps {
 			}
 			return current, nil
 		},
-		updateVault: func(payload *Payload, state *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
+		updateVault: func(_ context.Context, payload *Payload, state *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
 			r.calls = append(r.calls, "updateVault")
 			r.wrote.rotatePayload = payload
 			r.wrote.modeState = state
@@ -52,7 +53,7 @@ func TestRotateKeys_DefaultPayload(t *testing.T) {
 
 	rec := rotateRecorder{}
 
-	_, err := service.rotateKeys(&Payload{
+	_, err := service.rotateKeys(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 	}, rec.ops())
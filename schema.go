@@ -0,0 +1,219 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/justintsteele/go-chef-vault/schema"
+)
+
+// schemaItemSuffix is appended to an item name to form the id of its plaintext schema companion item.
+// The vault-wide schema uses the suffix alone as its id.
+const schemaItemSuffix = "_schema"
+
+// ValidateResponse represents the structure of the response from a Validate operation.
+type ValidateResponse struct {
+	Response
+	SchemaURI  string             `json:"schema_uri"`
+	Valid      bool               `json:"valid"`
+	Violations []schema.Violation `json:"violations,omitempty"`
+}
+
+// SetSchema stores a JSON Schema as a plaintext companion item of a vault item. When vaultItem is
+// empty, the schema is stored as the vault-wide default used by items without a schema of their own.
+func (s *Service) SetSchema(vaultName, vaultItem string, doc map[string]interface{}) (resp *Response, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.set_schema", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	if vaultName == "" {
+		return nil, ErrMissingVaultName
+	}
+
+	if _, err := schema.Compile(doc); err != nil {
+		return nil, err
+	}
+
+	id := vaultItem + schemaItemSuffix
+	schemaItem := map[string]interface{}{
+		"id":     id,
+		"schema": doc,
+	}
+
+	if err := putDataBagItem(ctx, s.backend(), vaultName, id, &schemaItem); err != nil {
+		return nil, err
+	}
+
+	return &Response{URI: fmt.Sprintf("%s/%s", s.vaultURL(vaultName), id)}, nil
+}
+
+// GetSchema returns the JSON Schema that applies to a vault item, preferring the item's own
+// schema over the vault-wide default. ErrSchemaNotFound is returned when neither exists.
+func (s *Service) GetSchema(vaultName, vaultItem string) (doc map[string]interface{}, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.get_schema", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	doc, _, err = s.loadSchema(ctx, pl.VaultName, pl.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrSchemaNotFound
+	}
+	return doc, nil
+}
+
+// Validate checks the current content of a vault item against its schema without modifying it.
+func (s *Service) Validate(vaultName, vaultItem string) (resp *ValidateResponse, err error) {
+	ctx, sp := s.startSpan(context.Background(), "vault.validate", Attribute{"vault", vaultName}, Attribute{"item", vaultItem})
+	defer sp.end(&err)
+
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	doc, uri, err := s.loadSchema(ctx, pl.VaultName, pl.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrSchemaNotFound
+	}
+
+	current, err := s.GetItemContext(ctx, pl.VaultName, pl.VaultItemName)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := item.DataBagItemMap(current)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ValidateResponse{
+		Response: Response{
+			URI: fmt.Sprintf("%s/%s", s.vaultURL(pl.VaultName), pl.VaultItemName),
+		},
+		SchemaURI: uri,
+		Valid:     true,
+	}
+
+	violations, err := checkSchema(doc, content)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) != 0 {
+		result.Valid = false
+		result.Violations = violations
+	}
+
+	return result, nil
+}
+
+// validateContent checks content against Payload.Schema, or the stored schema when none is supplied,
+// returning an error wrapping ErrSchemaValidation when the content does not conform.
+func (s *Service) validateContent(ctx context.Context, payload *Payload, content map[string]interface{}) error {
+	doc := payload.Schema
+	if doc == nil {
+		var err error
+		doc, _, err = s.loadSchema(ctx, payload.VaultName, payload.VaultItemName)
+		if err != nil {
+			return err
+		}
+	}
+	if doc == nil {
+		return nil
+	}
+
+	violations, err := checkSchema(doc, content)
+	if err != nil {
+		return err
+	}
+	if len(violations) != 0 {
+		return fmt.Errorf("%w: %w", ErrSchemaValidation, &schema.ValidationError{Violations: violations})
+	}
+	return nil
+}
+
+// loadSchema fetches the item schema, falling back to the vault-wide schema. A nil document is
+// returned when neither companion item exists.
+func (s *Service) loadSchema(ctx context.Context, vaultName, vaultItem string) (map[string]interface{}, string, error) {
+	for _, id := range []string{vaultItem + schemaItemSuffix, schemaItemSuffix} {
+		raw, err := s.backend().GetDataBagItem(ctx, vaultName, id)
+		if err != nil {
+			if cheferr.IsNotFound(err) {
+				continue
+			}
+			return nil, "", err
+		}
+
+		dbi, err := item.DataBagItemMap(raw)
+		if err != nil {
+			return nil, "", err
+		}
+
+		doc, ok := dbi["schema"].(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("%s/%s does not contain a schema object", vaultName, id)
+		}
+		return doc, fmt.Sprintf("%s/%s", s.vaultURL(vaultName), id), nil
+	}
+	return nil, "", nil
+}
+
+// checkSchema compiles doc and validates content against it, ignoring the item id.
+func checkSchema(doc, content map[string]interface{}) ([]schema.Violation, error) {
+	compiled, err := schema.Compile(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, err := normalizeContent(content)
+	if err != nil {
+		return nil, err
+	}
+	delete(normalized, "id")
+
+	if err := compiled.Validate(normalized); err != nil {
+		verr, ok := err.(*schema.ValidationError)
+		if !ok {
+			return nil, err
+		}
+		return verr.Violations, nil
+	}
+	return nil, nil
+}
+
+// normalizeContent round-trips content through JSON so Go values supplied by callers
+// (ints, structs, typed slices) are validated the same way as decrypted content.
+func normalizeContent(content map[string]interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = make(map[string]interface{})
+	}
+	return out, nil
+}
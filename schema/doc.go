@@ -0,0 +1,10 @@
+// Package schema implements a JSON Schema validator for decrypted vault item content.
+//
+// It supports the subset of JSON Schema (draft 7) keywords that is useful for
+// describing secret shapes: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, allOf,
+// anyOf, oneOf and not. The annotations $schema, $id, $comment, title,
+// description, default and examples are accepted and ignored; a schema using any
+// other keyword, such as $ref, format or patternProperties, fails to compile.
+package schema
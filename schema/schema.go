@@ -0,0 +1,462 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/justintsteele/go-chef-vault/item"
+)
+
+// ErrInvalidSchema is returned when a schema document cannot be compiled.
+var ErrInvalidSchema = errors.New("schema: invalid schema")
+
+// Schema is a compiled JSON Schema node.
+type Schema struct {
+	raw map[string]interface{}
+
+	types                []string
+	enum                 []interface{}
+	constValue           interface{}
+	hasConst             bool
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	noAdditional         bool
+	items                *Schema
+	minItems             *int
+	maxItems             *int
+	minLength            *int
+	maxLength            *int
+	pattern              *regexp.Regexp
+	minimum              *float64
+	maximum              *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	allOf                []*Schema
+	anyOf                []*Schema
+	oneOf                []*Schema
+	not                  *Schema
+}
+
+// Violation describes a single place where a document does not satisfy a schema.
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// String renders the violation as "path: message".
+func (v Violation) String() string {
+	p := v.Path
+	if p == "" {
+		p = "/"
+	}
+	return p + ": " + v.Message
+}
+
+// ValidationError is returned when a document does not satisfy a schema.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.String())
+	}
+	return "schema: validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Compile parses a decoded JSON Schema document. Keywords the package does not support are rejected
+// with ErrInvalidSchema rather than ignored, so a schema never silently validates less than it says.
+func Compile(raw map[string]interface{}) (*Schema, error) {
+	return compile(raw, "")
+}
+
+// Raw returns the schema document the Schema was compiled from.
+func (s *Schema) Raw() map[string]interface{} {
+	return s.raw
+}
+
+// compile builds a Schema from raw, using at to report the location of schema errors.
+func compile(raw map[string]interface{}, at string) (*Schema, error) {
+	s := &Schema{raw: raw}
+
+	invalid := func(keyword, msg string) error {
+		return fmt.Errorf("%w: %s/%s %s", ErrInvalidSchema, at, keyword, msg)
+	}
+
+	for keyword, val := range raw {
+		switch keyword {
+		case "type":
+			switch t := val.(type) {
+			case string:
+				s.types = []string{t}
+			case []interface{}:
+				for _, v := range t {
+					str, ok := v.(string)
+					if !ok {
+						return nil, invalid(keyword, "must be a string or array of strings")
+					}
+					s.types = append(s.types, str)
+				}
+			default:
+				return nil, invalid(keyword, "must be a string or array of strings")
+			}
+		case "enum":
+			arr, ok := val.([]interface{})
+			if !ok {
+				return nil, invalid(keyword, "must be an array")
+			}
+			s.enum = arr
+		case "const":
+			s.constValue = val
+			s.hasConst = true
+		case "properties":
+			props, ok := val.(map[string]interface{})
+			if !ok {
+				return nil, invalid(keyword, "must be an object")
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, sub := range props {
+				compiled, err := compileSub(sub, at+"/properties/"+name)
+				if err != nil {
+					return nil, err
+				}
+				s.properties[name] = compiled
+			}
+		case "required":
+			arr, ok := val.([]interface{})
+			if !ok {
+				return nil, invalid(keyword, "must be an array of strings")
+			}
+			for _, v := range arr {
+				str, ok := v.(string)
+				if !ok {
+					return nil, invalid(keyword, "must be an array of strings")
+				}
+				s.required = append(s.required, str)
+			}
+		case "additionalProperties":
+			if b, ok := val.(bool); ok {
+				s.noAdditional = !b
+				continue
+			}
+			compiled, err := compileSub(val, at+"/additionalProperties")
+			if err != nil {
+				return nil, err
+			}
+			s.additionalProperties = compiled
+		case "items":
+			compiled, err := compileSub(val, at+"/items")
+			if err != nil {
+				return nil, err
+			}
+			s.items = compiled
+		case "minItems", "maxItems", "minLength", "maxLength":
+			n, ok := toInt(val)
+			if !ok {
+				return nil, invalid(keyword, "must be a non-negative integer")
+			}
+			switch keyword {
+			case "minItems":
+				s.minItems = &n
+			case "maxItems":
+				s.maxItems = &n
+			case "minLength":
+				s.minLength = &n
+			case "maxLength":
+				s.maxLength = &n
+			}
+		case "pattern":
+			str, ok := val.(string)
+			if !ok {
+				return nil, invalid(keyword, "must be a string")
+			}
+			re, err := regexp.Compile(str)
+			if err != nil {
+				return nil, invalid(keyword, err.Error())
+			}
+			s.pattern = re
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			f, ok := val.(float64)
+			if !ok {
+				return nil, invalid(keyword, "must be a number")
+			}
+			switch keyword {
+			case "minimum":
+				s.minimum = &f
+			case "maximum":
+				s.maximum = &f
+			case "exclusiveMinimum":
+				s.exclusiveMinimum = &f
+			case "exclusiveMaximum":
+				s.exclusiveMaximum = &f
+			}
+		case "allOf", "anyOf", "oneOf":
+			arr, ok := val.([]interface{})
+			if !ok || len(arr) == 0 {
+				return nil, invalid(keyword, "must be a non-empty array of schemas")
+			}
+			subs := make([]*Schema, 0, len(arr))
+			for i, sub := range arr {
+				compiled, err := compileSub(sub, fmt.Sprintf("%s/%s/%d", at, keyword, i))
+				if err != nil {
+					return nil, err
+				}
+				subs = append(subs, compiled)
+			}
+			switch keyword {
+			case "allOf":
+				s.allOf = subs
+			case "anyOf":
+				s.anyOf = subs
+			case "oneOf":
+				s.oneOf = subs
+			}
+		case "not":
+			compiled, err := compileSub(val, at+"/not")
+			if err != nil {
+				return nil, err
+			}
+			s.not = compiled
+		case "$schema", "$id", "$comment", "title", "description", "default", "examples":
+			// annotations do not affect validation.
+		default:
+			return nil, invalid(keyword, "is not supported")
+		}
+	}
+
+	return s, nil
+}
+
+// compileSub compiles a nested schema, which may be an object or a boolean schema.
+func compileSub(val interface{}, at string) (*Schema, error) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return compile(v, at)
+	case bool:
+		if v {
+			return &Schema{raw: map[string]interface{}{}}, nil
+		}
+		return &Schema{raw: map[string]interface{}{"not": map[string]interface{}{}}, not: &Schema{}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s must be a schema", ErrInvalidSchema, at)
+	}
+}
+
+// toInt converts a decoded JSON number into a non-negative int.
+func toInt(v interface{}) (int, bool) {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// Validate checks doc against the schema and returns a *ValidationError describing every violation.
+func (s *Schema) Validate(doc interface{}) error {
+	violations := s.validate(doc, "")
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// validate returns the violations of doc, located at the JSON Pointer at.
+func (s *Schema) validate(doc interface{}, at string) []Violation {
+	var out []Violation
+	fail := func(format string, args ...interface{}) {
+		out = append(out, Violation{Path: at, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) != 0 && !matchesAnyType(doc, s.types) {
+		fail("expected %s, got %s", strings.Join(s.types, " or "), typeOf(doc))
+		return out
+	}
+
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if equal(e, doc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value is not one of the allowed values")
+		}
+	}
+
+	if s.hasConst && !equal(s.constValue, doc) {
+		fail("value does not match the required constant")
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		out = append(out, s.validateObject(v, at)...)
+	case []interface{}:
+		out = append(out, s.validateArray(v, at)...)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			fail("length %d is less than %d", n, *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("length %d is greater than %d", n, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("does not match pattern %q", s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("%v is less than %v", v, *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("%v is greater than %v", v, *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			fail("%v must be greater than %v", v, *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			fail("%v must be less than %v", v, *s.exclusiveMaximum)
+		}
+	}
+
+	for _, sub := range s.allOf {
+		out = append(out, sub.validate(doc, at)...)
+	}
+
+	if s.anyOf != nil {
+		matched := false
+		for _, sub := range s.anyOf {
+			if len(sub.validate(doc, at)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("does not match any of the anyOf schemas")
+		}
+	}
+
+	if s.oneOf != nil {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if len(sub.validate(doc, at)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("matches %d of the oneOf schemas, expected exactly 1", matched)
+		}
+	}
+
+	if s.not != nil && len(s.not.validate(doc, at)) == 0 {
+		fail("must not match the not schema")
+	}
+
+	return out
+}
+
+// validateObject applies the object keywords to v.
+func (s *Schema) validateObject(v map[string]interface{}, at string) []Violation {
+	var out []Violation
+
+	for _, name := range s.required {
+		if _, ok := v[name]; !ok {
+			out = append(out, Violation{Path: at, Message: fmt.Sprintf("missing required property %q", name)})
+		}
+	}
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		child := at + "/" + item.EscapeToken(k)
+		if sub, ok := s.properties[k]; ok {
+			out = append(out, sub.validate(v[k], child)...)
+			continue
+		}
+		if s.noAdditional {
+			out = append(out, Violation{Path: child, Message: "additional property is not allowed"})
+			continue
+		}
+		if s.additionalProperties != nil {
+			out = append(out, s.additionalProperties.validate(v[k], child)...)
+		}
+	}
+
+	return out
+}
+
+// validateArray applies the array keywords to v.
+func (s *Schema) validateArray(v []interface{}, at string) []Violation {
+	var out []Violation
+
+	if s.minItems != nil && len(v) < *s.minItems {
+		out = append(out, Violation{Path: at, Message: fmt.Sprintf("has %d items, expected at least %d", len(v), *s.minItems)})
+	}
+	if s.maxItems != nil && len(v) > *s.maxItems {
+		out = append(out, Violation{Path: at, Message: fmt.Sprintf("has %d items, expected at most %d", len(v), *s.maxItems)})
+	}
+
+	if s.items != nil {
+		for i, el := range v {
+			out = append(out, s.items.validate(el, fmt.Sprintf("%s/%d", at, i))...)
+		}
+	}
+
+	return out
+}
+
+// matchesAnyType reports whether v is an instance of one of the JSON Schema types.
+func matchesAnyType(v interface{}, types []string) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON Schema type name of a decoded JSON value.
+func typeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if t == math.Trunc(t) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// equal compares two decoded JSON values.
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const dbSchema = `{
+	"type": "object",
+	"required": ["db"],
+	"properties": {
+		"db": {
+			"type": "object",
+			"required": ["host", "user", "password"],
+			"additionalProperties": false,
+			"properties": {
+				"host": {"type": "string", "minLength": 1},
+				"user": {"type": "string"},
+				"password": {"type": "string", "pattern": "^.{8,}$"},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+			}
+		},
+		"tags": {"type": "array", "items": {"enum": ["prod", "dev"]}, "maxItems": 2}
+	}
+}`
+
+func TestValidate_Valid(t *testing.T) {
+	s := mustCompile(t, dbSchema)
+
+	err := s.Validate(decode(t, `{"db": {"host": "db1", "user": "app", "password": "longenough", "port": 5432}, "tags": ["prod"]}`))
+	require.NoError(t, err)
+}
+
+func TestValidate_Violations(t *testing.T) {
+	s := mustCompile(t, dbSchema)
+
+	err := s.Validate(decode(t, `{"db": {"hots": "db1", "user": "app", "password": "short", "port": 0.5}, "tags": ["prod", "qa", "dev"]}`))
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.ElementsMatch(t, []Violation{
+		{Path: "/db", Message: `missing required property "host"`},
+		{Path: "/db/hots", Message: "additional property is not allowed"},
+		{Path: "/db/password", Message: `does not match pattern "^.{8,}$"`},
+		{Path: "/db/port", Message: "expected integer, got number"},
+		{Path: "/tags", Message: "has 3 items, expected at most 2"},
+		{Path: "/tags/1", Message: "value is not one of the allowed values"},
+	}, verr.Violations)
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	s := mustCompile(t, `{"oneOf": [{"type": "string"}, {"type": "number"}], "not": {"const": "forbidden"}}`)
+
+	require.NoError(t, s.Validate("ok"))
+	require.NoError(t, s.Validate(float64(3)))
+	require.Error(t, s.Validate(true))
+	require.Error(t, s.Validate("forbidden"))
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		`{"type": 1}`,
+		`{"pattern": "("}`,
+		`{"minLength": -1}`,
+		`{"properties": {"a": 1}}`,
+		`{"anyOf": []}`,
+		`{"$ref": "#/definitions/db"}`,
+		`{"type": "string", "format": "email"}`,
+		`{"properties": {"a": {"uniqueItems": true}}}`,
+		`{"if": {}, "then": {}, "else": {}}`,
+		`{"minProperties": 1}`,
+	} {
+		_, err := Compile(decode(t, raw).(map[string]interface{}))
+		require.ErrorIs(t, err, ErrInvalidSchema, raw)
+	}
+}
+
+func TestCompile_Annotations(t *testing.T) {
+	s := mustCompile(t, `{"$schema": "http://json-schema.org/draft-07/schema#", "title": "db", "description": "credentials", "type": "object"}`)
+	require.NoError(t, s.Validate(map[string]interface{}{}))
+}
+
+func mustCompile(t *testing.T, raw string) *Schema {
+	t.Helper()
+
+	s, err := Compile(decode(t, raw).(map[string]interface{}))
+	require.NoError(t, err)
+	return s
+}
+
+func decode(t *testing.T, raw string) interface{} {
+	t.Helper()
+
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &v))
+	return v
+}
@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/schema"
+	"github.com/stretchr/testify/require"
+)
+
+const secretSchema = `{
+	"id": "secret1_schema",
+	"schema": {
+		"type": "object",
+		"required": ["foo", "bar"],
+		"properties": {
+			"foo": {"type": "string"},
+			"bar": {"type": "string"}
+		}
+	}
+}`
+
+func TestCreate_SchemaFromPayloadRejects(t *testing.T) {
+	setupStubs(t)
+
+	rec := &createRecorder{}
+
+	_, err := service.create(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"foo": 1},
+		Schema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"foo", "bar"},
+		},
+	}, rec.ops())
+
+	require.ErrorIs(t, err, ErrSchemaValidation)
+	var verr *schema.ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Len(t, verr.Violations, 1)
+	require.Empty(t, rec.calls)
+}
+
+func TestUpdate_StoredSchemaRejects(t *testing.T) {
+	setupStubs(t)
+	stubSchema(t)
+
+	rec := &updateRecorder{}
+	rec.content = map[string]interface{}{"id": "secret1", "foo": "foo-value-1", "bar": 2}
+
+	_, err := service.update(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+	}, rec.ops())
+
+	require.ErrorIs(t, err, ErrSchemaValidation)
+	require.Equal(t, []string{"resolveUpdateContent"}, rec.calls)
+}
+
+func TestUpdate_StoredSchemaAccepts(t *testing.T) {
+	setupStubs(t)
+	stubSchema(t)
+
+	rec := &updateRecorder{}
+
+	_, err := service.update(context.Background(), &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+	}, rec.ops())
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"resolveUpdateContent", "updateVault"}, rec.calls)
+}
+
+func TestService_GetSchema(t *testing.T) {
+	setupStubs(t)
+
+	_, err := service.GetSchema("vault1", "secret1")
+	require.ErrorIs(t, err, ErrSchemaNotFound)
+
+	stubSchema(t)
+
+	doc, err := service.GetSchema("vault1", "secret1")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"foo", "bar"}, doc["required"])
+}
+
+func TestCheckSchema_IgnoresId(t *testing.T) {
+	doc := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"foo": map[string]interface{}{"type": "integer"},
+		},
+	}
+
+	violations, err := checkSchema(doc, map[string]interface{}{"id": "secret1", "foo": 3})
+	require.NoError(t, err)
+	require.Empty(t, violations)
+}
+
+func TestService_SetSchemaRejectsInvalid(t *testing.T) {
+	svc := &Service{Client: &chef.Client{}}
+
+	_, err := svc.SetSchema("vault1", "secret1", map[string]interface{}{"pattern": "("})
+	require.ErrorIs(t, err, schema.ErrInvalidSchema)
+}
+
+func stubSchema(t *testing.T) {
+	t.Helper()
+
+	mux.HandleFunc("/data/vault1/secret1_schema", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, secretSchema)
+	})
+}
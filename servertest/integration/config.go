@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/profile"
 )
 
 type Target int
@@ -86,21 +87,11 @@ func LoadConfig() Config {
 }
 
 func (c *Config) loadKnifeConfig() (*chef.Config, error) {
-	data, err := os.ReadFile(c.Knife)
+	p, err := profile.Load(&profile.Options{ConfigFile: c.Knife})
 	if err != nil {
-		panic(err)
-	}
-
-	clientRb, err := chef.NewClientRb(string(data), c.WorkDir)
-	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return &chef.Config{
-		Name:                  clientRb.NodeName,
-		Key:                   clientRb.ClientKey,
-		BaseURL:               clientRb.ChefServerUrl + "/",
-		AuthenticationVersion: "1.0",
-	}, nil
+	return p.ChefConfig(), nil
 }
 
 func (c *Config) mustCreateClient() *chef.Client {
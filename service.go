@@ -1,11 +1,14 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/go-chef/chef"
 	"github.com/justintsteele/go-chef-vault/item"
@@ -14,7 +17,42 @@ import (
 
 // Service provides Vault operations backed by a Chef Server client.
 type Service struct {
+	// Client is the go-chef client of a Service created by NewService. It is used only when Backend is nil.
 	Client *chef.Client
+
+	// Backend serves the Chef Server API the Service runs against. When nil, the Service uses a
+	// ChefBackend wrapping Client.
+	Backend Backend
+
+	// HistoryLimit is the number of previous versions retained for each vault item written by this Service.
+	// Zero leaves items without history untouched; items that already carry history keep their stored limit.
+	HistoryLimit int
+
+	// LockTTL enables advisory locking when positive. Update, Remove, Refresh, RotateKeys, Rollback and
+	// DeleteItem then hold the item's lock for the duration of the operation, renewing it every LockTTL/3.
+	LockTTL time.Duration
+
+	// Cache, when set, caches decrypted vault items read by GetItem. Writes made through the Service
+	// invalidate the items they touch.
+	Cache *ItemCache
+
+	// Metrics, when set, receives measurements of operations and of the Chef API requests they make.
+	Metrics Metrics
+
+	// Logger, when set, receives a debug or info record per step of an operation, such as each search
+	// page fetched and keys item written. Records name vaults, items and actors but never secrets.
+	Logger *slog.Logger
+
+	// AuditSink, when set, receives an AuditEvent for each vault change made through the Service.
+	AuditSink AuditSink
+
+	// Retry, when set, repeats Chef API requests that fail with a transient error.
+	Retry *RetryPolicy
+
+	// Tracer, when set, opens a span per operation, with child spans per Chef API request, search page
+	// and cryptographic step. Operations taking a context, such as CreateContext, open their span as a
+	// child of the span in it.
+	Tracer Tracer
 }
 
 // Response represents the basic structure of a response from a Vault operation.
@@ -30,8 +68,45 @@ type clientSearchResult struct {
 // NewService returns a Service configured with the given Chef client.
 func NewService(client *chef.Client) *Service {
 	return &Service{
-		Client: client,
+		Client:  client,
+		Backend: NewChefBackend(client),
+	}
+}
+
+// NewServiceWithBackend returns a Service running against backend instead of a go-chef client.
+func NewServiceWithBackend(backend Backend) *Service {
+	return &Service{
+		Backend: backend,
+	}
+}
+
+// backend returns the Backend the Service runs against.
+func (s *Service) backend() Backend {
+	b := s.Backend
+	if b == nil {
+		b = NewChefBackend(s.Client)
+	}
+	if s.Metrics != nil {
+		b = &metricsBackend{Backend: b, metrics: s.Metrics}
 	}
+	if s.Tracer != nil {
+		b = &tracingBackend{Backend: b, tracer: s.Tracer}
+	}
+	if s.Retry != nil {
+		b = &retryBackend{Backend: b, svc: s, policy: s.Retry}
+	}
+	if s.Cache != nil {
+		return &cacheBackend{Backend: b, cache: s.Cache}
+	}
+	return b
+}
+
+// logger returns the Logger the Service writes to, which discards records when Logger is nil.
+func (s *Service) logger() *slog.Logger {
+	if s.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return s.Logger
 }
 
 // vaultURL constructs the canonical URL for a vault resource.
@@ -40,15 +115,15 @@ func (s *Service) vaultURL(vaultName string) string {
 		Path: path.Join("data", vaultName),
 	}
 
-	return s.Client.BaseURL.ResolveReference(ref).String()
+	return s.backend().BaseURL().ResolveReference(ref).String()
 }
 
 // bagIsVault returns bool of whether the specified data bag a vault.
 //
 // References:
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_base.rb#L51
-func (s *Service) bagIsVault(bagName string) (bool, error) {
-	rawItems, err := s.Client.DataBags.ListItems(bagName)
+func (s *Service) bagIsVault(ctx context.Context, bagName string) (bool, error) {
+	rawItems, err := s.backend().ListDataBagItems(ctx, bagName)
 	if err != nil {
 		return false, err
 	}
@@ -66,8 +141,8 @@ func (s *Service) bagIsVault(bagName string) (bool, error) {
 }
 
 // bagItemIsEncrypted determines whether the data bag item contains the encrypted_data key of an encrypted data bag.
-func (s *Service) bagItemIsEncrypted(vaultName, vaultItem string) (bool, error) {
-	dbi, err := s.Client.DataBags.GetItem(vaultName, vaultItem)
+func (s *Service) bagItemIsEncrypted(ctx context.Context, vaultName, vaultItem string) (bool, error) {
+	dbi, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem)
 	if err != nil {
 		return false, err
 	}
@@ -96,14 +171,14 @@ func (s *Service) bagItemIsEncrypted(vaultName, vaultItem string) (bool, error)
 }
 
 // getClientsFromSearch returns the names of clients matching the search query.
-func (s *Service) getClientsFromSearch(payload *Payload) ([]string, error) {
+func (s *Service) getClientsFromSearch(ctx context.Context, payload *Payload) ([]string, error) {
 	if payload.SearchQuery == nil {
 		return nil, nil
 	}
 
 	plan := item_keys.BuildClientSearchPlan(payload.SearchQuery)
 
-	rows, err := s.executeClientSearch(plan)
+	rows, err := s.executeClientSearch(ctx, plan)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +193,7 @@ func (s *Service) getClientsFromSearch(payload *Payload) ([]string, error) {
 }
 
 // executeClientSearch executes a client search plan against the Chef Server and returns the raw results.
-func (s *Service) executeClientSearch(plan *item_keys.ClientSearchPlan) ([]clientSearchResult, error) {
+func (s *Service) executeClientSearch(ctx context.Context, plan *item_keys.ClientSearchPlan) ([]clientSearchResult, error) {
 	if plan == nil {
 		return nil, nil
 	}
@@ -126,17 +201,12 @@ func (s *Service) executeClientSearch(plan *item_keys.ClientSearchPlan) ([]clien
 	start := 0
 	var allResults []clientSearchResult
 	for {
-		query, err := s.Client.Search.NewQuery(plan.Index, plan.Query)
-		if err != nil {
-			return nil, err
-		}
-
-		query.Start = start
-
-		result, err := query.DoPartialJSON(s.Client, plan.Fields)
+		result, err := s.searchPage(ctx, plan, start)
 		if err != nil {
 			return nil, err
 		}
+		s.metrics().ObserveSearchPage(plan.Index, len(result.Rows))
+		s.logger().Debug("search page fetched", "index", plan.Index, "query", plan.Query, "start", start, "rows", len(result.Rows), "total", result.Total)
 
 		if len(result.Rows) == 0 {
 			break
@@ -144,24 +214,37 @@ func (s *Service) executeClientSearch(plan *item_keys.ClientSearchPlan) ([]clien
 
 		for _, row := range result.Rows {
 			var r clientSearchResult
-			if err := json.Unmarshal(row.Data, &r); err != nil {
+			if err := json.Unmarshal(row, &r); err != nil {
 				return nil, err
 			}
 			allResults = append(allResults, r)
 		}
 
-		if len(result.Rows) < query.Rows {
+		start += len(result.Rows)
+		if start >= result.Total {
 			break
 		}
-		start += query.Rows
 	}
 
 	return allResults, nil
 }
 
+// searchPage fetches the page of results of a client search plan starting at start.
+func (s *Service) searchPage(ctx context.Context, plan *item_keys.ClientSearchPlan, start int) (result *SearchResult, err error) {
+	ctx, sp := s.startSpan(ctx, "vault.search_page", Attribute{"index", plan.Index}, Attribute{"start", start})
+	defer sp.end(&err)
+
+	result, err = s.backend().PartialSearch(ctx, plan.Index, plan.Query, start, plan.Fields)
+	if err != nil {
+		return nil, err
+	}
+	sp.set(Attribute{"rows", len(result.Rows)}, Attribute{"total", result.Total})
+	return result, nil
+}
+
 // loadActorKey retrieves the encrypted shared key for the specified actor.
-func (s *Service) loadActorKey(vaultName, vaultItem string) (string, error) {
-	rawKeys, err := s.Client.DataBags.GetItem(vaultName, vaultItem+"_keys")
+func (s *Service) loadActorKey(ctx context.Context, vaultName, vaultItem string) (string, error) {
+	rawKeys, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem+"_keys")
 	if err != nil {
 		return "", err
 	}
@@ -171,12 +254,12 @@ func (s *Service) loadActorKey(vaultName, vaultItem string) (string, error) {
 		return "", err
 	}
 
-	var actor = s.Client.Auth.ClientName
+	var actor = s.backend().ActorName()
 	var actorKey interface{}
 	actorKey, ok := keysMap[actor]
 	if !ok {
 		// not in default key, trying sparse keys
-		rawSparseKey, err := s.Client.DataBags.GetItem(vaultName, vaultItem+"_key_"+actor)
+		rawSparseKey, err := s.backend().GetDataBagItem(ctx, vaultName, vaultItem+"_key_"+actor)
 		if err != nil {
 			return "", fmt.Errorf("%s/%s is not encrypted with your public key", vaultName, vaultItem)
 		}
@@ -199,13 +282,15 @@ func (s *Service) loadActorKey(vaultName, vaultItem string) (string, error) {
 }
 
 // loadSharedSecret decrypts and returns the vault shared secret using the current actor's private key and encrypted shared key.
-func (s *Service) loadSharedSecret(payload *Payload) ([]byte, error) {
-	actorKey, err := s.loadActorKey(payload.VaultName, payload.VaultItemName)
+func (s *Service) loadSharedSecret(ctx context.Context, payload *Payload) (secret []byte, err error) {
+	actorKey, err := s.loadActorKey(ctx, payload.VaultName, payload.VaultItemName)
 	if err != nil {
 		return nil, err
 	}
 
-	secret, err := item_keys.DecryptSharedSecret(actorKey, s.Client.Auth.PrivateKey)
+	_, sp := s.startSpan(ctx, "vault.decrypt_secret")
+	defer sp.end(&err)
+	secret, err = item_keys.DecryptSharedSecret(actorKey, s.backend().PrivateKey())
 
 	if err != nil {
 		return nil, fmt.Errorf("unable to decrypt shared secret with available credentials")
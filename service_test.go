@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"net/url"
 	"testing"
 
@@ -22,7 +23,7 @@ func TestService_getClientsFromSearch_NoQuery(t *testing.T) {
 	svc := &Service{}
 	payload := &Payload{SearchQuery: nil}
 
-	clients, err := svc.getClientsFromSearch(payload)
+	clients, err := svc.getClientsFromSearch(context.Background(), payload)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -36,7 +37,7 @@ func TestGetClientsFromSearch_WithMatchingQuery(t *testing.T) {
 	setupStubs(t)
 
 	query := "name:testhost*"
-	clients, err := service.getClientsFromSearch(&Payload{SearchQuery: &query})
+	clients, err := service.getClientsFromSearch(context.Background(), &Payload{SearchQuery: &query})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -46,7 +47,7 @@ func TestGetClientsFromSearch_WithMatchingQuery(t *testing.T) {
 func TestGetClientsFromSearch_NoQueryReturnsEmpty(t *testing.T) {
 	setupStubs(t)
 
-	clients, err := service.getClientsFromSearch(&Payload{})
+	clients, err := service.getClientsFromSearch(context.Background(), &Payload{})
 	require.NoError(t, err)
 	require.Empty(t, clients)
 }
@@ -0,0 +1,125 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ShutdownTimeout is how long Serve waits for requests in flight once its context is done.
+const ShutdownTimeout = 5 * time.Second
+
+// peerUIDKey is the context key of the user id of a Unix socket peer.
+type peerUIDKey struct{}
+
+// unixConnKey is the context key marking requests that arrived on a Unix socket.
+type unixConnKey struct{}
+
+// ListenUnix listens on a Unix socket at path with permission perm. A stale socket left at path by
+// a previous run is replaced; any other file there is an error.
+func ListenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("sidecar: %s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// ListenLocal listens on a TCP address, which must be a loopback address such as 127.0.0.1:8200 or
+// [::1]:8200, so that secrets are never served off the host.
+func ListenLocal(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "localhost" {
+		return net.Listen("tcp", addr)
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return nil, fmt.Errorf("sidecar: %s is not a loopback address", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Serve serves requests on ln until ctx is done, then shuts down gracefully. It returns nil after a
+// shutdown caused by ctx.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			if _, ok := c.(*net.UnixConn); !ok {
+				return ctx
+			}
+			ctx = context.WithValue(ctx, unixConnKey{}, true)
+			if uid, ok := connUID(c); ok {
+				ctx = context.WithValue(ctx, peerUIDKey{}, uid)
+			}
+			return ctx
+		},
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// peerUID returns the user id of the Unix socket peer a request arrived from.
+func peerUID(ctx context.Context) (int, bool) {
+	uid, ok := ctx.Value(peerUIDKey{}).(int)
+	return uid, ok
+}
+
+// onUnixSocket reports whether a request arrived on a Unix socket served by Serve.
+func onUnixSocket(ctx context.Context) bool {
+	on, _ := ctx.Value(unixConnKey{}).(bool)
+	return on
+}
+
+// loopbackHost reports whether the Host of a request names the loopback interface, as a loopback
+// address literal or localhost, so that pages on other sites cannot reach a TCP listener through a
+// DNS name rebound to 127.0.0.1.
+func loopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
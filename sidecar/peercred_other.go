@@ -0,0 +1,11 @@
+//go:build !linux
+
+package sidecar
+
+import "net"
+
+// connUID reports no peer user id: peer credentials are only read on Linux, so elsewhere callers
+// must identify themselves with a token.
+func connUID(net.Conn) (int, bool) {
+	return 0, false
+}
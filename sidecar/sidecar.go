@@ -0,0 +1,358 @@
+// Package sidecar serves decrypted vault items to local applications over HTTP.
+//
+// A Server holds the node's Chef identity and answers read-only requests on a Unix socket or a
+// loopback address:
+//
+//	GET /v1/VAULT/ITEM          the whole item, less its id, as JSON
+//	GET /v1/VAULT/ITEM/PATH     the value at PATH, a JSON Pointer such as conn/host
+//	GET /v1/health              liveness, without authentication
+//
+// Adding ?format=raw serves a string value as plain text. Each request is made on behalf of a
+// Caller, identified by a bearer token or, on a Unix socket, by the peer's user id, and may only
+// read the items its allowlist names. Requests arriving over TCP must present a token and name a
+// loopback Host. Items are cached for Options.CacheTTL, and every request is logged without the
+// values served.
+package sidecar
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+)
+
+// DefaultCacheTTL is how long an item is served from the cache when Options.CacheTTL is zero.
+const DefaultCacheTTL = time.Minute
+
+// ErrNoCallers is returned by New when no Caller is configured.
+var ErrNoCallers = errors.New("sidecar: no callers configured")
+
+// Source reads decrypted vault items. *vault.Service satisfies it.
+type Source interface {
+	GetItem(vaultName, vaultItem string) (chef.DataBagItem, error)
+}
+
+// Caller is an application allowed to read secrets through the sidecar.
+//
+// A request is made by the caller whose Token it presents as "Authorization: Bearer TOKEN". A
+// request without a token is only accepted on a Unix socket, where it is made by the caller listing
+// the peer's user id in UIDs, or else by the caller with neither a Token nor UIDs, if one is
+// configured.
+type Caller struct {
+	// Name identifies the caller in the access log.
+	Name string `json:"name"`
+
+	// Token is the caller's bearer token.
+	Token string `json:"token,omitempty"`
+
+	// UIDs are the user ids of processes connecting as this caller over a Unix socket.
+	UIDs []int `json:"uids,omitempty"`
+
+	// Allow lists the items the caller may read as VAULT/ITEM patterns, in which * matches any
+	// vault or item name, as in "database/*" or "*/*".
+	Allow []string `json:"allow"`
+}
+
+// Options configures a Server.
+type Options struct {
+	// Source reads the vault items served. Required.
+	Source Source
+
+	// Callers are the applications allowed to read secrets. At least one is required.
+	Callers []Caller
+
+	// CacheTTL is how long an item read from Source is served from memory. Defaults to
+	// DefaultCacheTTL; a negative value disables caching.
+	CacheTTL time.Duration
+
+	// Logger receives an access record per request. Defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// Server is an http.Handler serving vault items. The zero value is not usable; create servers with New.
+type Server struct {
+	source  Source
+	callers []Caller
+	ttl     time.Duration
+	logger  *slog.Logger
+	now     func() time.Time
+
+	mu    sync.Mutex
+	items map[string]cachedItem
+}
+
+// cachedItem is a vault item read from the Source.
+type cachedItem struct {
+	content map[string]interface{}
+	expires time.Time
+}
+
+// New returns a Server reading vault items from opts.Source.
+func New(opts *Options) (*Server, error) {
+	if opts == nil || opts.Source == nil {
+		return nil, errors.New("sidecar: a source is required")
+	}
+	if len(opts.Callers) == 0 {
+		return nil, ErrNoCallers
+	}
+
+	for i, c := range opts.Callers {
+		if c.Name == "" {
+			return nil, fmt.Errorf("sidecar: caller %d has no name", i)
+		}
+		for _, pattern := range c.Allow {
+			if _, err := path.Match(pattern, ""); err != nil || strings.Count(pattern, "/") != 1 {
+				return nil, fmt.Errorf("sidecar: caller %s: invalid allow pattern %q", c.Name, pattern)
+			}
+		}
+	}
+
+	s := &Server{
+		source:  opts.Source,
+		callers: opts.Callers,
+		ttl:     opts.CacheTTL,
+		logger:  opts.Logger,
+		now:     time.Now,
+		items:   make(map[string]cachedItem),
+	}
+	if s.ttl == 0 {
+		s.ttl = DefaultCacheTTL
+	}
+	if s.logger == nil {
+		s.logger = slog.Default()
+	}
+	return s, nil
+}
+
+// Purge empties the item cache, so that the next request for each item reads it from the Source.
+func (s *Server) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]cachedItem)
+}
+
+// request is the state of a request recorded in the access log.
+type request struct {
+	caller string
+	vault  string
+	item   string
+	path   string
+	status int
+	cached bool
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := s.now()
+	req := &request{}
+	s.serve(w, r, req)
+
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("url", r.URL.Path),
+		slog.Int("status", req.status),
+		slog.Duration("duration", s.now().Sub(start)),
+	}
+	if req.caller != "" {
+		attrs = append(attrs, slog.String("caller", req.caller))
+	}
+	if req.vault != "" {
+		attrs = append(attrs, slog.String("vault", req.vault), slog.String("item", req.item), slog.Bool("cached", req.cached))
+	}
+	if req.path != "" {
+		attrs = append(attrs, slog.String("path", req.path))
+	}
+	s.logger.LogAttrs(r.Context(), slog.LevelInfo, "access", attrs...)
+}
+
+// serve answers a request, recording its outcome in req.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, req *request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.writeError(w, req, http.StatusMethodNotAllowed, "the sidecar is read-only")
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v1/")
+	if !ok {
+		s.writeError(w, req, http.StatusNotFound, "not found")
+		return
+	}
+	if rest == "health" {
+		s.writeJSON(w, req, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+
+	if !onUnixSocket(r.Context()) && !loopbackHost(r.Host) {
+		s.writeError(w, req, http.StatusForbidden, fmt.Sprintf("host %q is not a loopback address", r.Host))
+		return
+	}
+
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		s.writeError(w, req, http.StatusNotFound, "not found")
+		return
+	}
+	req.vault, req.item = parts[0], parts[1]
+	if len(parts) == 3 && parts[2] != "" {
+		req.path = parts[2]
+	}
+
+	caller := s.authenticate(r)
+	if caller == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		s.writeError(w, req, http.StatusUnauthorized, "unknown caller")
+		return
+	}
+	req.caller = caller.Name
+
+	if !allowed(caller, req.vault, req.item) {
+		s.writeError(w, req, http.StatusForbidden, fmt.Sprintf("%s may not read %s/%s", caller.Name, req.vault, req.item))
+		return
+	}
+
+	content, cached, err := s.getItem(req.vault, req.item)
+	req.cached = cached
+	if err != nil {
+		if cheferr.IsNotFound(err) {
+			s.writeError(w, req, http.StatusNotFound, fmt.Sprintf("%s/%s not found", req.vault, req.item))
+			return
+		}
+		s.logger.ErrorContext(r.Context(), "reading vault item failed", "vault", req.vault, "item", req.item, "error", err)
+		s.writeError(w, req, http.StatusBadGateway, fmt.Sprintf("unable to read %s/%s", req.vault, req.item))
+		return
+	}
+
+	var value interface{} = content
+	if req.path != "" {
+		p, err := item.ParsePath("/" + req.path)
+		if err != nil {
+			s.writeError(w, req, http.StatusBadRequest, err.Error())
+			return
+		}
+		if value, ok = item.Lookup(content, p); !ok {
+			s.writeError(w, req, http.StatusNotFound, fmt.Sprintf("%s/%s has no value at %s", req.vault, req.item, req.path))
+			return
+		}
+	}
+
+	if str, ok := value.(string); ok && r.URL.Query().Get("format") == "raw" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		req.status = http.StatusOK
+		w.WriteHeader(req.status)
+		_, _ = w.Write([]byte(str))
+		return
+	}
+	s.writeJSON(w, req, http.StatusOK, value)
+}
+
+// authenticate returns the caller making r, or nil when it matches none.
+func (s *Server) authenticate(r *http.Request) *Caller {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok || token == "" {
+			return nil
+		}
+		for i := range s.callers {
+			c := &s.callers[i]
+			if c.Token != "" && subtle.ConstantTimeCompare([]byte(c.Token), []byte(token)) == 1 {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// over TCP any local process, or a page rebinding a name to the loopback address, can connect.
+	if !onUnixSocket(r.Context()) {
+		return nil
+	}
+
+	if uid, ok := peerUID(r.Context()); ok {
+		for i := range s.callers {
+			for _, id := range s.callers[i].UIDs {
+				if id == uid {
+					return &s.callers[i]
+				}
+			}
+		}
+	}
+
+	for i := range s.callers {
+		if s.callers[i].Token == "" && len(s.callers[i].UIDs) == 0 {
+			return &s.callers[i]
+		}
+	}
+	return nil
+}
+
+// allowed reports whether c may read vaultName/vaultItem.
+func allowed(c *Caller, vaultName, vaultItem string) bool {
+	for _, pattern := range c.Allow {
+		if ok, _ := path.Match(pattern, vaultName+"/"+vaultItem); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getItem returns the content of a vault item, less its id, from the cache or the Source. It
+// reports whether the content came from the cache.
+func (s *Server) getItem(vaultName, vaultItem string) (map[string]interface{}, bool, error) {
+	key := vaultName + "/" + vaultItem
+
+	s.mu.Lock()
+	cached, ok := s.items[key]
+	s.mu.Unlock()
+	if ok && s.now().Before(cached.expires) {
+		return cached.content, true, nil
+	}
+
+	raw, err := s.source.GetItem(vaultName, vaultItem)
+	if err != nil {
+		return nil, false, err
+	}
+	content, err := item.DataBagItemMap(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	content = item.DeepCopy(content)
+	delete(content, "id")
+
+	if s.ttl > 0 {
+		s.mu.Lock()
+		s.items[key] = cachedItem{content: content, expires: s.now().Add(s.ttl)}
+		s.mu.Unlock()
+	}
+	return content, false, nil
+}
+
+// writeJSON writes v as the JSON response body.
+func (s *Server) writeJSON(w http.ResponseWriter, req *request, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body = []byte(`{"error":"unable to encode response"}`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	req.status = status
+	w.WriteHeader(status)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// writeError writes an error response.
+func (s *Server) writeError(w http.ResponseWriter, req *request, status int, msg string) {
+	s.writeJSON(w, req, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,259 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/vaulttest"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer returns a sidecar acting as client web1, which can read database/postgres and
+// apps/web, and the access log it writes.
+func newTestServer(t *testing.T, callers []Caller) (*Server, *vaulttest.Server, *bytes.Buffer) {
+	t.Helper()
+
+	srv := vaulttest.NewServer(t)
+	srv.AddUser("admin")
+	srv.AddClient("web1")
+
+	admin := srv.Service("admin")
+	for name, content := range map[string]map[string]interface{}{
+		"database/postgres": {"password": "hunter2", "conn": map[string]interface{}{"host": "db1", "port": 5432}},
+		"apps/web":          {"token": "abc"},
+	} {
+		vaultName, vaultItem, _ := strings.Cut(name, "/")
+		_, err := admin.Create(&vault.Payload{
+			VaultName:     vaultName,
+			VaultItemName: vaultItem,
+			Content:       content,
+			Admins:        []string{"admin"},
+			Clients:       []string{"web1"},
+		})
+		require.NoError(t, err)
+	}
+
+	var logs bytes.Buffer
+	s, err := New(&Options{
+		Source:  srv.Service("web1"),
+		Callers: callers,
+		Logger:  slog.New(slog.NewJSONHandler(&logs, nil)),
+	})
+	require.NoError(t, err)
+	return s, srv, &logs
+}
+
+// get makes a request as it would arrive on a loopback TCP listener.
+func get(t *testing.T, h http.Handler, url, token string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Host = "127.0.0.1:8200"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return serveRequest(h, req)
+}
+
+// getUnix makes a request without a token as it would arrive on a Unix socket.
+func getUnix(t *testing.T, h http.Handler, url string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req = req.WithContext(context.WithValue(req.Context(), unixConnKey{}, true))
+	return serveRequest(h, req)
+}
+
+func serveRequest(h http.Handler, req *http.Request) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestServer_ServesItems(t *testing.T) {
+	s, srv, logs := newTestServer(t, []Caller{{Name: "app", Allow: []string{"database/*"}}})
+
+	status, body := getUnix(t, s, "/v1/database/postgres")
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"password":"hunter2","conn":{"host":"db1","port":5432}}`, body)
+
+	status, body = getUnix(t, s, "/v1/database/postgres/conn/host")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "\"db1\"\n", body)
+
+	status, body = getUnix(t, s, "/v1/database/postgres/password?format=raw")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "hunter2", body)
+
+	status, _ = getUnix(t, s, "/v1/database/postgres/conn/user")
+	require.Equal(t, http.StatusNotFound, status)
+
+	status, _ = getUnix(t, s, "/v1/database/mysql")
+	require.Equal(t, http.StatusNotFound, status)
+
+	// the item was read from the Chef server once, then served from the cache.
+	reads := 0
+	for _, r := range srv.Requests() {
+		if r == "GET /data/database/postgres" {
+			reads++
+		}
+	}
+	require.Equal(t, 1, reads)
+
+	require.Contains(t, logs.String(), `"caller":"app"`)
+	require.Contains(t, logs.String(), `"cached":true`)
+	require.NotContains(t, logs.String(), "hunter2")
+}
+
+func TestServer_Access(t *testing.T) {
+	s, _, _ := newTestServer(t, []Caller{
+		{Name: "web", Token: "web-token", Allow: []string{"apps/web"}},
+		{Name: "ops", Token: "ops-token", Allow: []string{"*/*"}},
+	})
+
+	status, _ := get(t, s, "/v1/apps/web", "web-token")
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = get(t, s, "/v1/database/postgres", "web-token")
+	require.Equal(t, http.StatusForbidden, status)
+
+	status, _ = get(t, s, "/v1/database/postgres", "ops-token")
+	require.Equal(t, http.StatusOK, status)
+
+	// without a token, or with an unknown one, no caller matches.
+	status, _ = get(t, s, "/v1/apps/web", "")
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, _ = get(t, s, "/v1/apps/web", "wrong")
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// a caller without a token is refused over TCP, even when one without a token is configured.
+	anon, _, _ := newTestServer(t, []Caller{{Name: "app", Allow: []string{"*/*"}}})
+	status, _ = get(t, anon, "/v1/apps/web", "")
+	require.Equal(t, http.StatusUnauthorized, status)
+	status, _ = getUnix(t, anon, "/v1/apps/web")
+	require.Equal(t, http.StatusOK, status)
+
+	// over TCP, a Host that is not a loopback address is refused, as a rebound DNS name would be.
+	req := httptest.NewRequest(http.MethodGet, "/v1/apps/web", nil)
+	req.Host = "attacker.example:8200"
+	req.Header.Set("Authorization", "Bearer web-token")
+	status, _ = serveRequest(s, req)
+	require.Equal(t, http.StatusForbidden, status)
+	for _, host := range []string{"localhost:8200", "[::1]:8200", "127.0.0.1"} {
+		req.Host = host
+		status, _ = serveRequest(s, req)
+		require.Equal(t, http.StatusOK, status, host)
+	}
+
+	// health needs no caller.
+	status, body := get(t, s, "/v1/health", "")
+	require.Equal(t, http.StatusOK, status)
+	require.JSONEq(t, `{"status":"ok"}`, body)
+
+	// the sidecar is read-only.
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1/apps/web", strings.NewReader(`{}`)))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+}
+
+func TestServer_CacheTTL(t *testing.T) {
+	s, srv, _ := newTestServer(t, []Caller{{Name: "app", Allow: []string{"*/*"}}})
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	status, _ := getUnix(t, s, "/v1/apps/web/token")
+	require.Equal(t, http.StatusOK, status)
+
+	_, err := srv.Service("admin").Update(&vault.Payload{
+		VaultName:     "apps",
+		VaultItemName: "web",
+		Content:       map[string]interface{}{"token": "def"},
+	})
+	require.NoError(t, err)
+
+	_, body := getUnix(t, s, "/v1/apps/web/token")
+	require.Equal(t, "\"abc\"\n", body)
+
+	now = now.Add(DefaultCacheTTL)
+	_, body = getUnix(t, s, "/v1/apps/web/token")
+	require.Equal(t, "\"def\"\n", body)
+}
+
+func TestServer_UnixSocketPeer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only read on Linux")
+	}
+
+	s, _, _ := newTestServer(t, []Caller{{Name: "local", UIDs: []int{os.Getuid()}, Allow: []string{"apps/*"}}})
+
+	dir, err := os.MkdirTemp("", "sidecar")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "s.sock")
+
+	ln, err := ListenUnix(sock, 0o600)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+
+	resp, err := client.Get("http://sidecar/v1/apps/web/token?format=raw")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "abc", string(body))
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestListen(t *testing.T) {
+	_, err := ListenLocal("0.0.0.0:0")
+	require.ErrorContains(t, err, "not a loopback address")
+
+	ln, err := ListenLocal("127.0.0.1:0")
+	require.NoError(t, err)
+	_ = ln.Close()
+
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	_, err = ListenUnix(file, 0o600)
+	require.ErrorContains(t, err, "not a socket")
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&Options{Source: &vault.Service{}})
+	require.ErrorIs(t, err, ErrNoCallers)
+
+	_, err = New(&Options{Source: &vault.Service{}, Callers: []Caller{{Name: "app", Allow: []string{"database"}}}})
+	require.ErrorContains(t, err, "invalid allow pattern")
+
+	var callers []Caller
+	require.NoError(t, json.Unmarshal([]byte(`[{"name":"app","uids":[1000],"allow":["apps/*"]}]`), &callers))
+	_, err = New(&Options{Source: &vault.Service{}, Callers: callers})
+	require.NoError(t, err)
+}
@@ -0,0 +1,180 @@
+package vault
+
+import (
+	"context"
+	"crypto/rsa"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+)
+
+// Attribute is a key/value pair describing a span. Values are strings, ints, bools or string slices.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Tracer opens spans for a Service whose Tracer is set. The otelvault package provides one backed by
+// OpenTelemetry. Implementations must be safe for concurrent use.
+type Tracer interface {
+	// Start opens a span named name as a child of the span in ctx, and returns a context carrying it.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is an operation in progress, opened by a Tracer.
+type Span interface {
+	// SetAttributes adds attrs to the span.
+	SetAttributes(attrs ...Attribute)
+
+	// End closes the span, recording err if the operation failed.
+	End(err error)
+}
+
+// span is a Span that may be nil, for Services without a Tracer.
+type span struct {
+	Span
+}
+
+// startSpan opens a span named name as a child of the span in ctx, and returns a context carrying it.
+func (s *Service) startSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, span) {
+	if s.Tracer == nil {
+		return ctx, span{}
+	}
+	ctx, sp := s.Tracer.Start(ctx, name, attrs...)
+	return ctx, span{sp}
+}
+
+// set adds attrs to the span.
+func (sp span) set(attrs ...Attribute) {
+	if sp.Span != nil {
+		sp.SetAttributes(attrs...)
+	}
+}
+
+// end closes the span with a result attribute: "ok", or the cheferr class of *err.
+func (sp span) end(err *error) {
+	if sp.Span == nil {
+		return
+	}
+	result := "ok"
+	if *err != nil {
+		result = cheferr.Classify(*err)
+	}
+	sp.SetAttributes(Attribute{"result", result})
+	sp.End(*err)
+}
+
+// traceAttributes describes the vault item a payload addresses.
+func (p *Payload) traceAttributes() []Attribute {
+	if p == nil {
+		return nil
+	}
+	attrs := []Attribute{{"vault", p.VaultName}, {"item", p.VaultItemName}}
+	if p.KeysMode != nil {
+		attrs = append(attrs, Attribute{"keys_mode", string(*p.KeysMode)})
+	}
+	return attrs
+}
+
+// traceGetOps wraps the decryption steps of ops in spans that are children of the span in ctx.
+func (s *Service) traceGetOps(ctx context.Context, ops getOps) getOps {
+	if s.Tracer == nil {
+		return ops
+	}
+	return getOps{
+		deriveAESKey: func(actorKey string, key *rsa.PrivateKey) (secret []byte, err error) {
+			_, sp := s.startSpan(ctx, "vault.decrypt_secret")
+			defer sp.end(&err)
+			return ops.deriveAESKey(actorKey, key)
+		},
+		decrypt: func(raw chef.DataBagItem, secret []byte) (content chef.DataBagItem, err error) {
+			_, sp := s.startSpan(ctx, "vault.decrypt_item")
+			defer sp.end(&err)
+			return ops.decrypt(raw, secret)
+		},
+	}
+}
+
+// tracingBackend is the Backend of a Service with a Tracer. It opens a span per request it makes.
+type tracingBackend struct {
+	Backend
+	tracer Tracer
+}
+
+// start opens a span for a request as a child of the span in ctx.
+func (b *tracingBackend) start(ctx context.Context, call string, attrs ...Attribute) span {
+	_, sp := b.tracer.Start(ctx, "chef."+call, attrs...)
+	return span{sp}
+}
+
+// ListDataBags implements Backend.
+func (b *tracingBackend) ListDataBags(ctx context.Context) (res *chef.DataBagListResult, err error) {
+	defer b.start(ctx, "ListDataBags").end(&err)
+	return b.Backend.ListDataBags(ctx)
+}
+
+// CreateDataBag implements Backend.
+func (b *tracingBackend) CreateDataBag(ctx context.Context, name string) (err error) {
+	defer b.start(ctx, "CreateDataBag", Attribute{"vault", name}).end(&err)
+	return b.Backend.CreateDataBag(ctx, name)
+}
+
+// DeleteDataBag implements Backend.
+func (b *tracingBackend) DeleteDataBag(ctx context.Context, name string) (err error) {
+	defer b.start(ctx, "DeleteDataBag", Attribute{"vault", name}).end(&err)
+	return b.Backend.DeleteDataBag(ctx, name)
+}
+
+// ListDataBagItems implements Backend.
+func (b *tracingBackend) ListDataBagItems(ctx context.Context, bag string) (res *chef.DataBagListResult, err error) {
+	defer b.start(ctx, "ListDataBagItems", Attribute{"vault", bag}).end(&err)
+	return b.Backend.ListDataBagItems(ctx, bag)
+}
+
+// GetDataBagItem implements Backend.
+func (b *tracingBackend) GetDataBagItem(ctx context.Context, bag, id string) (res chef.DataBagItem, err error) {
+	defer b.start(ctx, "GetDataBagItem", Attribute{"vault", bag}, Attribute{"item", id}).end(&err)
+	return b.Backend.GetDataBagItem(ctx, bag, id)
+}
+
+// CreateDataBagItem implements Backend.
+func (b *tracingBackend) CreateDataBagItem(ctx context.Context, bag string, item chef.DataBagItem) (err error) {
+	defer b.start(ctx, "CreateDataBagItem", Attribute{"vault", bag}).end(&err)
+	return b.Backend.CreateDataBagItem(ctx, bag, item)
+}
+
+// UpdateDataBagItem implements Backend.
+func (b *tracingBackend) UpdateDataBagItem(ctx context.Context, bag, id string, item chef.DataBagItem) (err error) {
+	defer b.start(ctx, "UpdateDataBagItem", Attribute{"vault", bag}, Attribute{"item", id}).end(&err)
+	return b.Backend.UpdateDataBagItem(ctx, bag, id, item)
+}
+
+// DeleteDataBagItem implements Backend.
+func (b *tracingBackend) DeleteDataBagItem(ctx context.Context, bag, id string) (err error) {
+	defer b.start(ctx, "DeleteDataBagItem", Attribute{"vault", bag}, Attribute{"item", id}).end(&err)
+	return b.Backend.DeleteDataBagItem(ctx, bag, id)
+}
+
+// UserKey implements Backend.
+func (b *tracingBackend) UserKey(ctx context.Context, name string) (res chef.AccessKey, err error) {
+	defer b.start(ctx, "UserKey", Attribute{"actor", name}).end(&err)
+	return b.Backend.UserKey(ctx, name)
+}
+
+// ClientKey implements Backend.
+func (b *tracingBackend) ClientKey(ctx context.Context, name string) (res chef.AccessKey, err error) {
+	defer b.start(ctx, "ClientKey", Attribute{"actor", name}).end(&err)
+	return b.Backend.ClientKey(ctx, name)
+}
+
+// ClientExists implements Backend.
+func (b *tracingBackend) ClientExists(ctx context.Context, name string) (res bool, err error) {
+	defer b.start(ctx, "ClientExists", Attribute{"actor", name}).end(&err)
+	return b.Backend.ClientExists(ctx, name)
+}
+
+// PartialSearch implements Backend.
+func (b *tracingBackend) PartialSearch(ctx context.Context, index, query string, start int, fields map[string]interface{}) (res *SearchResult, err error) {
+	defer b.start(ctx, "PartialSearch", Attribute{"index", index}, Attribute{"start", start}).end(&err)
+	return b.Backend.PartialSearch(ctx, index, query, start, fields)
+}
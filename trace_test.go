@@ -0,0 +1,158 @@
+package vault
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+// recordingTracer records the spans it opens.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  map[string]any
+	ended  bool
+	err    error
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sp := &recordedSpan{name: name, attrs: make(map[string]any)}
+	sp.parent, _ = ctx.Value(spanKey{}).(*recordedSpan)
+	sp.SetAttributes(attrs...)
+	t.spans = append(t.spans, sp)
+	return context.WithValue(ctx, spanKey{}, sp), sp
+}
+
+func (sp *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		sp.attrs[a.Key] = a.Value
+	}
+}
+
+func (sp *recordedSpan) End(err error) {
+	sp.ended = true
+	sp.err = err
+}
+
+// named returns the spans named name.
+func (t *recordingTracer) named(name string) []*recordedSpan {
+	var out []*recordedSpan
+	for _, sp := range t.spans {
+		if sp.name == name {
+			out = append(out, sp)
+		}
+	}
+	return out
+}
+
+func TestService_Tracer(t *testing.T) {
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+	tracer := &recordingTracer{}
+	svc.Tracer = tracer
+
+	ctx, root := tracer.Start(context.Background(), "deploy")
+	query := "name:*"
+	_, err := svc.CreateContext(ctx, &Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+		SearchQuery:   &query,
+	})
+	require.NoError(t, err)
+
+	create := tracer.named("vault.create")
+	require.Len(t, create, 1)
+	require.Same(t, root, create[0].parent)
+	require.Equal(t, "vault1", create[0].attrs["vault"])
+	require.Equal(t, "secret1", create[0].attrs["item"])
+	require.Equal(t, "ok", create[0].attrs["result"])
+
+	pages := tracer.named("vault.search_page")
+	require.Len(t, pages, 1)
+	require.Same(t, create[0], pages[0].parent)
+	require.Equal(t, 1, pages[0].attrs["rows"])
+
+	searches := tracer.named("chef.PartialSearch")
+	require.Len(t, searches, 1)
+	require.Same(t, pages[0], searches[0].parent)
+
+	secrets := tracer.named("vault.encrypt_secret")
+	require.Len(t, secrets, 1)
+	require.Equal(t, 2, secrets[0].attrs["actors"])
+	require.Equal(t, "default", secrets[0].attrs["keys_mode"])
+	require.Len(t, tracer.named("vault.encrypt_item"), 1)
+
+	_, err = svc.GetItem("vault1", "missing")
+	require.True(t, cheferr.IsNotFound(err))
+	get := tracer.named("vault.get")
+	require.Len(t, get, 1)
+	require.Nil(t, get[0].parent)
+	require.Equal(t, cheferr.ClassNotFound, get[0].attrs["result"])
+	require.Error(t, get[0].err)
+
+	for _, sp := range tracer.spans[1:] {
+		require.True(t, sp.ended, sp.name)
+	}
+}
+
+func TestService_TracerReadOperations(t *testing.T) {
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+	})
+	require.NoError(t, err)
+
+	tracer := &recordingTracer{}
+	svc.Tracer = tracer
+
+	_, err = svc.Diff("vault1", "secret1", map[string]interface{}{"password": "swordfish"}, nil)
+	require.NoError(t, err)
+
+	diff := tracer.named("vault.diff")
+	require.Len(t, diff, 1)
+	require.Nil(t, diff[0].parent)
+	require.Equal(t, "secret1", diff[0].attrs["item"])
+	require.Equal(t, "ok", diff[0].attrs["result"])
+
+	// the item read and its Chef requests are children of the Diff span.
+	get := tracer.named("vault.get")
+	require.Len(t, get, 1)
+	require.Same(t, diff[0], get[0].parent)
+	reads := tracer.named("chef.GetDataBagItem")
+	require.NotEmpty(t, reads)
+	for _, sp := range reads {
+		require.Same(t, get[0], sp.parent)
+	}
+
+	ok, err := svc.IsVault("vault1", "secret1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	isVault := tracer.named("vault.is_vault")
+	require.Len(t, isVault, 1)
+	require.Empty(t, tracer.named("vault.item_type"))
+	list := tracer.named("chef.ListDataBagItems")
+	require.Len(t, list, 1)
+	require.Same(t, isVault[0], list[0].parent)
+
+	for _, sp := range tracer.spans {
+		require.True(t, sp.ended, sp.name)
+	}
+}
@@ -1,7 +1,9 @@
 package vault
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/justintsteele/go-chef-vault/item"
 	"github.com/justintsteele/go-chef-vault/item_keys"
@@ -21,39 +23,83 @@ type UpdateDataResponse struct {
 
 // updateOps defines the callable operations required to execute an Update request.
 type updateOps struct {
-	resolveUpdateContent func(p *Payload) (map[string]interface{}, error)
-	updateVault          func(*Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
+	resolveUpdateContent func(context.Context, *Payload) (map[string]interface{}, error)
+	updateVault          func(context.Context, *Payload, *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error)
 }
 
 // Update modifies a vault item and its access keys on the Chef server.
 //
+// The merged content is validated against Payload.Schema, or the stored schema, before it is encrypted.
+// Payload.Files replace the files stored under the same keys.
+// The write fails with ErrConflict if the item changed since Payload.Revision, or since the update read it;
+// Payload.ConflictRetries re-runs the update against the latest content that many times.
+//
 // References:
 //   - Chef API Docs: https://docs.chef.io/server/api_chef_server/#post-9
 //   - Chef-Vault Source: https://github.com/chef/chef-vault/blob/main/lib/chef/knife/vault_update.rb
 func (s *Service) Update(payload *Payload) (*UpdateResponse, error) {
+	return s.UpdateContext(context.Background(), payload)
+}
+
+// UpdateContext is Update with requests made under ctx.
+func (s *Service) UpdateContext(ctx context.Context, payload *Payload) (resp *UpdateResponse, err error) {
+	defer s.observe(OpUpdate, time.Now(), &err)
+	ctx, sp := s.startSpan(ctx, "vault."+OpUpdate, payload.traceAttributes()...)
+	defer sp.end(&err)
+
 	if err := payload.validatePayload(); err != nil {
 		return nil, err
 	}
 
+	payload, err = payload.withFiles()
+	if err != nil {
+		return nil, err
+	}
+
 	ops := updateOps{
 		resolveUpdateContent: s.resolveUpdateContent,
 		updateVault:          s.updateVault,
 	}
-	return s.update(payload, ops)
+	return withItemLock(ctx, s, payload, func(ctx context.Context) (*UpdateResponse, error) {
+		return withAudit(ctx, s, OpUpdate, payload.VaultName, payload.VaultItemName, func() (*UpdateResponse, error) {
+			return withConflictRetry(payload, func(p *Payload) (*UpdateResponse, error) {
+				return s.update(ctx, p, ops)
+			})
+		})
+	})
 }
 
 // update is the worker called by the public API with the operational methods to complete the update request.
-func (s *Service) update(payload *Payload, ops updateOps) (*UpdateResponse, error) {
-	keyState, err := s.loadKeysCurrentState(payload)
+func (s *Service) update(ctx context.Context, payload *Payload, ops updateOps) (*UpdateResponse, error) {
+	revision, err := s.expectedRevision(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	keyState, err := s.loadKeysCurrentState(ctx, payload)
 	if err != nil {
 		return nil, err
 	}
 
+	content, err := ops.resolveUpdateContent(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validateContent(ctx, payload, content); err != nil {
+		return nil, err
+	}
+
+	// everything below may write, so the item must still be at the revision the update was based on.
+	if err := s.checkRevision(ctx, payload.VaultName, payload.VaultItemName, revision); err != nil {
+		return nil, err
+	}
+
 	keyState.Admins = item_keys.MergeClients(keyState.Admins, payload.Admins)
 	keyState.Clients = item_keys.MergeClients(keyState.Clients, payload.Clients)
 
 	if payload.Clean {
-		if err := s.pruneKeys(keyState.Clients, keyState, payload); err != nil {
+		if err := s.pruneKeys(ctx, keyState.Clients, keyState, payload); err != nil {
 			return nil, err
 		}
 		keyState.Clients = nil
@@ -63,11 +109,6 @@ func (s *Service) update(payload *Payload, ops updateOps) (*UpdateResponse, erro
 
 	mode, modeState := payload.resolveKeysMode(keyState.Mode)
 
-	content, err := ops.resolveUpdateContent(payload)
-	if err != nil {
-		return nil, err
-	}
-
 	updatePayload := &Payload{
 		VaultName:     payload.VaultName,
 		VaultItemName: payload.VaultItemName,
@@ -78,7 +119,7 @@ func (s *Service) update(payload *Payload, ops updateOps) (*UpdateResponse, erro
 		Clients:       keyState.Clients,
 	}
 
-	keysResult, err := ops.updateVault(updatePayload, modeState)
+	keysResult, err := ops.updateVault(ctx, updatePayload, modeState)
 	if err != nil {
 		return nil, err
 	}
@@ -99,23 +140,31 @@ func (s *Service) update(payload *Payload, ops updateOps) (*UpdateResponse, erro
 }
 
 // updateVault performs the shared re-encryption logic used by Update and Refresh.
-func (s *Service) updateVault(payload *Payload, modeState *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
+func (s *Service) updateVault(ctx context.Context, payload *Payload, modeState *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
+	// history is decrypted with the outgoing secret before the keys are replaced.
+	hist, err := s.prepareHistory(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
 	secret, err := item_keys.GenSecret(32)
 	if err != nil {
 		return nil, err
 	}
 
-	keysResult, err := s.createKeysDataBag(payload, modeState, secret)
+	keysResult, err := s.createKeysDataBag(ctx, payload, modeState, secret)
 	if err != nil {
 		return nil, err
 	}
 
-	encrypted, err := item.Encrypt(payload.VaultItemName, payload.Content, secret)
+	encrypted, err := s.encryptItem(ctx, payload, secret)
 	if err != nil {
 		return nil, err
 	}
+	s.logger().Info("item re-encrypted", "vault", payload.VaultName, "item", payload.VaultItemName, "keys", len(payload.Content))
 
-	if err := s.Client.DataBags.UpdateItem(
+	if err := s.backend().UpdateDataBagItem(
+		ctx,
 		payload.VaultName,
 		payload.VaultItemName,
 		&encrypted,
@@ -123,12 +172,19 @@ func (s *Service) updateVault(payload *Payload, modeState *item_keys.KeysModeSta
 		return nil, err
 	}
 
+	if hist != nil {
+		if err := s.writeHistory(ctx, payload, hist, secret); err != nil {
+			return nil, err
+		}
+	}
+
 	return keysResult, nil
 }
 
-// resolveUpdateContent merges the payload content with the current content.
-func (s *Service) resolveUpdateContent(p *Payload) (map[string]interface{}, error) {
-	current, err := s.GetItem(p.VaultName, p.VaultItemName)
+// resolveUpdateContent merges the payload content with the current content, dropping the keys the
+// payload clears.
+func (s *Service) resolveUpdateContent(ctx context.Context, p *Payload) (map[string]interface{}, error) {
+	current, err := s.GetItemContext(ctx, p.VaultName, p.VaultItemName)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +202,9 @@ func (s *Service) resolveUpdateContent(p *Payload) (map[string]interface{}, erro
 	if err != nil {
 		return nil, err
 	}
+	for _, k := range p.clearKeys {
+		delete(merged, k)
+	}
 	return merged, nil
 }
 
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"testing"
 
 	"github.com/justintsteele/go-chef-vault/item_keys"
@@ -8,8 +9,9 @@ import (
 )
 
 type updateRecorder struct {
-	calls []string
-	wrote struct {
+	calls   []string
+	content map[string]interface{}
+	wrote   struct {
 		payload *Payload
 		state   *item_keys.KeysModeState
 	}
@@ -17,15 +19,18 @@ type updateRecorder struct {
 
 func (r *updateRecorder) ops() updateOps {
 	return updateOps{
-		resolveUpdateContent: func(p *Payload) (map[string]interface{}, error) {
+		resolveUpdateContent: func(_ context.Context, p *Payload) (map[string]interface{}, error) {
 			r.calls = append(r.calls, "resolveUpdateContent")
+			if r.content != nil {
+				return r.content, nil
+			}
 			content := map[string]interface{}{
 				"foo": "foo-value-1",
 				"bar": "bar-value-1",
 			}
 			return content, nil
 		},
-		updateVault: func(payload *Payload, state *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
+		updateVault: func(_ context.Context, payload *Payload, state *item_keys.KeysModeState) (*item_keys.VaultItemKeysResult, error) {
 			r.calls = append(r.calls, "updateVault")
 			r.wrote.payload = payload
 			r.wrote.state = state
@@ -42,7 +47,7 @@ func TestUpdate_ChangeKeysMode(t *testing.T) {
 	rec := &updateRecorder{}
 
 	mode := item_keys.KeysModeSparse
-	_, err := service.update(&Payload{
+	_, err := service.update(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 		KeysMode:      &mode,
@@ -57,7 +62,7 @@ func TestUpdate_NoKeysMode(t *testing.T) {
 
 	rec := &updateRecorder{}
 
-	_, err := service.update(&Payload{
+	_, err := service.update(context.Background(), &Payload{
 		VaultName:     "vault1",
 		VaultItemName: "secret1",
 	}, rec.ops())
@@ -92,7 +97,7 @@ func TestPayload_mergeKeyActors_MergesAdminsAndClients(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	keyState, err := service.loadKeysCurrentState(payload)
+	keyState, err := service.loadKeysCurrentState(context.Background(), payload)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -15,20 +15,73 @@ var (
 
 	// ErrMissingVaultItemName is returned when VaultItemName is empty.
 	ErrMissingVaultItemName = errors.New("vault: missing VaultItemName")
+
+	// ErrPathNotFound is returned by a strict Remove when a requested path does not exist in the vault item.
+	ErrPathNotFound = errors.New("vault: path not found")
+
+	// ErrSchemaValidation is returned when vault item content does not conform to its JSON Schema.
+	ErrSchemaValidation = errors.New("vault: content does not match schema")
+
+	// ErrSchemaNotFound is returned when neither the vault item nor its vault carries a JSON Schema.
+	ErrSchemaNotFound = errors.New("vault: schema not found")
+
+	// ErrVersionNotFound is returned when a requested vault item version is not retained.
+	ErrVersionNotFound = errors.New("vault: version not found")
+
+	// ErrConflict is returned when a vault item changed between the revision a write was based on and the write.
+	// The concrete error is a *ConflictError carrying both revisions.
+	ErrConflict = errors.New("vault: item changed concurrently")
+
+	// ErrLocked is returned when a vault item is locked by another holder.
+	// The concrete error is a *LockedError describing the current lock.
+	ErrLocked = errors.New("vault: item is locked")
+
+	// ErrImportFailed is returned by Import when one or more manifest items could not be imported.
+	ErrImportFailed = errors.New("vault: import failed")
+
+	// ErrInvalidEdit is returned by Edit when the edited item is not a JSON object or its id was changed.
+	ErrInvalidEdit = errors.New("vault: invalid edit")
+
+	// ErrInvalidEnvMapping is returned when an environment mapping spec is malformed or two mappings set the same variable.
+	ErrInvalidEnvMapping = errors.New("vault: invalid environment mapping")
+
+	// ErrFileNotFound is returned by GetFile when the vault item holds no file under the requested key.
+	ErrFileNotFound = errors.New("vault: file not found")
+
+	// ErrInvalidFile is returned when a file cannot be stored or a stored file cannot be decoded.
+	ErrInvalidFile = errors.New("vault: invalid file")
+
+	// ErrChecksumMismatch is returned by GetFile when a stored file does not match its recorded SHA-256.
+	ErrChecksumMismatch = errors.New("vault: file checksum mismatch")
+
+	// ErrNotBackupRecipient is returned by Restore when the backup archive was not encrypted to the given key.
+	ErrNotBackupRecipient = errors.New("vault: backup is not encrypted to this key")
+
+	// ErrInvalidInterval is returned by Watch when the polling interval is not positive.
+	ErrInvalidInterval = errors.New("vault: invalid watch interval")
 )
 
 // Payload represents the input parameters used to create, update, or refresh a vault item.
 type Payload struct {
-	VaultName     string
-	VaultItemName string
-	Content       map[string]interface{}
-	KeysMode      *item_keys.KeysMode
-	SearchQuery   *string
-	Admins        []string
-	Clients       []string
-	Clean         bool
-	CleanUnknown  bool
-	SkipReencrypt bool
+	VaultName       string
+	VaultItemName   string
+	Content         map[string]interface{}
+	Files           map[string]*File
+	KeysMode        *item_keys.KeysMode
+	SearchQuery     *string
+	Admins          []string
+	Clients         []string
+	Clean           bool
+	CleanUnknown    bool
+	SkipReencrypt   bool
+	RemovePaths     []string
+	StrictPaths     bool
+	Schema          map[string]interface{}
+	Revision        string
+	ConflictRetries int
+
+	// clearKeys are top-level keys an Update removes from the current content, set by withFiles.
+	clearKeys []string
 }
 
 // validatePayload ensures that required fields are provided in a given payload.
@@ -0,0 +1,429 @@
+package vaulttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justintsteele/go-chef-vault/internal/query"
+)
+
+// apiError is a failed request, served as a Chef Server error body with its status.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+func errorf(status int, format string, args ...interface{}) error {
+	return &apiError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody(err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	check := !s.skipCheck
+	s.mu.Unlock()
+
+	if check {
+		if err := s.authenticate(r, body); err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorBody(err.Error()))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	status, v, err := s.route(r, body)
+	s.mu.Unlock()
+
+	if err != nil {
+		status = http.StatusInternalServerError
+		if ae, ok := err.(*apiError); ok {
+			status = ae.status
+		}
+		writeJSON(w, status, errorBody(err.Error()))
+		return
+	}
+	writeJSON(w, status, v)
+}
+
+// errorBody returns the JSON body of a Chef Server error.
+func errorBody(msg string) map[string]interface{} {
+	return map[string]interface{}{"error": []string{msg}}
+}
+
+// writeJSON writes v as the JSON body of a response with status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// route dispatches a request to its handler. It is called with s.mu held.
+func (s *Server) route(r *http.Request, body []byte) (int, interface{}, error) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch parts[0] {
+	case "data":
+		return s.serveData(r.Method, parts[1:], body)
+	case "users", "clients":
+		return s.serveActors(r.Method, parts[0], parts[1:])
+	case "nodes":
+		return s.serveNodes(r.Method, parts[1:])
+	case "search":
+		if len(parts) == 2 {
+			return s.serveSearch(r, parts[1], body)
+		}
+	}
+	return 0, nil, errorf(http.StatusNotFound, "no route for %s", r.URL.Path)
+}
+
+// serveData serves /data, /data/BAG and /data/BAG/ID.
+func (s *Server) serveData(method string, parts []string, body []byte) (int, interface{}, error) {
+	switch {
+	case len(parts) == 0 && method == http.MethodGet:
+		names := make([]string, 0, len(s.bags))
+		for bag := range s.bags {
+			names = append(names, bag)
+		}
+		return http.StatusOK, s.urlMap("data", names), nil
+
+	case len(parts) == 0 && method == http.MethodPost:
+		var bag struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(body, &bag); err != nil || bag.Name == "" {
+			return 0, nil, errorf(http.StatusBadRequest, "invalid data bag")
+		}
+		if _, ok := s.bags[bag.Name]; ok {
+			return 0, nil, errorf(http.StatusConflict, "data bag %s already exists", bag.Name)
+		}
+		s.bags[bag.Name] = make(map[string]json.RawMessage)
+		return http.StatusCreated, map[string]string{"uri": s.URL + "data/" + bag.Name}, nil
+
+	case len(parts) == 1:
+		return s.serveDataBag(method, parts[0], body)
+
+	case len(parts) == 2:
+		return s.serveDataBagItem(method, parts[0], parts[1], body)
+	}
+	return 0, nil, errorf(http.StatusMethodNotAllowed, "%s not allowed", method)
+}
+
+// serveDataBag serves /data/BAG.
+func (s *Server) serveDataBag(method, bag string, body []byte) (int, interface{}, error) {
+	items, ok := s.bags[bag]
+	if !ok {
+		return 0, nil, errorf(http.StatusNotFound, "cannot load data bag %s", bag)
+	}
+
+	switch method {
+	case http.MethodGet:
+		ids := make([]string, 0, len(items))
+		for id := range items {
+			ids = append(ids, id)
+		}
+		return http.StatusOK, s.urlMap("data/"+bag, ids), nil
+
+	case http.MethodPost:
+		raw, id, err := decodeItem(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		if _, ok := items[id]; ok {
+			return 0, nil, errorf(http.StatusConflict, "data bag item %s/%s already exists", bag, id)
+		}
+		items[id] = raw
+		return http.StatusCreated, raw, nil
+
+	case http.MethodDelete:
+		delete(s.bags, bag)
+		return http.StatusOK, map[string]string{"name": bag, "json_class": "Chef::DataBag", "chef_type": "data_bag"}, nil
+	}
+	return 0, nil, errorf(http.StatusMethodNotAllowed, "%s not allowed", method)
+}
+
+// serveDataBagItem serves /data/BAG/ID.
+func (s *Server) serveDataBagItem(method, bag, id string, body []byte) (int, interface{}, error) {
+	current, ok := s.bags[bag][id]
+	if !ok {
+		return 0, nil, errorf(http.StatusNotFound, "cannot load data bag item %s for data bag %s", id, bag)
+	}
+
+	switch method {
+	case http.MethodGet:
+		return http.StatusOK, current, nil
+
+	case http.MethodPut:
+		raw, bodyID, err := decodeItem(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		if bodyID != id {
+			return 0, nil, errorf(http.StatusBadRequest, "data bag item id %q does not match %q", bodyID, id)
+		}
+		s.bags[bag][id] = raw
+		return http.StatusOK, raw, nil
+
+	case http.MethodDelete:
+		delete(s.bags[bag], id)
+		return http.StatusOK, current, nil
+	}
+	return 0, nil, errorf(http.StatusMethodNotAllowed, "%s not allowed", method)
+}
+
+// decodeItem validates a data bag item body and returns it with its id.
+func decodeItem(body []byte) (json.RawMessage, string, error) {
+	var item map[string]interface{}
+	if err := json.Unmarshal(body, &item); err != nil {
+		return nil, "", errorf(http.StatusBadRequest, "invalid data bag item: %v", err)
+	}
+
+	// items may be wrapped in a raw_data envelope, as knife sends them.
+	if rawData, ok := item["raw_data"].(map[string]interface{}); ok {
+		item = rawData
+		var err error
+		if body, err = json.Marshal(item); err != nil {
+			return nil, "", err
+		}
+	}
+
+	id, _ := item["id"].(string)
+	if id == "" {
+		return nil, "", errorf(http.StatusBadRequest, "data bag item has no id")
+	}
+	return body, id, nil
+}
+
+// serveActors serves /users and /clients, their members and their default keys.
+func (s *Server) serveActors(method, kind string, parts []string) (int, interface{}, error) {
+	actors := s.users
+	if kind == "clients" {
+		actors = s.clients
+	}
+
+	if len(parts) == 0 {
+		if method != http.MethodGet {
+			return 0, nil, errorf(http.StatusMethodNotAllowed, "%s not allowed", method)
+		}
+		names := make([]string, 0, len(actors))
+		for name := range actors {
+			names = append(names, name)
+		}
+		return http.StatusOK, s.urlMap(kind, names), nil
+	}
+
+	actor, ok := actors[parts[0]]
+	if !ok {
+		return 0, nil, errorf(http.StatusNotFound, "cannot load %s %s", strings.TrimSuffix(kind, "s"), parts[0])
+	}
+
+	switch {
+	case len(parts) == 1 && method == http.MethodGet:
+		return http.StatusOK, actorDoc(kind, actor), nil
+
+	case len(parts) == 1 && method == http.MethodDelete:
+		delete(actors, actor.Name)
+		return http.StatusOK, actorDoc(kind, actor), nil
+
+	case len(parts) == 3 && parts[1] == "keys" && parts[2] == "default" && method == http.MethodGet:
+		return http.StatusOK, map[string]string{
+			"name":            "default",
+			"public_key":      actor.PublicKeyPEM,
+			"expiration_date": "infinity",
+		}, nil
+	}
+	return 0, nil, errorf(http.StatusNotFound, "no route for %s/%s", kind, strings.Join(parts, "/"))
+}
+
+// actorDoc returns the object the server serves for a user or client.
+func actorDoc(kind string, actor *Actor) map[string]interface{} {
+	if kind == "users" {
+		return map[string]interface{}{
+			"username":     actor.Name,
+			"display_name": actor.Name,
+		}
+	}
+	return map[string]interface{}{
+		"name":       actor.Name,
+		"clientname": actor.Name,
+		"validator":  false,
+		"json_class": "Chef::ApiClient",
+		"chef_type":  "client",
+	}
+}
+
+// serveNodes serves /nodes and /nodes/NAME.
+func (s *Server) serveNodes(method string, parts []string) (int, interface{}, error) {
+	if method != http.MethodGet {
+		return 0, nil, errorf(http.StatusMethodNotAllowed, "%s not allowed", method)
+	}
+
+	switch len(parts) {
+	case 0:
+		names := make([]string, 0, len(s.nodes))
+		for name := range s.nodes {
+			names = append(names, name)
+		}
+		return http.StatusOK, s.urlMap("nodes", names), nil
+	case 1:
+		node, ok := s.nodes[parts[0]]
+		if !ok {
+			return 0, nil, errorf(http.StatusNotFound, "cannot load node %s", parts[0])
+		}
+		return http.StatusOK, node, nil
+	}
+	return 0, nil, errorf(http.StatusNotFound, "no route for nodes/%s", strings.Join(parts, "/"))
+}
+
+// searchDoc is a document of a search index with the URL of the object it describes.
+type searchDoc struct {
+	url string
+	doc map[string]interface{}
+}
+
+// serveSearch serves /search/INDEX. GET returns whole documents; POST is a partial search returning
+// the fields named in the body, each field being a path into the document or its attributes.
+func (s *Server) serveSearch(r *http.Request, index string, body []byte) (int, interface{}, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return 0, nil, errorf(http.StatusMethodNotAllowed, "%s not allowed", r.Method)
+	}
+
+	params := r.URL.Query()
+	q, err := query.Parse(params.Get("q"))
+	if err != nil {
+		return 0, nil, errorf(http.StatusBadRequest, "%v", err)
+	}
+	start, _ := strconv.Atoi(params.Get("start"))
+	rows, err := strconv.Atoi(params.Get("rows"))
+	if err != nil || rows <= 0 {
+		rows = 1000
+	}
+
+	var fields map[string][]string
+	if r.Method == http.MethodPost {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return 0, nil, errorf(http.StatusBadRequest, "invalid partial search body: %v", err)
+		}
+	}
+
+	docs, err := s.searchIndex(index)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var matches []searchDoc
+	for _, d := range docs {
+		if q.Match(d.doc) {
+			matches = append(matches, d)
+		}
+	}
+
+	out := make([]interface{}, 0, rows)
+	for i := start; i < len(matches) && i < start+rows; i++ {
+		if fields == nil {
+			out = append(out, matches[i].doc)
+			continue
+		}
+
+		data := make(map[string]interface{}, len(fields))
+		for name, path := range fields {
+			data[name] = lookupPath(matches[i].doc, path)
+		}
+		out = append(out, map[string]interface{}{"url": matches[i].url, "data": data})
+	}
+
+	return http.StatusOK, map[string]interface{}{
+		"total": len(matches),
+		"start": start,
+		"rows":  out,
+	}, nil
+}
+
+// searchIndex returns the documents of a search index sorted by URL: nodes, clients, or the items of a data bag.
+func (s *Server) searchIndex(index string) ([]searchDoc, error) {
+	var docs []searchDoc
+
+	switch index {
+	case "node":
+		for name, raw := range s.nodes {
+			var node map[string]interface{}
+			if err := json.Unmarshal(raw, &node); err != nil {
+				return nil, err
+			}
+			docs = append(docs, searchDoc{url: s.URL + "nodes/" + name, doc: node})
+		}
+
+	case "client":
+		for _, actor := range s.clients {
+			docs = append(docs, searchDoc{url: s.URL + "clients/" + actor.Name, doc: actorDoc("clients", actor)})
+		}
+
+	default:
+		items, ok := s.bags[index]
+		if !ok {
+			return nil, errorf(http.StatusNotFound, "no search index %s", index)
+		}
+		for id, raw := range items {
+			var item map[string]interface{}
+			if err := json.Unmarshal(raw, &item); err != nil {
+				return nil, err
+			}
+			docs = append(docs, searchDoc{url: s.URL + "data/" + index + "/" + id, doc: item})
+		}
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].url < docs[j].url })
+	return docs, nil
+}
+
+// lookupPath returns the value at path in doc, looking the path up in the document itself and then
+// in its attributes, highest precedence first. A missing value is nil.
+func lookupPath(doc map[string]interface{}, path []string) interface{} {
+	for _, root := range []string{"", "automatic", "override", "normal", "default"} {
+		var cur interface{} = doc
+		if root != "" {
+			cur = doc[root]
+		}
+
+		found := true
+		for _, key := range path {
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				found = false
+				break
+			}
+			if cur, ok = m[key]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return cur
+		}
+	}
+	return nil
+}
+
+// urlMap returns the name to URL map the Chef Server uses for listings.
+func (s *Server) urlMap(prefix string, names []string) map[string]string {
+	out := make(map[string]string, len(names))
+	for _, n := range names {
+		out[n] = s.URL + prefix + "/" + n
+	}
+	return out
+}
@@ -0,0 +1,280 @@
+// Package vaulttest provides an in-process Chef Server for testing code built on the vault package.
+//
+//	srv := vaulttest.NewServer(t)
+//	srv.AddUser("admin")
+//	srv.AddClient("web1")
+//	srv.AddNode("web1", map[string]interface{}{"chef_environment": "prod"})
+//
+//	svc := srv.Service("admin")
+//
+// The server implements the parts of the Chef Server API the vault package uses: data bags and their
+// items, users and clients with their default keys, nodes, and search over the node and client indexes
+// and data bags. Queries support *:*, field:value terms with * and ? wildcards, AND, OR and NOT.
+// Requests must be signed by a known user or client, as a real server requires.
+package vaulttest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/go-chef/chef"
+	vault "github.com/justintsteele/go-chef-vault"
+)
+
+// KeyBits is the size of the RSA keys generated for actors.
+const KeyBits = 2048
+
+// Actor is a user or client known to the server.
+type Actor struct {
+	Name string
+
+	// Key is the actor's private key. Its public half is served as the actor's default key.
+	Key *rsa.PrivateKey
+
+	// PrivateKeyPEM and PublicKeyPEM are the PEM encodings of the key pair.
+	PrivateKeyPEM string
+	PublicKeyPEM  string
+}
+
+// Server is an in-process Chef Server. The zero value is not usable; create servers with NewServer.
+type Server struct {
+	// URL is the base URL of the server, ending in a slash.
+	URL string
+
+	tb  testing.TB
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	bags      map[string]map[string]json.RawMessage
+	users     map[string]*Actor
+	clients   map[string]*Actor
+	nodes     map[string]json.RawMessage
+	requests  []string
+	skipCheck bool
+}
+
+// NewServer starts a Server and closes it when the test ends.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	s := &Server{
+		tb:      tb,
+		bags:    make(map[string]map[string]json.RawMessage),
+		users:   make(map[string]*Actor),
+		clients: make(map[string]*Actor),
+		nodes:   make(map[string]json.RawMessage),
+	}
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL + "/"
+
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Close shuts the server down. It is safe to call more than once.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// SetCheckSignatures enables or disables request signature checking, which is on by default. With
+// checking disabled any request is accepted, whoever it claims to come from.
+func (s *Server) SetCheckSignatures(check bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skipCheck = !check
+}
+
+// AddUser creates a user with a generated key pair.
+func (s *Server) AddUser(name string) *Actor {
+	s.tb.Helper()
+	return s.addActor(s.users, "user", name)
+}
+
+// AddClient creates a client with a generated key pair.
+func (s *Server) AddClient(name string) *Actor {
+	s.tb.Helper()
+	return s.addActor(s.clients, "client", name)
+}
+
+// DeleteClient removes a client, as knife client delete does. Its node, if any, is kept.
+func (s *Server) DeleteClient(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, name)
+}
+
+// addActor generates a key pair and registers an actor of kind in actors.
+func (s *Server) addActor(actors map[string]*Actor, kind, name string) *Actor {
+	s.tb.Helper()
+
+	s.mu.Lock()
+	_, exists := actors[name]
+	s.mu.Unlock()
+	if exists {
+		s.tb.Fatalf("vaulttest: %s %q already exists", kind, name)
+	}
+
+	actor, err := newActor(name)
+	if err != nil {
+		s.tb.Fatalf("vaulttest: generating key for %s %q: %v", kind, name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	actors[name] = actor
+	return actor
+}
+
+// newActor returns an actor with a generated key pair.
+func newActor(name string) (*Actor, error) {
+	key, err := rsa.GenerateKey(rand.Reader, KeyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Actor{
+		Name:          name,
+		Key:           key,
+		PrivateKeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		PublicKeyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, nil
+}
+
+// AddNode creates or replaces a node. attrs are merged over the defaults of a new node, so they may set
+// chef_environment, run_list, and the normal, default, override and automatic attributes.
+func (s *Server) AddNode(name string, attrs map[string]interface{}) {
+	s.tb.Helper()
+
+	node := map[string]interface{}{
+		"name":             name,
+		"chef_type":        "node",
+		"json_class":       "Chef::Node",
+		"chef_environment": "_default",
+		"run_list":         []interface{}{},
+		"normal":           map[string]interface{}{},
+		"default":          map[string]interface{}{},
+		"override":         map[string]interface{}{},
+		"automatic":        map[string]interface{}{},
+	}
+	for k, v := range attrs {
+		node[k] = v
+	}
+
+	raw, err := json.Marshal(node)
+	if err != nil {
+		s.tb.Fatalf("vaulttest: encoding node %q: %v", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes[name] = raw
+}
+
+// Client returns a go-chef client that signs its requests as the named user or client.
+func (s *Server) Client(name string) *chef.Client {
+	s.tb.Helper()
+
+	actor := s.actor(name)
+	if actor == nil {
+		s.tb.Fatalf("vaulttest: no user or client %q", name)
+	}
+
+	client, err := chef.NewClient(&chef.Config{
+		Name:                  actor.Name,
+		Key:                   actor.PrivateKeyPEM,
+		BaseURL:               s.URL,
+		AuthenticationVersion: "1.0",
+	})
+	if err != nil {
+		s.tb.Fatalf("vaulttest: creating client for %q: %v", name, err)
+	}
+	return client
+}
+
+// Service returns a vault.Service acting as the named user or client.
+func (s *Server) Service(name string) *vault.Service {
+	s.tb.Helper()
+	return vault.NewService(s.Client(name))
+}
+
+// DataBagItem returns the stored content of a data bag item, as the server would serve it.
+func (s *Server) DataBagItem(bag, id string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	raw, ok := s.bags[bag][id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// PutDataBagItem stores a data bag item directly, creating the bag if needed. The item's id is set to id.
+func (s *Server) PutDataBagItem(bag, id string, content map[string]interface{}) {
+	s.tb.Helper()
+
+	item := make(map[string]interface{}, len(content)+1)
+	for k, v := range content {
+		item[k] = v
+	}
+	item["id"] = id
+
+	raw, err := json.Marshal(item)
+	if err != nil {
+		s.tb.Fatalf("vaulttest: encoding %s/%s: %v", bag, id, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bags[bag] == nil {
+		s.bags[bag] = make(map[string]json.RawMessage)
+	}
+	s.bags[bag][id] = raw
+}
+
+// DataBagItems returns the sorted ids of the items in a data bag.
+func (s *Server) DataBagItems(bag string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.bags[bag]))
+	for id := range s.bags[bag] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Requests returns the requests served so far, as "METHOD /path" strings.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// actor returns the user, or failing that the client, with the given name.
+func (s *Server) actor(name string) *Actor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.users[name]; ok {
+		return a
+	}
+	return s.clients[name]
+}
@@ -0,0 +1,145 @@
+package vaulttest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-chef/chef"
+	vault "github.com/justintsteele/go-chef-vault"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/justintsteele/go-chef-vault/item"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_VaultLifecycle(t *testing.T) {
+	srv := NewServer(t)
+	srv.AddUser("admin")
+	srv.AddClient("web1")
+	srv.AddClient("db1")
+	srv.AddNode("web1", map[string]interface{}{"chef_environment": "prod", "run_list": []string{"role[web]"}})
+	srv.AddNode("db1", map[string]interface{}{"chef_environment": "prod", "run_list": []string{"role[db]"}})
+
+	admin := srv.Service("admin")
+	query := "role:web AND chef_environment:prod"
+
+	_, err := admin.Create(&vault.Payload{
+		VaultName:     "secrets",
+		VaultItemName: "api",
+		Content:       map[string]interface{}{"token": "abc"},
+		Admins:        []string{"admin"},
+		SearchQuery:   &query,
+	})
+	require.NoError(t, err)
+
+	keys, ok := srv.DataBagItem("secrets", "api_keys")
+	require.True(t, ok)
+	require.Contains(t, keys, "admin")
+	require.Contains(t, keys, "web1")
+	require.NotContains(t, keys, "db1")
+
+	// the matching client decrypts the item with its own key; the other cannot.
+	got, err := srv.Service("web1").GetItem("secrets", "api")
+	require.NoError(t, err)
+	content, err := item.DataBagItemMap(got)
+	require.NoError(t, err)
+	require.Equal(t, "abc", content["token"])
+
+	_, err = srv.Service("db1").GetItem("secrets", "api")
+	require.ErrorContains(t, err, "not encrypted with your public key")
+
+	// a new matching node is picked up by Refresh, and a deleted client is cleaned.
+	srv.AddClient("web2")
+	srv.AddNode("web2", map[string]interface{}{"chef_environment": "prod", "run_list": []string{"role[web]"}})
+	srv.DeleteClient("web1")
+
+	_, err = admin.Refresh(&vault.Payload{VaultName: "secrets", VaultItemName: "api", CleanUnknown: true})
+	require.NoError(t, err)
+
+	keys, _ = srv.DataBagItem("secrets", "api_keys")
+	require.Contains(t, keys, "web2")
+	require.NotContains(t, keys, "web1")
+
+	_, err = admin.DeleteItem("secrets", "api")
+	require.NoError(t, err)
+	require.Empty(t, srv.DataBagItems("secrets"))
+}
+
+func TestServer_RejectsBadSignatures(t *testing.T) {
+	srv := NewServer(t)
+	srv.AddUser("admin")
+	impostor := srv.AddUser("impostor")
+
+	// a client claiming to be admin but signing with another key is refused.
+	client, err := chef.NewClient(&chef.Config{
+		Name:                  "admin",
+		Key:                   impostor.PrivateKeyPEM,
+		BaseURL:               srv.URL,
+		AuthenticationVersion: "1.0",
+	})
+	require.NoError(t, err)
+
+	_, err = client.DataBags.List()
+	requireStatus(t, err, http.StatusUnauthorized)
+
+	// unknown actors are refused.
+	client.Auth.ClientName = "nobody"
+	_, err = client.DataBags.List()
+	requireStatus(t, err, http.StatusUnauthorized)
+
+	// protocol 1.3 signatures are accepted.
+	client, err = chef.NewClient(&chef.Config{
+		Name:                  "modern",
+		Key:                   srv.AddUser("modern").PrivateKeyPEM,
+		BaseURL:               srv.URL,
+		AuthenticationVersion: "1.3",
+	})
+	require.NoError(t, err)
+	_, err = client.DataBags.List()
+	require.NoError(t, err)
+
+	srv.SetCheckSignatures(false)
+	client.Auth.ClientName = "nobody"
+	_, err = client.DataBags.List()
+	require.NoError(t, err)
+}
+
+func TestServer_Search(t *testing.T) {
+	srv := NewServer(t)
+	srv.AddUser("admin")
+	srv.AddNode("web1", map[string]interface{}{"chef_environment": "prod", "automatic": map[string]interface{}{"fqdn": "web1.example.com"}})
+	srv.AddNode("web2", map[string]interface{}{"chef_environment": "dev"})
+	srv.PutDataBagItem("users", "alice", map[string]interface{}{"shell": "zsh"})
+
+	client := srv.Client("admin")
+
+	res, err := client.Search.Exec("node", "name:web* AND NOT chef_environment:dev")
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Total)
+
+	q, err := client.Search.NewQuery("node", "name:web*")
+	require.NoError(t, err)
+	partial, err := q.DoPartial(client, map[string]interface{}{"fqdn": []string{"fqdn"}})
+	require.NoError(t, err)
+	require.Equal(t, 2, partial.Total)
+	require.Equal(t, "web1.example.com", partial.Rows[0].(map[string]interface{})["data"].(map[string]interface{})["fqdn"])
+
+	res, err = client.Search.Exec("users", "shell:zsh")
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Total)
+
+	_, err = client.Search.Exec("node", "(name:web1)")
+	requireStatus(t, err, http.StatusBadRequest)
+
+	_, err = client.Search.Exec("missing", "*:*")
+	requireStatus(t, err, http.StatusNotFound)
+
+	require.Contains(t, srv.Requests(), "POST /search/node")
+}
+
+func requireStatus(t *testing.T, err error, status int) {
+	t.Helper()
+
+	ce, ok := cheferr.AsChefError(err)
+	require.True(t, ok, "expected a Chef error, got %v", err)
+	require.Equal(t, status, ce.StatusCode())
+}
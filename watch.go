@@ -0,0 +1,124 @@
+package vault
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/item"
+)
+
+// WatchEvent reports the state of a vault item watched with Watch.
+type WatchEvent struct {
+	VaultName     string
+	VaultItemName string
+
+	// Revision identifies the stored state Content was read at, as returned by Revision.
+	Revision string
+
+	// Content is the decrypted item. It is nil when Err is set.
+	Content map[string]interface{}
+
+	// Err is set when the item could not be read or decrypted. Watching continues; an error is
+	// reported once per run of failed polls.
+	Err error
+}
+
+// watchOps defines the callable operations required to execute a Watch request.
+type watchOps struct {
+	revision func(ctx context.Context, vaultName, vaultItem string) (string, error)
+	getItem  func(ctx context.Context, vaultName, vaultItem string) (chef.DataBagItem, string, error)
+}
+
+// Watch polls a vault item every interval and sends an event carrying its decrypted content on the
+// returned channel: first for the current content, then whenever the item or its keys change.
+//
+// Each poll only fetches the encrypted item and its keys item and compares their revision with the
+// last one seen; the item is decrypted again only when it changed. The channel is closed once ctx is
+// done. Events are not buffered, so a slow receiver delays the next poll.
+func (s *Service) Watch(ctx context.Context, vaultName, vaultItem string, interval time.Duration) (<-chan WatchEvent, error) {
+	pl := &Payload{
+		VaultName:     vaultName,
+		VaultItemName: vaultItem,
+	}
+
+	if err := pl.validatePayload(); err != nil {
+		return nil, err
+	}
+
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	ops := watchOps{
+		revision: s.itemRevision,
+		getItem:  s.getItemWithRevision,
+	}
+
+	events := make(chan WatchEvent)
+	go s.watch(ctx, pl, interval, events, ops)
+	return events, nil
+}
+
+// watch is the worker called by the public API with the operational methods to complete the watch request.
+func (s *Service) watch(ctx context.Context, payload *Payload, interval time.Duration, events chan<- WatchEvent, ops watchOps) {
+	defer close(events)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		last   string
+		failed bool
+	)
+	for first := true; ; first = false {
+		if !first {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+
+		ev := WatchEvent{
+			VaultName:     payload.VaultName,
+			VaultItemName: payload.VaultItemName,
+		}
+
+		if !first {
+			rev, err := ops.revision(ctx, payload.VaultName, payload.VaultItemName)
+			if err == nil && rev == last && !failed {
+				continue
+			}
+			ev.Err = err
+		}
+
+		if ev.Err == nil {
+			var content chef.DataBagItem
+			content, ev.Revision, ev.Err = ops.getItem(ctx, payload.VaultName, payload.VaultItemName)
+			if ev.Err == nil {
+				ev.Content, ev.Err = item.DataBagItemMap(content)
+			}
+		}
+
+		switch {
+		case ev.Err != nil && failed:
+			continue
+		case ev.Err != nil:
+			failed = true
+		case ev.Revision == last:
+			// recovered from a failure without the item changing.
+			failed = false
+			continue
+		default:
+			failed = false
+			last = ev.Revision
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case events <- ev:
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chef/chef"
+	"github.com/justintsteele/go-chef-vault/cheferr"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	t.Helper()
+
+	select {
+	case ev, ok := <-events:
+		require.True(t, ok, "events closed")
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no watch event")
+		return WatchEvent{}
+	}
+}
+
+func TestService_Watch(t *testing.T) {
+	backend := newMemoryBackend(t)
+	svc := NewServiceWithBackend(backend)
+
+	_, err := svc.Create(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "hunter2"},
+		Admins:        []string{userid},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := svc.Watch(ctx, "vault1", "secret1", 10*time.Millisecond)
+	require.NoError(t, err)
+
+	ev := receive(t, events)
+	require.NoError(t, ev.Err)
+	require.Equal(t, "hunter2", ev.Content["password"])
+	require.NotEmpty(t, ev.Revision)
+
+	_, err = svc.Update(&Payload{
+		VaultName:     "vault1",
+		VaultItemName: "secret1",
+		Content:       map[string]interface{}{"password": "swordfish"},
+	})
+	require.NoError(t, err)
+
+	next := receive(t, events)
+	require.NoError(t, next.Err)
+	require.Equal(t, "swordfish", next.Content["password"])
+	require.NotEqual(t, ev.Revision, next.Revision)
+
+	_, err = svc.DeleteItem("vault1", "secret1")
+	require.NoError(t, err)
+
+	ev = receive(t, events)
+	require.True(t, cheferr.IsNotFound(ev.Err))
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestService_WatchPolling(t *testing.T) {
+	revisions := []string{"r1", "r1", "", "", "r1", "r2"}
+	var reads int
+
+	ops := watchOps{
+		revision: func(context.Context, string, string) (string, error) {
+			rev := revisions[0]
+			revisions = revisions[1:]
+			if rev == "" {
+				return "", errors.New("bad gateway")
+			}
+			return rev, nil
+		},
+		getItem: func(context.Context, string, string) (chef.DataBagItem, string, error) {
+			reads++
+			rev := "r1"
+			if len(revisions) == 0 {
+				rev = "r2"
+			}
+			return map[string]interface{}{"rev": rev}, rev, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan WatchEvent)
+	go service.watch(ctx, &Payload{VaultName: "vault1", VaultItemName: "secret1"}, time.Millisecond, events, ops)
+
+	// the initial read, one error for two failed polls, and the change to r2.
+	require.Equal(t, "r1", receive(t, events).Revision)
+	require.EqualError(t, receive(t, events).Err, "bad gateway")
+	require.Equal(t, "r2", receive(t, events).Revision)
+
+	// unchanged polls do not decrypt; recovering without a change does, once.
+	require.Equal(t, 3, reads)
+}
+
+func TestService_WatchValidation(t *testing.T) {
+	_, err := service.Watch(context.Background(), "", "secret1", time.Second)
+	require.ErrorIs(t, err, ErrMissingVaultName)
+
+	_, err = service.Watch(context.Background(), "vault1", "secret1", 0)
+	require.ErrorIs(t, err, ErrInvalidInterval)
+}